@@ -0,0 +1,56 @@
+// Package blob stores the contents of uploaded files. Metadata stays in the database;
+// a Store only maps keys such as "pet3_1700000000_x1y2z3.pdf" to bytes, either in a
+// local directory or in an S3-compatible bucket that every API replica shares.
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"path"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned when no object has the requested key
+var ErrNotFound = errors.New("blob not found")
+
+// Info describes a stored object
+type Info struct {
+	Key         string
+	Size        int64
+	ContentType string
+	ModTime     time.Time
+}
+
+// Store keeps objects by key. Keys are slash-separated relative paths.
+type Store interface {
+	// Put stores the contents of r under key, replacing any existing object.
+	// size is the length of r, or -1 if it is not known in advance.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get opens an object; the caller closes the reader
+	Get(ctx context.Context, key string) (io.ReadCloser, Info, error)
+	Delete(ctx context.Context, key string) error
+	Stat(ctx context.Context, key string) (Info, error)
+	// List returns the objects whose keys start with prefix, ordered by key
+	List(ctx context.Context, prefix string) ([]Info, error)
+}
+
+// checkKey rejects keys that could escape the store's root
+func checkKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, `\`) || path.Clean(key) != key ||
+		key == ".." || strings.HasPrefix(key, "../") {
+		return fmt.Errorf("invalid blob key %q", key)
+	}
+	return nil
+}
+
+// typeByKey guesses the content type from the key's extension
+func typeByKey(key string) string {
+	if t := mime.TypeByExtension(path.Ext(key)); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
@@ -0,0 +1,150 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// tempPrefix marks partly written files, which List skips
+const tempPrefix = ".upload-"
+
+// Local keeps objects as files under Dir. It suits a single API instance; replicas
+// only see each other's files if Dir is on a shared volume.
+type Local struct {
+	Dir string
+}
+
+func (l Local) path(key string) (string, error) {
+	if err := checkKey(key); err != nil {
+		return "", err
+	}
+	return filepath.Join(l.Dir, filepath.FromSlash(key)), nil
+}
+
+// Put writes to a temporary file first, so readers never see a partial object
+func (l Local) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(p), tempPrefix+"*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	n, err := io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if size >= 0 && n != size {
+		return fmt.Errorf("blob %s: wrote %d bytes, expected %d", key, n, size)
+	}
+	return os.Rename(tmp.Name(), p)
+}
+
+// Get returns the open *os.File, which callers may also use as an io.ReadSeeker
+func (l Local) Get(ctx context.Context, key string) (io.ReadCloser, Info, error) {
+	p, err := l.path(key)
+	if err != nil {
+		return nil, Info{}, err
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, Info{}, notExist(err)
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, Info{}, err
+	}
+	return f, fileInfo(key, st), nil
+}
+
+func (l Local) Delete(ctx context.Context, key string) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil {
+		return notExist(err)
+	}
+	// Prune directories the key leaves empty; removing one that is not empty fails harmlessly
+	for dir := filepath.Dir(p); dir != filepath.Clean(l.Dir) && strings.HasPrefix(dir, filepath.Clean(l.Dir)); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+	return nil
+}
+
+func (l Local) Stat(ctx context.Context, key string) (Info, error) {
+	p, err := l.path(key)
+	if err != nil {
+		return Info{}, err
+	}
+	st, err := os.Stat(p)
+	if err != nil {
+		return Info{}, notExist(err)
+	}
+	return fileInfo(key, st), nil
+}
+
+func (l Local) List(ctx context.Context, prefix string) ([]Info, error) {
+	infos := []Info{}
+	err := filepath.WalkDir(l.Dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == l.Dir && errors.Is(err, fs.ErrNotExist) {
+				return filepath.SkipAll // nothing uploaded yet
+			}
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), tempPrefix) {
+			return nil
+		}
+		rel, err := filepath.Rel(l.Dir, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		st, err := d.Info()
+		if err != nil {
+			return err
+		}
+		infos = append(infos, fileInfo(key, st))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Key < infos[j].Key })
+	return infos, nil
+}
+
+func fileInfo(key string, st fs.FileInfo) Info {
+	return Info{Key: key, Size: st.Size(), ContentType: typeByKey(key), ModTime: st.ModTime()}
+}
+
+// notExist maps a missing file to ErrNotFound
+func notExist(err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrNotFound
+	}
+	return err
+}
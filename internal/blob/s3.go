@@ -0,0 +1,454 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// S3 keeps objects in a bucket of Amazon S3 or a compatible server such as MinIO.
+// Requests are signed with AWS Signature Version 4; bodies are sent unsigned
+// (UNSIGNED-PAYLOAD) so uploads are not read twice.
+type S3 struct {
+	Endpoint        string // e.g. "https://s3.eu-west-1.amazonaws.com" or "http://localhost:9000"
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// PathStyle addresses the bucket as Endpoint/Bucket rather than Bucket.host; MinIO needs it
+	PathStyle bool
+	Client    *http.Client // default http.DefaultClient
+}
+
+// S3Error is an error response from the server
+type S3Error struct {
+	StatusCode int
+	Code       string `xml:"Code"`
+	Message    string `xml:"Message"`
+}
+
+func (e *S3Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("s3: HTTP %d", e.StatusCode)
+	}
+	return fmt.Sprintf("s3: %s: %s (HTTP %d)", e.Code, e.Message, e.StatusCode)
+}
+
+const (
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+	emptySHA256     = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	amzDateFormat   = "20060102T150405Z"
+	// partSize is the part length of multipart uploads; S3 requires at least 5 MiB
+	// for every part but the last. One part at a time is held in memory.
+	partSize = 8 << 20
+)
+
+// Put sends objects of known size in one request and others as a multipart upload
+func (s *S3) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	if size < 0 {
+		return s.putMultipart(ctx, key, r, contentType)
+	}
+	if contentType == "" {
+		contentType = typeByKey(key)
+	}
+	req, err := s.newRequest(ctx, http.MethodPut, key, nil, io.NopCloser(r))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	if size == 0 {
+		req.Body = http.NoBody
+	}
+	req.Header.Set("Content-Type", contentType)
+	resp, err := s.do(req, unsignedPayload)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// putMultipart uploads a stream of unknown length part by part. Streams that fit in one
+// part are sent with a plain PUT; a failed upload is aborted so no parts are left behind.
+func (s *S3) putMultipart(ctx context.Context, key string, r io.Reader, contentType string) error {
+	buf := make([]byte, partSize)
+	n, err := io.ReadFull(r, buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return s.Put(ctx, key, bytes.NewReader(buf[:n]), int64(n), contentType)
+	}
+	if err != nil {
+		return err
+	}
+
+	if contentType == "" {
+		contentType = typeByKey(key)
+	}
+	req, err := s.newRequest(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	var initiated struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := s.doXML(req, emptySHA256, &initiated); err != nil {
+		return err
+	}
+	upload := url.Values{"uploadId": {initiated.UploadID}}
+
+	var complete completeMultipart
+	for partNumber := 1; n > 0; partNumber++ {
+		etag, err := s.uploadPart(ctx, key, upload, partNumber, buf[:n])
+		if err != nil {
+			s.abortMultipart(ctx, key, upload)
+			return err
+		}
+		complete.Parts = append(complete.Parts, completedPart{PartNumber: partNumber, ETag: etag})
+
+		n, err = io.ReadFull(r, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			s.abortMultipart(ctx, key, upload)
+			return err
+		}
+	}
+
+	body, err := xml.Marshal(complete)
+	if err != nil {
+		s.abortMultipart(ctx, key, upload)
+		return err
+	}
+	req, err = s.newRequest(ctx, http.MethodPost, key, upload, io.NopCloser(bytes.NewReader(body)))
+	if err != nil {
+		s.abortMultipart(ctx, key, upload)
+		return err
+	}
+	req.ContentLength = int64(len(body))
+	if err := s.doXML(req, hexSHA256(string(body)), nil); err != nil {
+		s.abortMultipart(ctx, key, upload)
+		return err
+	}
+	return nil
+}
+
+// completeMultipart is the CompleteMultipartUpload request body
+type completeMultipart struct {
+	XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+	Parts   []completedPart `xml:"Part"`
+}
+
+type completedPart struct {
+	PartNumber int
+	ETag       string
+}
+
+// uploadPart sends one part of a multipart upload and returns its ETag
+func (s *S3) uploadPart(ctx context.Context, key string, upload url.Values, partNumber int, data []byte) (string, error) {
+	query := url.Values{"partNumber": {strconv.Itoa(partNumber)}, "uploadId": upload["uploadId"]}
+	req, err := s.newRequest(ctx, http.MethodPut, key, query, io.NopCloser(bytes.NewReader(data)))
+	if err != nil {
+		return "", err
+	}
+	req.ContentLength = int64(len(data))
+	resp, err := s.do(req, unsignedPayload)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	return resp.Header.Get("ETag"), nil
+}
+
+// abortMultipart discards the parts of a failed upload, even when ctx was cancelled
+func (s *S3) abortMultipart(ctx context.Context, key string, upload url.Values) {
+	req, err := s.newRequest(context.WithoutCancel(ctx), http.MethodDelete, key, upload, nil)
+	if err != nil {
+		return
+	}
+	if resp, err := s.do(req, emptySHA256); err == nil {
+		resp.Body.Close()
+	}
+}
+
+func (s *S3) Get(ctx context.Context, key string) (io.ReadCloser, Info, error) {
+	if err := checkKey(key); err != nil {
+		return nil, Info{}, err
+	}
+	req, err := s.newRequest(ctx, http.MethodGet, key, nil, nil)
+	if err != nil {
+		return nil, Info{}, err
+	}
+	resp, err := s.do(req, emptySHA256)
+	if err != nil {
+		return nil, Info{}, err
+	}
+	return resp.Body, responseInfo(key, resp), nil
+}
+
+// Delete reports ErrNotFound only where the server does; S3 itself accepts deleting a missing key
+func (s *S3) Delete(ctx context.Context, key string) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	req, err := s.newRequest(ctx, http.MethodDelete, key, nil, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(req, emptySHA256)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (s *S3) Stat(ctx context.Context, key string) (Info, error) {
+	if err := checkKey(key); err != nil {
+		return Info{}, err
+	}
+	req, err := s.newRequest(ctx, http.MethodHead, key, nil, nil)
+	if err != nil {
+		return Info{}, err
+	}
+	resp, err := s.do(req, emptySHA256)
+	if err != nil {
+		return Info{}, err
+	}
+	resp.Body.Close()
+	return responseInfo(key, resp), nil
+}
+
+// listResult is the ListObjectsV2 response body
+type listResult struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		LastModified time.Time `xml:"LastModified"`
+		Size         int64     `xml:"Size"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// List follows continuation tokens until every matching key has been read. Content
+// types are not part of the listing and are guessed from the keys.
+func (s *S3) List(ctx context.Context, prefix string) ([]Info, error) {
+	infos := []Info{}
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		req, err := s.newRequest(ctx, http.MethodGet, "", query, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := s.do(req, emptySHA256)
+		if err != nil {
+			return nil, err
+		}
+		var result listResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("s3: decoding object list: %w", err)
+		}
+		for _, c := range result.Contents {
+			infos = append(infos, Info{Key: c.Key, Size: c.Size, ContentType: typeByKey(c.Key), ModTime: c.LastModified})
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return infos, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// --- Requests and signing ---
+
+// newRequest builds an unsigned request for key ("" addresses the bucket itself)
+func (s *S3) newRequest(ctx context.Context, method, key string, query url.Values, body io.ReadCloser) (*http.Request, error) {
+	u, err := url.Parse(s.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("s3: invalid endpoint: %w", err)
+	}
+	objectPath := "/" + key
+	if s.PathStyle {
+		objectPath = "/" + s.Bucket + objectPath
+	} else {
+		u.Host = s.Bucket + "." + u.Host
+	}
+	u.Path = objectPath
+	u.RawPath = uriEncode(objectPath, false)
+	u.RawQuery = canonicalQuery(query)
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Body = body
+	}
+	return req, nil
+}
+
+// do signs and sends the request, turning error responses into ErrNotFound or *S3Error
+func (s *S3) do(req *http.Request, payloadHash string) (*http.Response, error) {
+	s.sign(req, payloadHash, time.Now().UTC())
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	s3err := &S3Error{StatusCode: resp.StatusCode}
+	xml.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(s3err)
+	// HEAD responses have no body that would tell a missing key from a missing bucket
+	if s3err.Code == "NoSuchKey" || resp.StatusCode == http.StatusNotFound && req.Method == http.MethodHead {
+		return nil, ErrNotFound
+	}
+	return nil, s3err
+}
+
+// doXML sends the request and decodes the XML response into v, if not nil. Some calls report
+// errors in the body of a 200 response, so an Error document is always checked for.
+func (s *S3) doXML(req *http.Request, payloadHash string, v interface{}) error {
+	resp, err := s.do(req, payloadHash)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	var probe struct {
+		XMLName xml.Name
+		S3Error
+	}
+	if xml.Unmarshal(body, &probe) == nil && probe.XMLName.Local == "Error" {
+		probe.S3Error.StatusCode = resp.StatusCode
+		return &probe.S3Error
+	}
+	if v == nil {
+		return nil
+	}
+	if err := xml.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("s3: decoding response: %w", err)
+	}
+	return nil
+}
+
+// sign adds the Signature Version 4 Authorization header
+func (s *S3) sign(req *http.Request, payloadHash string, now time.Time) {
+	amzDate := now.Format(amzDateFormat)
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if lower == "content-type" || strings.HasPrefix(lower, "x-amz-") {
+			headers[lower] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := date + "/" + s.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hexSHA256(canonicalRequest)
+
+	key := hmacSHA256([]byte("AWS4"+s.SecretAccessKey), date)
+	key = hmacSHA256(key, s.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+s.AccessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+// canonicalQuery encodes the query sorted by name, as both the URL and the signature use it
+func canonicalQuery(query url.Values) string {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var parts []string
+	for _, name := range names {
+		values := append([]string(nil), query[name]...)
+		sort.Strings(values)
+		for _, v := range values {
+			parts = append(parts, uriEncode(name, true)+"="+uriEncode(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// uriEncode percent-encodes everything but unreserved characters (RFC 3986), and
+// slashes too unless encodeSlash is set, as SigV4 requires
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' || c == '/' && !encodeSlash {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func responseInfo(key string, resp *http.Response) Info {
+	info := Info{Key: key, Size: resp.ContentLength, ContentType: resp.Header.Get("Content-Type")}
+	info.ModTime, _ = http.ParseTime(resp.Header.Get("Last-Modified"))
+	return info
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func hexSHA256(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,15 +1,19 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
 
+	"pets_project/internal/db/migrations"
+
 	_ "github.com/lib/pq"
 )
 
-// InitDB initializes and returns a database connection
-func InitDB(connStr string) *sql.DB {
+// InitDB initializes and returns a database connection.
+// When migrate is true, pending schema migrations are applied before returning.
+func InitDB(connStr string, migrate bool) *sql.DB {
 	db, err := sql.Open("postgres", connStr)
 	if err != nil {
 		log.Fatalf("ERROR: Failed to open database connection: %v", err)
@@ -21,5 +25,13 @@ func InitDB(connStr string) *sql.DB {
 	}
 
 	fmt.Println("INFO: Successfully connected to the database.")
+
+	if migrate {
+		applied, err := migrations.Up(context.Background(), db)
+		if err != nil {
+			log.Fatalf("ERROR: Failed to apply database migrations: %v", err)
+		}
+		fmt.Printf("INFO: Database schema up to date (%d migration(s) applied).\n", applied)
+	}
 	return db
 }
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS file_records;
+DROP TABLE IF EXISTS appointments;
+DROP TABLE IF EXISTS pets;
+DROP TABLE IF EXISTS owners;
+DROP TABLE IF EXISTS users;
@@ -1,12 +1,14 @@
+-- Baseline schema. IF NOT EXISTS lets databases that were set up by hand adopt migrations.
+
 -- USERS TABLE
-CREATE TABLE users (
+CREATE TABLE IF NOT EXISTS users (
     id SERIAL PRIMARY KEY,
     email TEXT UNIQUE NOT NULL,
     password_hash TEXT NOT NULL
 );
 
 -- OWNERS TABLE
-CREATE TABLE owners (
+CREATE TABLE IF NOT EXISTS owners (
     id SERIAL PRIMARY KEY,
     name TEXT NOT NULL,
     contact TEXT,
@@ -14,7 +16,7 @@ CREATE TABLE owners (
 );
 
 -- PETS TABLE
-CREATE TABLE pets (
+CREATE TABLE IF NOT EXISTS pets (
     id SERIAL PRIMARY KEY,
     name TEXT NOT NULL,
     species TEXT,
@@ -24,7 +26,7 @@ CREATE TABLE pets (
 );
 
 -- APPOINTMENTS TABLE
-CREATE TABLE appointments (
+CREATE TABLE IF NOT EXISTS appointments (
     id SERIAL PRIMARY KEY,
     pet_id INT REFERENCES pets(id) ON DELETE CASCADE,
     appointment_date TEXT,
@@ -33,7 +35,7 @@ CREATE TABLE appointments (
 );
 
 -- FILE UPLOAD TABLE
-CREATE TABLE file_records (
+CREATE TABLE IF NOT EXISTS file_records (
     id SERIAL PRIMARY KEY,
     pet_id INT REFERENCES pets(id) ON DELETE CASCADE,
     file_name TEXT NOT NULL,
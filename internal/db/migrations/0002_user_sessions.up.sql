@@ -0,0 +1,17 @@
+-- USER SESSIONS TABLE (one row per login; revoking it invalidates its access and refresh tokens)
+CREATE TABLE user_sessions (
+    id TEXT PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    revoked_at TIMESTAMPTZ
+);
+
+-- REFRESH TOKENS TABLE (only the SHA-256 hash of each token is stored)
+CREATE TABLE refresh_tokens (
+    id SERIAL PRIMARY KEY,
+    session_id TEXT NOT NULL REFERENCES user_sessions(id) ON DELETE CASCADE,
+    token_hash TEXT UNIQUE NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
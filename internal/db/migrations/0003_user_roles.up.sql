@@ -0,0 +1,6 @@
+ALTER TABLE users
+    ADD COLUMN role TEXT NOT NULL DEFAULT 'owner'
+    CHECK (role IN ('admin', 'vet', 'receptionist', 'owner'));
+
+-- Promote the first administrator of a clinic by hand; afterwards use PUT /admin/users/{id}/role
+-- UPDATE users SET role = 'admin' WHERE email = 'admin@example.com';
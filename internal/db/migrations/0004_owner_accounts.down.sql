@@ -0,0 +1 @@
+ALTER TABLE owners DROP COLUMN IF EXISTS user_id;
@@ -0,0 +1,2 @@
+-- Login account of the pet owner
+ALTER TABLE owners ADD COLUMN user_id INT UNIQUE REFERENCES users(id) ON DELETE SET NULL;
@@ -0,0 +1,6 @@
+ALTER TABLE file_records DROP COLUMN IF EXISTS clinic_id;
+ALTER TABLE appointments DROP COLUMN IF EXISTS clinic_id;
+ALTER TABLE pets DROP COLUMN IF EXISTS clinic_id;
+ALTER TABLE owners DROP COLUMN IF EXISTS clinic_id;
+ALTER TABLE users DROP COLUMN IF EXISTS clinic_id;
+DROP TABLE IF EXISTS clinics;
@@ -0,0 +1,35 @@
+-- CLINICS TABLE (each clinic location is a tenant; every other table is scoped by clinic_id)
+CREATE TABLE clinics (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    address TEXT,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Existing data is assigned to the first clinic
+INSERT INTO clinics (name) VALUES ('Main Clinic');
+
+ALTER TABLE users ADD COLUMN clinic_id INT REFERENCES clinics(id);
+ALTER TABLE owners ADD COLUMN clinic_id INT REFERENCES clinics(id);
+ALTER TABLE pets ADD COLUMN clinic_id INT REFERENCES clinics(id);
+ALTER TABLE appointments ADD COLUMN clinic_id INT REFERENCES clinics(id);
+ALTER TABLE file_records ADD COLUMN clinic_id INT REFERENCES clinics(id);
+
+UPDATE users SET clinic_id = (SELECT MIN(id) FROM clinics);
+UPDATE owners SET clinic_id = (SELECT MIN(id) FROM clinics);
+UPDATE pets SET clinic_id = (SELECT MIN(id) FROM clinics);
+UPDATE appointments SET clinic_id = (SELECT MIN(id) FROM clinics);
+UPDATE file_records SET clinic_id = (SELECT MIN(id) FROM clinics);
+
+ALTER TABLE users ALTER COLUMN clinic_id SET NOT NULL;
+ALTER TABLE owners ALTER COLUMN clinic_id SET NOT NULL;
+ALTER TABLE pets ALTER COLUMN clinic_id SET NOT NULL;
+ALTER TABLE appointments ALTER COLUMN clinic_id SET NOT NULL;
+ALTER TABLE file_records ALTER COLUMN clinic_id SET NOT NULL;
+
+-- Tenant lookups filter every query by clinic_id
+CREATE INDEX idx_users_clinic ON users(clinic_id);
+CREATE INDEX idx_owners_clinic ON owners(clinic_id);
+CREATE INDEX idx_pets_clinic ON pets(clinic_id);
+CREATE INDEX idx_appointments_clinic ON appointments(clinic_id);
+CREATE INDEX idx_file_records_clinic ON file_records(clinic_id);
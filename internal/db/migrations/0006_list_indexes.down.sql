@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_appointments_clinic_date;
+DROP INDEX IF EXISTS idx_appointments_clinic_pet;
+DROP INDEX IF EXISTS idx_owners_clinic_name;
+DROP INDEX IF EXISTS idx_pets_clinic_name;
+DROP INDEX IF EXISTS idx_pets_clinic_owner;
@@ -0,0 +1,6 @@
+-- Indexes backing the filters and keyset pagination of the list endpoints
+CREATE INDEX IF NOT EXISTS idx_pets_clinic_owner ON pets (clinic_id, owner_id, id);
+CREATE INDEX IF NOT EXISTS idx_pets_clinic_name ON pets (clinic_id, name, id);
+CREATE INDEX IF NOT EXISTS idx_owners_clinic_name ON owners (clinic_id, name, id);
+CREATE INDEX IF NOT EXISTS idx_appointments_clinic_pet ON appointments (clinic_id, pet_id, id);
+CREATE INDEX IF NOT EXISTS idx_appointments_clinic_date ON appointments (clinic_id, appointment_date, id);
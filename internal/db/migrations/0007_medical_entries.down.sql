@@ -0,0 +1,15 @@
+ALTER TABLE pets ADD COLUMN medical_history TEXT;
+
+-- Fold the entries back into the free-text column, oldest first
+UPDATE pets p
+SET medical_history = h.history
+FROM (
+    SELECT pet_id,
+           string_agg(occurred_on || ' ' || entry_type || ': ' || description, E'\n' ORDER BY occurred_on, id) AS history
+    FROM medical_entries
+    GROUP BY pet_id
+) h
+WHERE h.pet_id = p.id;
+
+DROP TABLE IF EXISTS medical_entries;
+DROP FUNCTION IF EXISTS medical_entries_reject_update();
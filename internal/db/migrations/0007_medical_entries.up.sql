@@ -0,0 +1,35 @@
+-- Append-only medical history replacing the free-text pets.medical_history column
+CREATE TABLE medical_entries (
+    id SERIAL PRIMARY KEY,
+    clinic_id INT NOT NULL REFERENCES clinics(id),
+    pet_id INT NOT NULL REFERENCES pets(id) ON DELETE CASCADE,
+    entry_type TEXT NOT NULL CHECK (entry_type IN ('diagnosis', 'treatment', 'note')),
+    description TEXT NOT NULL,
+    occurred_on DATE NOT NULL DEFAULT CURRENT_DATE,
+    author_user_id INT REFERENCES users(id) ON DELETE SET NULL,
+    amends_id INT REFERENCES medical_entries(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_medical_entries_pet ON medical_entries (pet_id, occurred_on, id);
+
+-- Entries are never edited; corrections are appended with amends_id set.
+-- Deletes stay possible so removing a pet still cascades.
+CREATE FUNCTION medical_entries_reject_update() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'medical_entries is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER medical_entries_append_only
+    BEFORE UPDATE ON medical_entries
+    FOR EACH ROW EXECUTE FUNCTION medical_entries_reject_update();
+
+-- Keep existing free-text history as each pet's first entry
+INSERT INTO medical_entries (clinic_id, pet_id, entry_type, description)
+SELECT clinic_id, id, 'note', medical_history
+FROM pets
+WHERE medical_history IS NOT NULL AND btrim(medical_history) <> ''
+ORDER BY id;
+
+ALTER TABLE pets DROP COLUMN medical_history;
@@ -0,0 +1,15 @@
+CREATE TABLE vaccinations (
+    id SERIAL PRIMARY KEY,
+    clinic_id INT NOT NULL REFERENCES clinics(id),
+    pet_id INT NOT NULL REFERENCES pets(id) ON DELETE CASCADE,
+    vaccine TEXT NOT NULL,
+    lot_number TEXT NOT NULL DEFAULT '',
+    administered_on DATE NOT NULL,
+    next_due_on DATE,
+    administered_by INT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (next_due_on IS NULL OR next_due_on >= administered_on)
+);
+
+CREATE INDEX idx_vaccinations_pet ON vaccinations (pet_id, administered_on);
+CREATE INDEX idx_vaccinations_due ON vaccinations (clinic_id, next_due_on);
@@ -0,0 +1,20 @@
+ALTER TABLE appointments ADD COLUMN appointment_date TEXT;
+ALTER TABLE appointments ADD COLUMN appointment_time TEXT;
+
+UPDATE appointments
+SET appointment_date = to_char(starts_at, 'YYYY-MM-DD'),
+    appointment_time = to_char(starts_at, 'HH24:MI');
+
+INSERT INTO appointments (id, clinic_id, pet_id, appointment_date, appointment_time, reason, starts_at)
+SELECT id, clinic_id, pet_id, appointment_date, appointment_time, reason, NOW()
+FROM appointments_unscheduled;
+
+DROP INDEX IF EXISTS idx_appointments_vet_starts;
+DROP INDEX IF EXISTS idx_appointments_pet_starts;
+DROP INDEX IF EXISTS idx_appointments_clinic_starts;
+CREATE INDEX IF NOT EXISTS idx_appointments_clinic_date ON appointments (clinic_id, appointment_date, id);
+
+DROP TABLE appointments_unscheduled;
+ALTER TABLE appointments DROP COLUMN vet_id;
+ALTER TABLE appointments DROP COLUMN duration_minutes;
+ALTER TABLE appointments DROP COLUMN starts_at;
@@ -0,0 +1,53 @@
+-- Appointments get a real start time, a duration and an optional vet (a user with the vet role).
+-- Legacy TEXT date/time values are parsed in the session time zone; rows that cannot be
+-- parsed are moved to appointments_unscheduled so staff can rebook them by hand.
+ALTER TABLE appointments ADD COLUMN starts_at TIMESTAMPTZ;
+ALTER TABLE appointments ADD COLUMN duration_minutes INT NOT NULL DEFAULT 30
+    CHECK (duration_minutes > 0 AND duration_minutes <= 1440);
+ALTER TABLE appointments ADD COLUMN vet_id INT REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE TABLE appointments_unscheduled (
+    id INT PRIMARY KEY,
+    clinic_id INT NOT NULL REFERENCES clinics(id),
+    pet_id INT REFERENCES pets(id) ON DELETE CASCADE,
+    appointment_date TEXT,
+    appointment_time TEXT,
+    reason TEXT,
+    archived_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+DO $$
+DECLARE
+    a RECORD;
+BEGIN
+    -- Only ISO dates and HH:MM[:SS] times; Postgres would also accept words like 'tomorrow'
+    FOR a IN
+        SELECT id, appointment_date, appointment_time FROM appointments
+        WHERE btrim(appointment_date) ~ '^\d{4}-\d{2}-\d{2}$'
+          AND btrim(appointment_time) ~ '^\d{1,2}:\d{2}(:\d{2})?$'
+    LOOP
+        BEGIN
+            UPDATE appointments
+            SET starts_at = (btrim(a.appointment_date) || ' ' || btrim(a.appointment_time))::timestamptz
+            WHERE id = a.id;
+        EXCEPTION WHEN others THEN
+            NULL; -- left NULL and archived below
+        END;
+    END LOOP;
+END;
+$$;
+
+INSERT INTO appointments_unscheduled (id, clinic_id, pet_id, appointment_date, appointment_time, reason)
+SELECT id, clinic_id, pet_id, appointment_date, appointment_time, reason
+FROM appointments WHERE starts_at IS NULL;
+
+DELETE FROM appointments WHERE starts_at IS NULL;
+
+ALTER TABLE appointments ALTER COLUMN starts_at SET NOT NULL;
+ALTER TABLE appointments DROP COLUMN appointment_date;
+ALTER TABLE appointments DROP COLUMN appointment_time;
+
+DROP INDEX IF EXISTS idx_appointments_clinic_date;
+CREATE INDEX idx_appointments_clinic_starts ON appointments (clinic_id, starts_at, id);
+CREATE INDEX idx_appointments_pet_starts ON appointments (pet_id, starts_at);
+CREATE INDEX idx_appointments_vet_starts ON appointments (vet_id, starts_at) WHERE vet_id IS NOT NULL;
@@ -0,0 +1,8 @@
+ALTER TABLE appointments DROP CONSTRAINT appointments_vet_id_fkey;
+UPDATE appointments a SET vet_id = s.user_id FROM staff s WHERE s.id = a.vet_id;
+ALTER TABLE appointments ADD CONSTRAINT appointments_vet_id_fkey
+    FOREIGN KEY (vet_id) REFERENCES users(id) ON DELETE SET NULL;
+
+DROP TABLE IF EXISTS staff_working_hours;
+DROP TABLE IF EXISTS staff;
+ALTER TABLE clinics DROP COLUMN IF EXISTS timezone;
@@ -0,0 +1,35 @@
+-- Clinic staff with weekly working hours. Appointments are assigned to a staff member
+-- with the vet role instead of directly to a login account.
+ALTER TABLE clinics ADD COLUMN timezone TEXT NOT NULL DEFAULT 'UTC';
+
+CREATE TABLE staff (
+    id SERIAL PRIMARY KEY,
+    clinic_id INT NOT NULL REFERENCES clinics(id),
+    user_id INT UNIQUE REFERENCES users(id) ON DELETE SET NULL,
+    name TEXT NOT NULL,
+    role TEXT NOT NULL CHECK (role IN ('vet', 'nurse', 'technician', 'receptionist')),
+    specialties TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_staff_clinic ON staff (clinic_id, id);
+
+-- weekday follows Go's time.Weekday: 0 = Sunday ... 6 = Saturday; times are clinic local time
+CREATE TABLE staff_working_hours (
+    staff_id INT NOT NULL REFERENCES staff(id) ON DELETE CASCADE,
+    weekday SMALLINT NOT NULL CHECK (weekday BETWEEN 0 AND 6),
+    start_time TIME NOT NULL,
+    end_time TIME NOT NULL,
+    PRIMARY KEY (staff_id, weekday, start_time),
+    CHECK (end_time > start_time)
+);
+
+-- Every vet account, and any account already assigned to appointments, becomes a vet staff member
+INSERT INTO staff (clinic_id, user_id, name, role)
+SELECT clinic_id, id, email, 'vet' FROM users
+WHERE role = 'vet' OR id IN (SELECT vet_id FROM appointments WHERE vet_id IS NOT NULL)
+ORDER BY id;
+
+ALTER TABLE appointments DROP CONSTRAINT appointments_vet_id_fkey;
+UPDATE appointments a SET vet_id = s.id FROM staff s WHERE s.user_id = a.vet_id;
+ALTER TABLE appointments ADD CONSTRAINT appointments_vet_id_fkey
+    FOREIGN KEY (vet_id) REFERENCES staff(id) ON DELETE SET NULL;
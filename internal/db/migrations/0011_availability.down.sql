@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS blocked_periods;
+DROP TABLE IF EXISTS clinic_hours;
+DROP TABLE IF EXISTS appointment_types;
@@ -0,0 +1,31 @@
+-- Inputs of the availability search: bookable appointment types, clinic opening
+-- hours and periods in which the clinic or a staff member cannot be booked.
+CREATE TABLE appointment_types (
+    id SERIAL PRIMARY KEY,
+    clinic_id INT NOT NULL REFERENCES clinics(id),
+    name TEXT NOT NULL,
+    duration_minutes INT NOT NULL CHECK (duration_minutes > 0 AND duration_minutes <= 1440),
+    UNIQUE (clinic_id, name)
+);
+
+-- Same shape as staff_working_hours: weekday 0 = Sunday, clinic local time
+CREATE TABLE clinic_hours (
+    clinic_id INT NOT NULL REFERENCES clinics(id) ON DELETE CASCADE,
+    weekday SMALLINT NOT NULL CHECK (weekday BETWEEN 0 AND 6),
+    start_time TIME NOT NULL,
+    end_time TIME NOT NULL,
+    PRIMARY KEY (clinic_id, weekday, start_time),
+    CHECK (end_time > start_time)
+);
+
+-- staff_id NULL blocks the whole clinic (holidays, closures)
+CREATE TABLE blocked_periods (
+    id SERIAL PRIMARY KEY,
+    clinic_id INT NOT NULL REFERENCES clinics(id),
+    staff_id INT REFERENCES staff(id) ON DELETE CASCADE,
+    starts_at TIMESTAMPTZ NOT NULL,
+    ends_at TIMESTAMPTZ NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    CHECK (ends_at > starts_at)
+);
+CREATE INDEX idx_blocked_periods_clinic ON blocked_periods (clinic_id, starts_at);
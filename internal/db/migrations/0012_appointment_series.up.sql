@@ -0,0 +1,11 @@
+-- Recurring appointments: the rule is kept for reference, occurrences are
+-- materialised as ordinary appointments pointing at their series.
+CREATE TABLE appointment_series (
+    id SERIAL PRIMARY KEY,
+    clinic_id INT NOT NULL REFERENCES clinics(id),
+    rrule TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE appointments ADD COLUMN series_id INT REFERENCES appointment_series(id) ON DELETE SET NULL;
+CREATE INDEX idx_appointments_series ON appointments (series_id, starts_at) WHERE series_id IS NOT NULL;
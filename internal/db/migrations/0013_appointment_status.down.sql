@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS appointment_status_events;
+DROP INDEX IF EXISTS idx_appointments_clinic_status;
+ALTER TABLE appointments DROP COLUMN IF EXISTS status;
@@ -0,0 +1,15 @@
+-- Appointment lifecycle: every status change is recorded with the acting user
+ALTER TABLE appointments ADD COLUMN status TEXT NOT NULL DEFAULT 'scheduled'
+    CHECK (status IN ('scheduled', 'checked_in', 'in_progress', 'completed', 'cancelled', 'no_show'));
+
+CREATE TABLE appointment_status_events (
+    id SERIAL PRIMARY KEY,
+    appointment_id INT NOT NULL REFERENCES appointments(id) ON DELETE CASCADE,
+    from_status TEXT NOT NULL,
+    to_status TEXT NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    user_id INT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX idx_appointment_status_events_appointment ON appointment_status_events (appointment_id, id);
+CREATE INDEX idx_appointments_clinic_status ON appointments (clinic_id, status, starts_at);
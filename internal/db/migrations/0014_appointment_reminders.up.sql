@@ -0,0 +1,9 @@
+-- One row per reminder: claimed before sending, marked sent afterwards.
+-- Keyed by the start time too, so a rescheduled appointment is reminded again.
+CREATE TABLE appointment_reminders (
+    appointment_id INT NOT NULL REFERENCES appointments(id) ON DELETE CASCADE,
+    starts_at TIMESTAMPTZ NOT NULL,
+    claimed_at TIMESTAMPTZ NOT NULL,
+    sent_at TIMESTAMPTZ,
+    PRIMARY KEY (appointment_id, starts_at)
+);
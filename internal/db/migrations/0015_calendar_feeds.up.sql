@@ -0,0 +1,7 @@
+-- One secret calendar feed token per user, stored hashed like refresh tokens.
+-- Creating a new one replaces the old, which stops working at once.
+CREATE TABLE calendar_feed_tokens (
+    user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
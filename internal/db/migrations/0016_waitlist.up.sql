@@ -0,0 +1,24 @@
+-- Pets waiting for an appointment when no suitable slot is free. When a booking is
+-- cancelled or deleted its slot is offered to the first matching entry (highest
+-- priority, then oldest); the offer is then accepted, which books it, or declined.
+CREATE TABLE waitlist_entries (
+    id SERIAL PRIMARY KEY,
+    clinic_id INT NOT NULL REFERENCES clinics(id),
+    pet_id INT NOT NULL REFERENCES pets(id) ON DELETE CASCADE,
+    vet_id INT REFERENCES staff(id) ON DELETE SET NULL,
+    earliest_date DATE NOT NULL,
+    latest_date DATE NOT NULL,
+    duration_minutes INT NOT NULL CHECK (duration_minutes > 0 AND duration_minutes <= 1440),
+    priority INT NOT NULL DEFAULT 0,
+    notes TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'waiting' CHECK (status IN ('waiting', 'offered', 'booked')),
+    offered_starts_at TIMESTAMPTZ,
+    offered_duration_minutes INT,
+    offered_vet_id INT REFERENCES staff(id) ON DELETE SET NULL,
+    offered_at TIMESTAMPTZ,
+    appointment_id INT REFERENCES appointments(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (latest_date >= earliest_date),
+    CHECK ((status = 'offered') = (offered_starts_at IS NOT NULL))
+);
+CREATE INDEX idx_waitlist_entries_queue ON waitlist_entries (clinic_id, status, priority DESC, created_at);
@@ -0,0 +1 @@
+UPDATE file_records SET file_path = 'uploads/' || file_path;
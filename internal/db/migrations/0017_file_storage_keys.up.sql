@@ -0,0 +1,4 @@
+-- file_path now holds the key of the file in the blob store rather than a path on the
+-- API server's disk. The local store is rooted at the old ./uploads directory, so keys
+-- of existing files are their paths without that directory.
+UPDATE file_records SET file_path = regexp_replace(file_path, '^(\./)?uploads/', '');
@@ -0,0 +1,2 @@
+ALTER TABLE file_records DROP COLUMN IF EXISTS sha256;
+ALTER TABLE file_records DROP COLUMN IF EXISTS size_bytes;
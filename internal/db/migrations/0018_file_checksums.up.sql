@@ -0,0 +1,4 @@
+-- Size and SHA-256 digest of each file, computed while it is uploaded.
+-- Files uploaded earlier have neither.
+ALTER TABLE file_records ADD COLUMN size_bytes BIGINT;
+ALTER TABLE file_records ADD COLUMN sha256 TEXT;
@@ -0,0 +1,18 @@
+-- Resumable (tus) uploads in progress. Chunks are stored as separate blobs listed in
+-- chunk_keys until the upload is complete and joined into a file record.
+CREATE TABLE uploads (
+    id TEXT PRIMARY KEY,
+    clinic_id INT NOT NULL REFERENCES clinics(id),
+    pet_id INT NOT NULL REFERENCES pets(id) ON DELETE CASCADE,
+    file_name TEXT NOT NULL,
+    content_type TEXT NOT NULL DEFAULT '',
+    upload_length BIGINT NOT NULL CHECK (upload_length >= 0),
+    upload_offset BIGINT NOT NULL DEFAULT 0,
+    chunk_keys TEXT[] NOT NULL DEFAULT '{}',
+    file_id INT REFERENCES file_records(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    CHECK (upload_offset BETWEEN 0 AND upload_length)
+);
+
+CREATE INDEX idx_uploads_expires_at ON uploads(expires_at);
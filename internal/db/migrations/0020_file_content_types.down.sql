@@ -0,0 +1 @@
+ALTER TABLE file_records DROP COLUMN IF EXISTS content_type;
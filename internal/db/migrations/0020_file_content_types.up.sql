@@ -0,0 +1,3 @@
+-- MIME type detected from each file's content when it was uploaded. Files uploaded
+-- earlier have none and are served as application/octet-stream.
+ALTER TABLE file_records ADD COLUMN content_type TEXT;
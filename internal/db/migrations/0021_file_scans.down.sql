@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_file_records_pending_scan;
+ALTER TABLE file_records DROP COLUMN IF EXISTS scanned_at;
+ALTER TABLE file_records DROP COLUMN IF EXISTS scan_signature;
+ALTER TABLE file_records DROP COLUMN IF EXISTS scan_status;
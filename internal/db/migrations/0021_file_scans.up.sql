@@ -0,0 +1,9 @@
+-- Antivirus scan state of each file. New files are 'pending' until scanned when a scanner
+-- is configured; files uploaded earlier or without a scanner are 'unscanned'.
+-- Pending and infected (quarantined) files cannot be downloaded.
+ALTER TABLE file_records ADD COLUMN scan_status TEXT NOT NULL DEFAULT 'unscanned'
+    CHECK (scan_status IN ('unscanned', 'pending', 'clean', 'infected'));
+ALTER TABLE file_records ADD COLUMN scan_signature TEXT;
+ALTER TABLE file_records ADD COLUMN scanned_at TIMESTAMPTZ;
+
+CREATE INDEX idx_file_records_pending_scan ON file_records(uploaded_at) WHERE scan_status = 'pending';
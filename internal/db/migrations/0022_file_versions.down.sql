@@ -0,0 +1,4 @@
+ALTER TABLE uploads DROP COLUMN IF EXISTS document_id;
+ALTER TABLE file_records DROP CONSTRAINT IF EXISTS file_records_document_version_key;
+ALTER TABLE file_records DROP COLUMN IF EXISTS version;
+ALTER TABLE file_records DROP COLUMN IF EXISTS document_id;
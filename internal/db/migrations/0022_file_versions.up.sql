@@ -0,0 +1,11 @@
+-- Each file record is a version of a document. document_id is the id of the document's
+-- first version (not a foreign key, since versions can be deleted one by one); uploading
+-- a corrected file adds the next version, and the highest version is the current one.
+ALTER TABLE file_records ADD COLUMN document_id INT;
+ALTER TABLE file_records ADD COLUMN version INT NOT NULL DEFAULT 1;
+UPDATE file_records SET document_id = id;
+ALTER TABLE file_records ALTER COLUMN document_id SET NOT NULL;
+ALTER TABLE file_records ADD CONSTRAINT file_records_document_version_key UNIQUE (document_id, version);
+
+-- A resumable upload may be a new version of an existing document
+ALTER TABLE uploads ADD COLUMN document_id INT;
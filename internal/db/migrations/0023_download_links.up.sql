@@ -0,0 +1,8 @@
+-- Signed download links are not stored; only single-use links that were used are
+-- remembered, until they expire.
+CREATE TABLE used_download_links (
+    nonce TEXT PRIMARY KEY,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_used_download_links_expires_at ON used_download_links(expires_at);
@@ -0,0 +1,3 @@
+-- finishing_at marks a complete upload whose chunks a request is joining into a file
+-- record, so that a repeated PATCH does not create a second record meanwhile
+ALTER TABLE uploads ADD COLUMN finishing_at TIMESTAMPTZ;
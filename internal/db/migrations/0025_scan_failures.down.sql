@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_file_records_pending_scan;
+CREATE INDEX idx_file_records_pending_scan ON file_records(uploaded_at) WHERE scan_status = 'pending';
+ALTER TABLE file_records DROP COLUMN IF EXISTS scan_attempted_at;
+UPDATE file_records SET scan_status = 'pending', scan_signature = NULL, scanned_at = NULL WHERE scan_status = 'failed';
+ALTER TABLE file_records DROP CONSTRAINT IF EXISTS file_records_scan_status_check;
+ALTER TABLE file_records ADD CONSTRAINT file_records_scan_status_check
+    CHECK (scan_status IN ('unscanned', 'pending', 'clean', 'infected'));
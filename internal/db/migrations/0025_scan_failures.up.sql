@@ -0,0 +1,14 @@
+-- Files the scanner refuses, such as those above clamd's StreamMaxLength, are 'failed'
+-- rather than pending forever; scan_signature then holds the reason. Like infected files
+-- they cannot be downloaded.
+ALTER TABLE file_records DROP CONSTRAINT IF EXISTS file_records_scan_status_check;
+ALTER TABLE file_records ADD CONSTRAINT file_records_scan_status_check
+    CHECK (scan_status IN ('unscanned', 'pending', 'clean', 'infected', 'failed'));
+
+-- Retries take the files attempted least recently first, so files that keep failing
+-- cannot fill every batch
+ALTER TABLE file_records ADD COLUMN scan_attempted_at TIMESTAMPTZ;
+
+DROP INDEX IF EXISTS idx_file_records_pending_scan;
+CREATE INDEX idx_file_records_pending_scan ON file_records(scan_attempted_at NULLS FIRST, uploaded_at)
+    WHERE scan_status = 'pending';
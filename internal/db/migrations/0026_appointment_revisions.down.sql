@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS appointments_revise ON appointments;
+DROP FUNCTION IF EXISTS appointments_revise();
+ALTER TABLE appointments DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE appointments DROP COLUMN IF EXISTS revision;
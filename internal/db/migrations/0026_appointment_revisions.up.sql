@@ -0,0 +1,18 @@
+-- Every change to an appointment bumps its revision and updated_at. Calendar feeds publish
+-- them as SEQUENCE and LAST-MODIFIED, which calendar apps need before they replace the copy
+-- of an event they already hold.
+ALTER TABLE appointments ADD COLUMN revision INT NOT NULL DEFAULT 0;
+ALTER TABLE appointments ADD COLUMN updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+CREATE FUNCTION appointments_revise() RETURNS trigger AS $$
+BEGIN
+    NEW.revision := OLD.revision + 1;
+    NEW.updated_at := NOW();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER appointments_revise
+    BEFORE UPDATE ON appointments
+    FOR EACH ROW WHEN (OLD.* IS DISTINCT FROM NEW.*)
+    EXECUTE FUNCTION appointments_revise();
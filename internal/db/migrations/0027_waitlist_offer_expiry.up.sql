@@ -0,0 +1,3 @@
+-- Offers that are not answered in time go back to the waitlist; the server looks for
+-- them every few minutes
+CREATE INDEX idx_waitlist_entries_offered ON waitlist_entries (offered_at) WHERE status = 'offered';
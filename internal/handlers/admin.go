@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// === Admin User Handlers ==========================================================
+// AdminUsersHandler (capitalized) is the mini-router. Exported to main.go.
+//
+//	GET /admin/users               list users and their roles
+//	PUT /admin/users/{id}/role     assign a role {"role": "vet"}
+func (env *Env) AdminUsersHandler(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+
+	if path == "/admin/users" {
+		switch r.Method {
+		case "GET":
+			env.getAllUsers(w, r)
+		default:
+			http.Error(w, "Method not allowed for /admin/users", http.StatusMethodNotAllowed)
+		}
+	} else if strings.HasPrefix(path, "/admin/users/") && strings.HasSuffix(path, "/role") {
+		idStr := strings.TrimSuffix(strings.TrimPrefix(path, "/admin/users/"), "/role")
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			http.Error(w, "Invalid ID in path", http.StatusBadRequest)
+			return
+		}
+		switch r.Method {
+		case "PUT":
+			env.updateUserRole(w, r, id)
+		default:
+			http.Error(w, "Method not allowed for /admin/users/{id}/role", http.StatusMethodNotAllowed)
+		}
+	} else {
+		http.Error(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// --- Admin User Functions (internal) ---
+func (env *Env) getAllUsers(w http.ResponseWriter, r *http.Request) {
+	clinicID, _ := r.Context().Value("clinicID").(int)
+	users, err := env.Users.ListByClinic(r.Context(), clinicID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(users)
+}
+
+func (env *Env) updateUserRole(w http.ResponseWriter, r *http.Request, id int) {
+	var body struct {
+		Role string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !isValidRole(body.Role) {
+		http.Error(w, "role must be one of admin, vet, receptionist, owner", http.StatusBadRequest)
+		return
+	}
+
+	// Guard against an admin locking themselves out
+	adminID, _ := r.Context().Value("userID").(int)
+	if adminID == id {
+		http.Error(w, "You cannot change your own role", http.StatusBadRequest)
+		return
+	}
+
+	// Admins only manage accounts of their own clinic
+	clinicID, _ := r.Context().Value("clinicID").(int)
+	u, err := env.Users.UpdateRole(r.Context(), clinicID, id, body.Role)
+	if err != nil {
+		writeStoreError(w, err, "User not found")
+		return
+	}
+
+	// Roles travel inside access tokens, so end existing sessions to apply the change now
+	if err := env.Sessions.RevokeAllForUser(r.Context(), id); err != nil {
+		Error("Failed to revoke sessions for user ID %d after role change: %v", id, err)
+		http.Error(w, "Role updated but existing sessions could not be revoked", http.StatusInternalServerError)
+		return
+	}
+
+	Info("Admin user ID %d set role of user ID %d to %s", adminID, id, u.Role)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(u)
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"pets_project/internal/models"
+	"pets_project/internal/recurrence"
+)
+
+// === Recurring Appointments =======================================================
+// A POST /appointments body with an "rrule" (e.g. "FREQ=WEEKLY;COUNT=8") books every
+// occurrence at once. PUT and DELETE /appointments/{id} and POST /appointments/{id}/cancel
+// act on that occurrence only, or with ?scope=following on it and every later occurrence
+// of the same series.
+
+// occurrenceScope reads ?scope=this|following and reports whether following was asked for
+func occurrenceScope(w http.ResponseWriter, r *http.Request) (bool, bool) {
+	switch r.URL.Query().Get("scope") {
+	case "", "this":
+		return false, true
+	case "following":
+		return true, true
+	}
+	http.Error(w, "scope must be this or following", http.StatusBadRequest)
+	return false, false
+}
+
+// --- Recurring Appointment Functions (internal) ---
+// createAppointmentSeries expands the rule in the clinic's time zone and books every occurrence
+func (env *Env) createAppointmentSeries(w http.ResponseWriter, r *http.Request, scope accessScope, a models.Appointment, rrule string) {
+	rule, err := recurrence.Parse(rrule)
+	if err != nil {
+		http.Error(w, "Invalid rrule: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	loc, ok := env.clinicLocation(w, r, scope.ClinicID)
+	if !ok {
+		return
+	}
+	start, _ := time.Parse(time.RFC3339, a.StartsAt)
+	starts, err := rule.Expand(start.In(loc))
+	if err != nil {
+		http.Error(w, "Invalid rrule: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	series := models.AppointmentSeries{RRule: rule.String()}
+	for _, t := range starts {
+		occurrence := a
+		occurrence.StartsAt = t.Format(time.RFC3339)
+		series.Appointments = append(series.Appointments, occurrence)
+	}
+	if err := env.Appointments.CreateSeries(r.Context(), scope.Scope, &series); err != nil {
+		env.writeBookingError(w, r, scope, err)
+		return
+	}
+
+	Info("User ID %d booked series ID %d with %d appointments (%s)", scope.UserID, series.ID, len(series.Appointments), series.RRule)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(series)
+}
+
+// updateFollowingAppointments applies the changes made to one occurrence to it and every
+// later occurrence that is still scheduled. A new start time moves them all by the same
+// number of days and to the same local time of day.
+func (env *Env) updateFollowingAppointments(w http.ResponseWriter, r *http.Request, scope accessScope, id int, changes models.Appointment) {
+	following, err := env.Appointments.Following(r.Context(), scope.Scope, id)
+	if err != nil {
+		writeStoreError(w, err, "Appointment not found")
+		return
+	}
+	if following[0].Status != models.AppointmentScheduled {
+		http.Error(w, "Only scheduled appointments can be changed", http.StatusConflict)
+		return
+	}
+	var occurrences []models.Appointment
+	for _, o := range following {
+		if o.Status == models.AppointmentScheduled {
+			occurrences = append(occurrences, o)
+		}
+	}
+	loc, ok := env.clinicLocation(w, r, scope.ClinicID)
+	if !ok {
+		return
+	}
+	oldStart, _ := time.Parse(time.RFC3339, occurrences[0].StartsAt)
+	newStart, _ := time.Parse(time.RFC3339, changes.StartsAt)
+	oldStart, newStart = oldStart.In(loc), newStart.In(loc)
+	days := dayNumber(newStart) - dayNumber(oldStart)
+
+	for i := range occurrences {
+		o := &occurrences[i]
+		start, _ := time.Parse(time.RFC3339, o.StartsAt)
+		start = start.In(loc)
+		moved := time.Date(start.Year(), start.Month(), start.Day()+days, newStart.Hour(), newStart.Minute(), newStart.Second(), 0, loc)
+		o.StartsAt = moved.Format(time.RFC3339)
+		o.PetID, o.VetID, o.DurationMinutes, o.Reason = changes.PetID, changes.VetID, changes.DurationMinutes, changes.Reason
+	}
+	if err := env.Appointments.UpdateMany(r.Context(), scope.Scope, occurrences); err != nil {
+		env.writeBookingError(w, r, scope, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(occurrences)
+}
+
+// deleteFollowingAppointments deletes the occurrence and every later one that is still
+// scheduled. Occurrences that were checked in, completed, cancelled or missed keep their
+// record and status history, as they do when the series is changed or cancelled.
+func (env *Env) deleteFollowingAppointments(w http.ResponseWriter, r *http.Request, scope accessScope, id int) {
+	following, err := env.Appointments.Following(r.Context(), scope.Scope, id)
+	if err != nil {
+		writeStoreError(w, err, "Appointment not found")
+		return
+	}
+	if following[0].Status != models.AppointmentScheduled {
+		http.Error(w, "Only scheduled appointments can be deleted", http.StatusConflict)
+		return
+	}
+	var occurrences []models.Appointment
+	var ids []int
+	for _, o := range following {
+		if o.Status == models.AppointmentScheduled {
+			occurrences = append(occurrences, o)
+			ids = append(ids, o.ID)
+		}
+	}
+	if err := env.Appointments.DeleteMany(r.Context(), scope.Scope, ids); err != nil {
+		writeStoreError(w, err, "Appointment not found")
+		return
+	}
+	env.offerFreedSlots(r.Context(), scope.ClinicID, occurrences)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": fmt.Sprintf("%d appointments deleted successfully", len(ids))})
+}
+
+// dayNumber counts days since the epoch for the calendar date of t in its own location
+func dayNumber(t time.Time) int {
+	return int(time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).Unix() / 86400)
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"pets_project/internal/models"
+	"pets_project/internal/store"
+)
+
+// newSeries books a weekly series of count occurrences of the pet, starting in two days
+func (ts *testServer) newSeries(t *testing.T, clinicID, petID, count int) []models.Appointment {
+	t.Helper()
+	start := time.Now().Add(48 * time.Hour).Truncate(time.Hour).UTC()
+	series := models.AppointmentSeries{RRule: fmt.Sprintf("FREQ=WEEKLY;COUNT=%d", count)}
+	for i := 0; i < count; i++ {
+		series.Appointments = append(series.Appointments, models.Appointment{
+			PetID:           petID,
+			StartsAt:        start.AddDate(0, 0, 7*i).Format(time.RFC3339),
+			DurationMinutes: 30,
+			Reason:          "Physiotherapy",
+		})
+	}
+	if err := ts.env.Appointments.CreateSeries(context.Background(), store.Scope{ClinicID: clinicID}, &series); err != nil {
+		t.Fatalf("create series: %v", err)
+	}
+	return series.Appointments
+}
+
+func TestDeleteFollowingKeepsFinishedOccurrences(t *testing.T) {
+	ts := newTestServer(t)
+	_, token := ts.login(t, 1, models.RoleVet)
+	pet := ts.newPet(t, 1, "Rex")
+	occurrences := ts.newSeries(t, 1, pet.ID, 4)
+
+	// The second occurrence already took place; the third was cancelled
+	scope := store.Scope{ClinicID: 1}
+	for _, change := range []struct {
+		id int
+		to string
+	}{{occurrences[1].ID, models.AppointmentCompleted}, {occurrences[2].ID, models.AppointmentCancelled}} {
+		ev := models.AppointmentStatusEvent{ToStatus: change.to, Reason: "test"}
+		if _, err := ts.env.Appointments.Transition(context.Background(), scope, []int{change.id}, []string{models.AppointmentScheduled}, ev); err != nil {
+			t.Fatalf("transition: %v", err)
+		}
+	}
+
+	var resp map[string]string
+	expect(t, ts.do(t, token, "DELETE", fmt.Sprintf("/appointments/%d?scope=following", occurrences[0].ID), nil), http.StatusOK, &resp)
+	if resp["message"] != "2 appointments deleted successfully" {
+		t.Errorf("message = %q", resp["message"])
+	}
+
+	for i, want := range []int{http.StatusNotFound, http.StatusOK, http.StatusOK, http.StatusNotFound} {
+		expect(t, ts.do(t, token, "GET", fmt.Sprintf("/appointments/%d", occurrences[i].ID), nil), want, nil)
+	}
+	var completed models.Appointment
+	expect(t, ts.do(t, token, "GET", fmt.Sprintf("/appointments/%d", occurrences[1].ID), nil), http.StatusOK, &completed)
+	if completed.Status != models.AppointmentCompleted {
+		t.Errorf("completed occurrence is now %s", completed.Status)
+	}
+	var history []models.AppointmentStatusEvent
+	expect(t, ts.do(t, token, "GET", fmt.Sprintf("/appointments/%d/history", occurrences[1].ID), nil), http.StatusOK, &history)
+	if len(history) == 0 || history[len(history)-1].ToStatus != models.AppointmentCompleted {
+		t.Errorf("history of the completed occurrence = %+v", history)
+	}
+
+	// Starting from an occurrence that is no longer scheduled deletes nothing
+	expect(t, ts.do(t, token, "DELETE", fmt.Sprintf("/appointments/%d?scope=following", occurrences[1].ID), nil), http.StatusConflict, nil)
+	expect(t, ts.do(t, token, "GET", fmt.Sprintf("/appointments/%d", occurrences[2].ID), nil), http.StatusOK, nil)
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"pets_project/internal/models"
+)
+
+// === Appointment Status Handlers ==================================================
+// appointmentTransitions is the appointment state machine: each action sets a status
+// and may only be taken from the listed ones.
+//
+//	scheduled -> checked_in -> in_progress -> completed
+//	scheduled, checked_in -> cancelled
+//	scheduled -> no_show
+var appointmentTransitions = map[string]struct {
+	to   string
+	from []string
+}{
+	"check-in": {models.AppointmentCheckedIn, []string{models.AppointmentScheduled}},
+	"start":    {models.AppointmentInProgress, []string{models.AppointmentCheckedIn}},
+	"complete": {models.AppointmentCompleted, []string{models.AppointmentInProgress}},
+	"cancel":   {models.AppointmentCancelled, []string{models.AppointmentScheduled, models.AppointmentCheckedIn}},
+	"no-show":  {models.AppointmentNoShow, []string{models.AppointmentScheduled}},
+}
+
+// appointmentActionHandler serves the sub-resources of one appointment:
+//
+//	POST /appointments/{id}/check-in|start|complete|no-show
+//	POST /appointments/{id}/cancel      {"reason": "..."}, ?scope=following for later occurrences too
+//	GET  /appointments/{id}/history     status changes with time and acting user
+func (env *Env) appointmentActionHandler(w http.ResponseWriter, r *http.Request, id int, action string) {
+	if action == "history" {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed for /appointments/{id}/history", http.StatusMethodNotAllowed)
+			return
+		}
+		env.getAppointmentHistory(w, r, id)
+		return
+	}
+	transition, ok := appointmentTransitions[action]
+	if !ok {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed for /appointments/{id}/"+action, http.StatusMethodNotAllowed)
+		return
+	}
+
+	following := false
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	if action == "cancel" {
+		if following, ok = occurrenceScope(w, r); !ok {
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if strings.TrimSpace(body.Reason) == "" {
+			http.Error(w, "reason is required to cancel", http.StatusBadRequest)
+			return
+		}
+	}
+	scope, ok := env.requestScope(w, r)
+	if !ok {
+		return
+	}
+
+	ids := []int{id}
+	if following {
+		occurrences, err := env.Appointments.Following(r.Context(), scope.Scope, id)
+		if err != nil {
+			writeStoreError(w, err, "Appointment not found")
+			return
+		}
+		// Later occurrences that already ended one way or another are left alone
+		for _, o := range occurrences[1:] {
+			if o.Status == models.AppointmentScheduled {
+				ids = append(ids, o.ID)
+			}
+		}
+	}
+
+	event := models.AppointmentStatusEvent{ToStatus: transition.to, Reason: body.Reason, UserID: scope.UserID}
+	appointments, err := env.Appointments.Transition(r.Context(), scope.Scope, ids, transition.from, event)
+	if err != nil {
+		writeStoreError(w, err, "Appointment not found")
+		return
+	}
+
+	Info("User ID %d set appointment ID %d to %s (%d appointments)", scope.UserID, id, transition.to, len(appointments))
+	if action == "cancel" {
+		env.offerFreedSlots(r.Context(), scope.ClinicID, appointments)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if following {
+		json.NewEncoder(w).Encode(appointments)
+	} else {
+		json.NewEncoder(w).Encode(appointments[0])
+	}
+}
+
+// --- Appointment Status Functions (internal) ---
+func (env *Env) getAppointmentHistory(w http.ResponseWriter, r *http.Request, id int) {
+	scope, ok := env.requestScope(w, r)
+	if !ok {
+		return
+	}
+	events, err := env.Appointments.History(r.Context(), scope.Scope, id)
+	if err != nil {
+		writeStoreError(w, err, "Appointment not found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(events)
+}
+
+// isValidAppointmentStatus reports whether status is one of the appointment statuses
+func isValidAppointmentStatus(status string) bool {
+	switch status {
+	case models.AppointmentScheduled, models.AppointmentCheckedIn, models.AppointmentInProgress,
+		models.AppointmentCompleted, models.AppointmentCancelled, models.AppointmentNoShow:
+		return true
+	}
+	return false
+}
+
+// queryStatuses reads an optional comma separated ?status= list; nil means any status
+func queryStatuses(w http.ResponseWriter, r *http.Request) ([]string, bool) {
+	s := r.URL.Query().Get("status")
+	if s == "" {
+		return nil, true
+	}
+	statuses := strings.Split(s, ",")
+	for _, status := range statuses {
+		if !isValidAppointmentStatus(status) {
+			http.Error(w, "status must be a comma separated list of scheduled, checked_in, in_progress, completed, cancelled, no_show", http.StatusBadRequest)
+			return nil, false
+		}
+	}
+	return statuses, true
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"pets_project/internal/models"
+	"pets_project/internal/store"
+)
+
+func TestDeleteKeepsAppointmentHistory(t *testing.T) {
+	ts := newTestServer(t)
+	_, token := ts.login(t, 1, models.RoleReceptionist)
+	pet := ts.newPet(t, 1, "Rex")
+	occurrences := ts.newSeries(t, 1, pet.ID, 2)
+	done, scheduled := occurrences[0], occurrences[1]
+
+	for _, action := range []string{"check-in", "start", "complete"} {
+		expect(t, ts.do(t, token, "POST", fmt.Sprintf("/appointments/%d/%s", done.ID, action), nil), http.StatusOK, nil)
+	}
+	rec := ts.do(t, token, "DELETE", fmt.Sprintf("/appointments/%d", done.ID), nil)
+	expect(t, rec, http.StatusConflict, nil)
+	if rec.Body.String() != "Only scheduled appointments can be deleted\n" {
+		t.Errorf("409 body = %q", rec.Body.String())
+	}
+	history, err := ts.env.Appointments.History(context.Background(), store.Scope{ClinicID: 1}, done.ID)
+	if err != nil || len(history) != 3 {
+		t.Errorf("history after the refused delete = %+v, %v; want 3 events", history, err)
+	}
+
+	// A cancelled appointment is kept as well
+	expect(t, ts.do(t, token, "POST", fmt.Sprintf("/appointments/%d/cancel", scheduled.ID), map[string]string{"reason": "Owner ill"}), http.StatusOK, nil)
+	expect(t, ts.do(t, token, "DELETE", fmt.Sprintf("/appointments/%d", scheduled.ID), nil), http.StatusConflict, nil)
+
+	fresh := ts.newSeries(t, 1, ts.newPet(t, 1, "Tom").ID, 1)[0]
+	expect(t, ts.do(t, token, "DELETE", fmt.Sprintf("/appointments/%d", fresh.ID), nil), http.StatusOK, nil)
+	expect(t, ts.do(t, token, "GET", fmt.Sprintf("/appointments/%d", fresh.ID), nil), http.StatusNotFound, nil)
+}
@@ -2,8 +2,8 @@ package handlers
 
 import (
 	"context"
-	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"pets_project/internal/models"
+	"pets_project/internal/store"
 
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
@@ -18,7 +19,10 @@ import (
 
 // Claims struct defines the data we store in the JWT
 type Claims struct {
-	UserID int `json:"user_id"`
+	UserID    int    `json:"user_id"`
+	ClinicID  int    `json:"clinic_id"`
+	Role      string `json:"role"`
+	SessionID string `json:"sid"`
 	jwt.RegisteredClaims
 }
 
@@ -34,11 +38,17 @@ func checkPasswordHash(password, hash string) bool {
 }
 
 // --- JWT Generation ---
-func generateJWT(userID int) (string, error) {
+// Access tokens are short-lived; clients renew them through /token/refresh.
+const accessTokenTTL = 15 * time.Minute
+
+func generateJWT(user models.User, sessionID string) (string, error) {
 	jwtSecretKey := []byte(os.Getenv("JWT_SECRET"))
-	expirationTime := time.Now().Add(3 * time.Hour)
+	expirationTime := time.Now().Add(accessTokenTTL)
 	claims := &Claims{
-		UserID: userID,
+		UserID:    user.ID,
+		ClinicID:  user.ClinicID,
+		Role:      user.Role,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 		},
@@ -81,8 +91,24 @@ func (env *Env) JwtAuthMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		Info("Authenticated request from user ID %d", claims.UserID)
+		// Reject tokens whose session was revoked by logout or refresh token reuse
+		active, err := env.Sessions.IsActive(r.Context(), claims.SessionID, claims.UserID)
+		if err != nil {
+			Error("Failed to check session %s: %v", claims.SessionID, err)
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		if !active {
+			Warn("Rejected token for revoked session %s (user ID %d)", claims.SessionID, claims.UserID)
+			http.Error(w, "Session has been revoked", http.StatusUnauthorized)
+			return
+		}
+
+		Info("Authenticated request from user ID %d (role %s, clinic %d)", claims.UserID, claims.Role, claims.ClinicID)
 		ctx := context.WithValue(r.Context(), "userID", claims.UserID)
+		ctx = context.WithValue(ctx, "clinicID", claims.ClinicID)
+		ctx = context.WithValue(ctx, "role", claims.Role)
+		ctx = context.WithValue(ctx, "sessionID", claims.SessionID)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -105,6 +131,23 @@ func (env *Env) SignupHandler(w http.ResponseWriter, r *http.Request) {
 		Warn("Signup failed: Missing email or password")
 		return
 	}
+	if creds.ClinicID == 0 {
+		http.Error(w, "clinic_id is required", http.StatusBadRequest)
+		Warn("Signup failed: Missing clinic_id for %s", creds.Email)
+		return
+	}
+
+	clinicExists, err := env.Clinics.Exists(r.Context(), creds.ClinicID)
+	if err != nil {
+		Error("Failed to look up clinic %d: %v", creds.ClinicID, err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if !clinicExists {
+		http.Error(w, "Unknown clinic_id", http.StatusBadRequest)
+		Warn("Signup failed: Unknown clinic %d for %s", creds.ClinicID, creds.Email)
+		return
+	}
 
 	hashedPassword, err := hashPassword(creds.Password)
 	if err != nil {
@@ -113,14 +156,18 @@ func (env *Env) SignupHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sqlStatement := `INSERT INTO users (email, password_hash) VALUES ($1, $2) RETURNING id`
-	var userID int
-	err = env.DB.QueryRow(sqlStatement, creds.Email, hashedPassword).Scan(&userID)
-	if err != nil {
+	user := models.User{Email: creds.Email, PasswordHash: hashedPassword, ClinicID: creds.ClinicID}
+	if err := env.Users.Create(r.Context(), &user); err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			Warn("Signup failed: Email %s already in use", creds.Email)
+			http.Error(w, "Email already in use", http.StatusConflict)
+			return
+		}
 		Error("Signup failed for email %s: %v", creds.Email, err)
 		http.Error(w, "Email already in use or database error", http.StatusInternalServerError)
 		return
 	}
+	userID := user.ID
 
 	Info("User %s registered successfully (ID: %d)", creds.Email, userID)
 	w.Header().Set("Content-Type", "application/json")
@@ -144,11 +191,9 @@ func (env *Env) LoginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var user models.User
-	sqlStatement := `SELECT id, email, password_hash FROM users WHERE email = $1`
-	err := env.DB.QueryRow(sqlStatement, creds.Email).Scan(&user.ID, &user.Email, &user.PasswordHash)
+	user, err := env.Users.GetByEmail(r.Context(), creds.Email)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, store.ErrNotFound) {
 			Warn("Login failed: User not found (%s)", creds.Email)
 			http.Error(w, "Invalid email or password", http.StatusUnauthorized)
 		} else {
@@ -164,7 +209,14 @@ func (env *Env) LoginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tokenString, err := generateJWT(user.ID)
+	sessionID, refreshToken, err := env.createSession(r.Context(), user.ID)
+	if err != nil {
+		Error("Failed to create session for %s: %v", creds.Email, err)
+		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		return
+	}
+
+	tokenString, err := generateJWT(user, sessionID)
 	if err != nil {
 		Error("Failed to generate JWT: %v", err)
 		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
@@ -173,5 +225,8 @@ func (env *Env) LoginHandler(w http.ResponseWriter, r *http.Request) {
 
 	Info("User %s logged in successfully", creds.Email)
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"token": tokenString})
+	json.NewEncoder(w).Encode(map[string]string{
+		"token":         tokenString,
+		"refresh_token": refreshToken,
+	})
 }
@@ -0,0 +1,459 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"pets_project/internal/models"
+	"pets_project/internal/scheduling"
+	"pets_project/internal/store"
+)
+
+// maxAvailabilityDays bounds the date range of one availability search
+const maxAvailabilityDays = 31
+
+// === Appointment Type Handlers ====================================================
+// AppointmentTypesHandler (capitalized) is the mini-router. Exported to main.go.
+//
+//	GET    /appointment-types         list the clinic's appointment types
+//	POST   /appointment-types         add one {"name", "duration_minutes"}
+//	GET    /appointment-types/{id}    fetch one
+//	PUT    /appointment-types/{id}    replace
+//	DELETE /appointment-types/{id}    remove
+func (env *Env) AppointmentTypesHandler(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	if path == "/appointment-types" {
+		switch r.Method {
+		case "GET":
+			env.getAllAppointmentTypes(w, r)
+		case "POST":
+			env.createAppointmentType(w, r)
+		default:
+			http.Error(w, "Method not allowed for /appointment-types", http.StatusMethodNotAllowed)
+		}
+	} else if strings.HasPrefix(path, "/appointment-types/") {
+		id, err := getIDFromPath(w, r, "/appointment-types/")
+		if err != nil {
+			return
+		}
+		switch r.Method {
+		case "GET":
+			env.getAppointmentTypeByID(w, r, id)
+		case "PUT":
+			env.updateAppointmentType(w, r, id)
+		case "DELETE":
+			env.deleteAppointmentType(w, r, id)
+		default:
+			http.Error(w, "Method not allowed for /appointment-types/{id}", http.StatusMethodNotAllowed)
+		}
+	} else {
+		http.Error(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// --- Appointment Type Functions (internal) ---
+func decodeAppointmentType(w http.ResponseWriter, r *http.Request) (models.AppointmentType, bool) {
+	var t models.AppointmentType
+	if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return t, false
+	}
+	if strings.TrimSpace(t.Name) == "" {
+		http.Error(w, "name is required", http.StatusBadRequest)
+		return t, false
+	}
+	if t.DurationMinutes < 5 || t.DurationMinutes > maxAppointmentMinutes {
+		http.Error(w, fmt.Sprintf("duration_minutes must be between 5 and %d", maxAppointmentMinutes), http.StatusBadRequest)
+		return t, false
+	}
+	return t, true
+}
+
+func (env *Env) getAllAppointmentTypes(w http.ResponseWriter, r *http.Request) {
+	clinicID, _ := r.Context().Value("clinicID").(int)
+	types, err := env.Types.List(r.Context(), clinicID)
+	if err != nil {
+		writeStoreError(w, err, "Appointment type not found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(types)
+}
+
+func (env *Env) createAppointmentType(w http.ResponseWriter, r *http.Request) {
+	t, ok := decodeAppointmentType(w, r)
+	if !ok {
+		return
+	}
+	clinicID, _ := r.Context().Value("clinicID").(int)
+	if err := env.Types.Create(r.Context(), clinicID, &t); err != nil {
+		writeStoreError(w, err, "Appointment type not found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(t)
+}
+
+func (env *Env) getAppointmentTypeByID(w http.ResponseWriter, r *http.Request, id int) {
+	clinicID, _ := r.Context().Value("clinicID").(int)
+	t, err := env.Types.Get(r.Context(), clinicID, id)
+	if err != nil {
+		writeStoreError(w, err, "Appointment type not found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(t)
+}
+
+func (env *Env) updateAppointmentType(w http.ResponseWriter, r *http.Request, id int) {
+	t, ok := decodeAppointmentType(w, r)
+	if !ok {
+		return
+	}
+	clinicID, _ := r.Context().Value("clinicID").(int)
+	t.ID = id
+	if err := env.Types.Update(r.Context(), clinicID, &t); err != nil {
+		writeStoreError(w, err, "Appointment type not found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(t)
+}
+
+func (env *Env) deleteAppointmentType(w http.ResponseWriter, r *http.Request, id int) {
+	clinicID, _ := r.Context().Value("clinicID").(int)
+	if err := env.Types.Delete(r.Context(), clinicID, id); err != nil {
+		writeStoreError(w, err, "Appointment type not found")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Appointment type deleted successfully"})
+}
+
+// === Clinic Hours Handlers ========================================================
+// ClinicHoursHandler (capitalized) is the mini-router. Exported to main.go.
+//
+//	GET /clinic-hours    weekly opening hours of the caller's clinic
+//	PUT /clinic-hours    replace them [{"weekday": 1, "start": "08:00", "end": "18:00"}]
+//
+// A clinic without opening hours does not restrict bookings beyond staff working hours.
+func (env *Env) ClinicHoursHandler(w http.ResponseWriter, r *http.Request) {
+	clinicID, _ := r.Context().Value("clinicID").(int)
+	switch r.Method {
+	case "GET":
+		hours, err := env.Clinics.Hours(r.Context(), clinicID)
+		if err != nil {
+			writeStoreError(w, err, "Clinic not found")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(hours)
+	case "PUT":
+		var hours []models.WorkingHours
+		if err := json.NewDecoder(r.Body).Decode(&hours); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := scheduling.ValidateHours(hours); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if hours == nil {
+			hours = []models.WorkingHours{}
+		}
+		if err := env.Clinics.SetHours(r.Context(), clinicID, hours); err != nil {
+			writeStoreError(w, err, "Clinic not found")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(hours)
+	default:
+		http.Error(w, "Method not allowed for /clinic-hours", http.StatusMethodNotAllowed)
+	}
+}
+
+// === Blocked Period Handlers ======================================================
+// BlockedPeriodsHandler (capitalized) is the mini-router. Exported to main.go.
+//
+//	GET    /blocked-periods           list periods overlapping ?from=/?to= (default: from now on)
+//	POST   /blocked-periods           block the clinic, or one staff member with staff_id
+//	DELETE /blocked-periods/{id}      remove
+func (env *Env) BlockedPeriodsHandler(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	if path == "/blocked-periods" {
+		switch r.Method {
+		case "GET":
+			env.getAllBlockedPeriods(w, r)
+		case "POST":
+			env.createBlockedPeriod(w, r)
+		default:
+			http.Error(w, "Method not allowed for /blocked-periods", http.StatusMethodNotAllowed)
+		}
+	} else if strings.HasPrefix(path, "/blocked-periods/") {
+		id, err := getIDFromPath(w, r, "/blocked-periods/")
+		if err != nil {
+			return
+		}
+		switch r.Method {
+		case "DELETE":
+			env.deleteBlockedPeriod(w, r, id)
+		default:
+			http.Error(w, "Method not allowed for /blocked-periods/{id}", http.StatusMethodNotAllowed)
+		}
+	} else {
+		http.Error(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// --- Blocked Period Functions (internal) ---
+func (env *Env) getAllBlockedPeriods(w http.ResponseWriter, r *http.Request) {
+	from, ok := queryTime(w, r, "from", false)
+	if !ok {
+		return
+	}
+	to, ok := queryTime(w, r, "to", true)
+	if !ok {
+		return
+	}
+	if from.IsZero() {
+		from = time.Now()
+	}
+	if to.IsZero() {
+		to = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+	}
+	clinicID, _ := r.Context().Value("clinicID").(int)
+	periods, err := env.Blocked.List(r.Context(), clinicID, from, to)
+	if err != nil {
+		writeStoreError(w, err, "Blocked period not found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(periods)
+}
+
+func (env *Env) createBlockedPeriod(w http.ResponseWriter, r *http.Request) {
+	var b models.BlockedPeriod
+	if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	start, err1 := time.Parse(time.RFC3339, b.StartsAt)
+	end, err2 := time.Parse(time.RFC3339, b.EndsAt)
+	if err1 != nil || err2 != nil {
+		http.Error(w, "starts_at and ends_at must be RFC 3339 times with a zone", http.StatusBadRequest)
+		return
+	}
+	if !end.After(start) {
+		http.Error(w, "ends_at must be after starts_at", http.StatusBadRequest)
+		return
+	}
+	b.StartsAt = start.UTC().Format(time.RFC3339)
+	b.EndsAt = end.UTC().Format(time.RFC3339)
+
+	clinicID, _ := r.Context().Value("clinicID").(int)
+	if err := env.Blocked.Create(r.Context(), clinicID, &b); err != nil {
+		writeStoreError(w, err, "Blocked period not found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(b)
+}
+
+func (env *Env) deleteBlockedPeriod(w http.ResponseWriter, r *http.Request, id int) {
+	clinicID, _ := r.Context().Value("clinicID").(int)
+	if err := env.Blocked.Delete(r.Context(), clinicID, id); err != nil {
+		writeStoreError(w, err, "Blocked period not found")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Blocked period deleted successfully"})
+}
+
+// === Availability =================================================================
+// availableSlot is one bookable start time with the vet it would be booked with
+type availableSlot struct {
+	VetID   int       `json:"vet_id"`
+	VetName string    `json:"vet_name"`
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+}
+
+// vetCalendar collects what limits the bookings of a vet in [from, to): clinic opening
+// hours, the vet's working hours, their appointments, blocked periods and time already past.
+// Busy periods are moved into from's location so that slots align to the local clock.
+func (env *Env) vetCalendar(ctx context.Context, clinicID int, vet models.Staff, clinicHours []models.WorkingHours,
+	blocked []models.BlockedPeriod, from, to time.Time) (scheduling.Calendar, error) {
+	loc := from.Location()
+	cal := scheduling.Calendar{ClinicHours: clinicHours, StaffHours: vet.WorkingHours}
+	if now := time.Now().In(loc); now.After(from) {
+		cal.Busy = append(cal.Busy, scheduling.Interval{Start: from, End: now})
+	}
+
+	appointments, err := env.Appointments.ListForVet(ctx, clinicID, vet.ID, from, to)
+	if err != nil {
+		return cal, err
+	}
+	for _, a := range appointments {
+		start, _ := time.Parse(time.RFC3339, a.StartsAt)
+		start = start.In(loc)
+		cal.Busy = append(cal.Busy, scheduling.Interval{Start: start, End: start.Add(time.Duration(a.DurationMinutes) * time.Minute)})
+	}
+	for _, b := range blocked {
+		if b.StaffID != 0 && b.StaffID != vet.ID {
+			continue
+		}
+		start, _ := time.Parse(time.RFC3339, b.StartsAt)
+		end, _ := time.Parse(time.RFC3339, b.EndsAt)
+		cal.Busy = append(cal.Busy, scheduling.Interval{Start: start.In(loc), End: end.In(loc)})
+	}
+	return cal, nil
+}
+
+// bookableVets returns the requested vet, or every vet of the clinic when vetID is 0
+func (env *Env) bookableVets(ctx context.Context, clinicID int, vetID int) ([]models.Staff, error) {
+	if vetID != 0 {
+		vet, err := env.Staff.Get(ctx, clinicID, vetID)
+		if err != nil {
+			return nil, err
+		}
+		if vet.Role != models.StaffVet {
+			return nil, &store.ReferenceError{Entity: "vet", ID: vetID}
+		}
+		return []models.Staff{vet}, nil
+	}
+
+	var vets []models.Staff
+	page := store.Page{Limit: store.MaxPageLimit}
+	for {
+		batch, next, err := env.Staff.List(ctx, clinicID, store.StaffFilter{Role: models.StaffVet}, page)
+		if err != nil {
+			return nil, err
+		}
+		vets = append(vets, batch...)
+		if next == "" {
+			return vets, nil
+		}
+		page.Cursor = next
+	}
+}
+
+// getAvailability answers GET /appointments/availability.
+//
+//	?from=YYYY-MM-DD   first day (default today, clinic time)
+//	?to=YYYY-MM-DD     last day, inclusive (default from; at most 31 days)
+//	?type_id=          appointment type whose duration is used, or
+//	?duration=         length in minutes (default 30)
+//	?vet_id=           only this vet; otherwise every vet of the clinic
+//
+// Slots are open in clinic hours and the vet's working hours, and overlap no
+// appointment or blocked period. They are ordered by start time, then vet.
+func (env *Env) getAvailability(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	clinicID, _ := r.Context().Value("clinicID").(int)
+
+	typeID, ok := queryInt(w, r, "type_id")
+	if !ok {
+		return
+	}
+	vetID, ok := queryInt(w, r, "vet_id")
+	if !ok {
+		return
+	}
+	duration := defaultAppointmentMinutes
+	if typeID != 0 {
+		if q.Get("duration") != "" {
+			http.Error(w, "Pass either type_id or duration, not both", http.StatusBadRequest)
+			return
+		}
+		t, err := env.Types.Get(r.Context(), clinicID, typeID)
+		if err != nil {
+			writeStoreError(w, err, "Appointment type not found")
+			return
+		}
+		duration = t.DurationMinutes
+	} else if s := q.Get("duration"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 5 || n > maxAppointmentMinutes {
+			http.Error(w, fmt.Sprintf("duration must be a number of minutes between 5 and %d", maxAppointmentMinutes), http.StatusBadRequest)
+			return
+		}
+		duration = n
+	}
+
+	loc, ok := env.clinicLocation(w, r, clinicID)
+	if !ok {
+		return
+	}
+	fromStr, toStr := q.Get("from"), q.Get("to")
+	if fromStr == "" {
+		fromStr = time.Now().In(loc).Format("2006-01-02")
+	}
+	if toStr == "" {
+		toStr = fromStr
+	}
+	from, err1 := scheduling.Day(fromStr, loc)
+	last, err2 := scheduling.Day(toStr, loc)
+	if err1 != nil || err2 != nil {
+		http.Error(w, "from and to must be dates in YYYY-MM-DD format", http.StatusBadRequest)
+		return
+	}
+	to := last.AddDate(0, 0, 1)
+	if !to.After(from) || to.After(from.AddDate(0, 0, maxAvailabilityDays)) {
+		http.Error(w, fmt.Sprintf("to must be on or after from and at most %d days later", maxAvailabilityDays-1), http.StatusBadRequest)
+		return
+	}
+
+	vets, err := env.bookableVets(r.Context(), clinicID, vetID)
+	if err != nil {
+		writeStoreError(w, err, "Vet not found")
+		return
+	}
+	clinicHours, err := env.Clinics.Hours(r.Context(), clinicID)
+	if err != nil {
+		writeStoreError(w, err, "Clinic not found")
+		return
+	}
+	blocked, err := env.Blocked.List(r.Context(), clinicID, from, to)
+	if err != nil {
+		writeStoreError(w, err, "Blocked period not found")
+		return
+	}
+
+	length := time.Duration(duration) * time.Minute
+	slots := []availableSlot{}
+	for _, vet := range vets {
+		cal, err := env.vetCalendar(r.Context(), clinicID, vet, clinicHours, blocked, from, to)
+		if err != nil {
+			writeStoreError(w, err, "Vet not found")
+			return
+		}
+		for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+			for _, s := range scheduling.Slots(cal.Open(day), length, scheduling.DefaultStep) {
+				slots = append(slots, availableSlot{VetID: vet.ID, VetName: vet.Name, Start: s.Start, End: s.End})
+			}
+		}
+	}
+	sort.SliceStable(slots, func(i, j int) bool {
+		if !slots[i].Start.Equal(slots[j].Start) {
+			return slots[i].Start.Before(slots[j].Start)
+		}
+		return slots[i].VetID < slots[j].VetID
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"from":             from.Format("2006-01-02"),
+		"to":               last.Format("2006-01-02"),
+		"timezone":         loc.String(),
+		"duration_minutes": duration,
+		"slots":            slots,
+	})
+}
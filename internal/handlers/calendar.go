@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"pets_project/internal/ical"
+	"pets_project/internal/models"
+	"pets_project/internal/store"
+)
+
+// Calendar feeds publish appointments as iCalendar files that calendar apps subscribe to.
+// Apps cannot send a bearer header, so each user gets a secret feed token that is part of
+// the URL and stands in for their login: the feed shows what the user could list through
+// the API right now, with their current role.
+const (
+	feedProdID    = "-//pets_project//Appointments//EN"
+	feedUIDDomain = "pets-project"
+	// feedHistory is how far back feeds go; later appointments are all included
+	feedHistory = 90 * 24 * time.Hour
+)
+
+// feedKinds maps the feed path segment to the permission it requires
+var feedKinds = map[string]Permission{
+	"vets":   "schedules:read", // a vet's feed lists appointments of every owner
+	"pets":   "appointments:read",
+	"owners": "appointments:read",
+}
+
+// === Calendar Feed Handlers =======================================================
+// CalendarFeedTokenHandler manages the caller's feed token (JWT required):
+//
+//	POST   /calendar-feed   create a new token, replacing any earlier one, and return the feed URLs
+//	DELETE /calendar-feed   revoke the token
+//
+// The token is only stored hashed, so it is shown once; POST again to get a new one.
+func (env *Env) CalendarFeedTokenHandler(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value("userID").(int)
+	role, _ := r.Context().Value("role").(string)
+	switch r.Method {
+	case http.MethodPost:
+		token, err := randomToken(32)
+		if err != nil {
+			Error("Failed to generate calendar feed token: %v", err)
+			http.Error(w, "Failed to create calendar feed", http.StatusInternalServerError)
+			return
+		}
+		if err := env.Users.SetFeedToken(r.Context(), userID, hashToken(token)); err != nil {
+			writeStoreError(w, err, "User not found")
+			return
+		}
+
+		base := env.baseURL(r) + "/calendar/" + token
+		feeds := map[string]string{}
+		for kind, perm := range feedKinds {
+			if hasPermission(role, perm) {
+				feeds[kind] = base + "/" + kind + "/{id}.ics"
+			}
+		}
+		Info("User ID %d created a calendar feed token", userID)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{"token": token, "feeds": feeds})
+	case http.MethodDelete:
+		if err := env.Users.DeleteFeedToken(r.Context(), userID); err != nil {
+			writeStoreError(w, err, "Calendar feed not found")
+			return
+		}
+		Info("User ID %d revoked their calendar feed token", userID)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Calendar feed revoked successfully"})
+	default:
+		http.Error(w, "Method not allowed for /calendar-feed", http.StatusMethodNotAllowed)
+	}
+}
+
+// CalendarFeedHandler serves feeds without a bearer header, authenticated by the token in the path:
+//
+//	GET /calendar/{token}/vets/{staff_id}.ics   a vet's appointments
+//	GET /calendar/{token}/pets/{pet_id}.ics     a pet's appointments
+//	GET /calendar/{token}/owners/{owner_id}.ics appointments of all of an owner's pets
+//
+// Each appointment keeps the UID appointment-{id}@pets-project and carries its revision as
+// SEQUENCE, so changed times replace the event in subscribed calendars and cancelled or
+// missed appointments show as cancelled.
+func (env *Env) CalendarFeedHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/calendar/"), "/")
+	if len(parts) != 3 || !strings.HasSuffix(parts[2], ".ics") {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	token, kind := parts[0], parts[1]
+	perm, known := feedKinds[kind]
+	id, err := strconv.Atoi(strings.TrimSuffix(parts[2], ".ics"))
+	if !known || err != nil || id <= 0 {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	user, err := env.Users.GetByFeedToken(r.Context(), hashToken(token))
+	if errors.Is(err, store.ErrNotFound) {
+		Warn("Calendar feed requested with an unknown token")
+		http.Error(w, "Calendar feed not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		Error("Failed to look up calendar feed token: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if !hasPermission(user.Role, perm) {
+		Warn("Denied %s calendar feed for user ID %d: role %q lacks %s", kind, user.ID, user.Role, perm)
+		http.Error(w, "Forbidden: insufficient permissions", http.StatusForbidden)
+		return
+	}
+	scope, err := env.userScope(r.Context(), user.ID, user.Role, user.ClinicID)
+	if err != nil {
+		Error("Failed to resolve access scope for user ID %d: %v", user.ID, err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	name, filter, ok := env.feedSubject(w, r, scope, kind, id)
+	if !ok {
+		return
+	}
+	filter.From = time.Now().Add(-feedHistory)
+	entries, err := env.Appointments.Calendar(r.Context(), scope.Scope, filter)
+	if err != nil {
+		Error("Failed to load calendar feed for user ID %d: %v", user.ID, err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	clinic, err := env.Clinics.Get(r.Context(), user.ClinicID)
+	if err != nil {
+		Error("Failed to load clinic ID %d: %v", user.ClinicID, err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	cal := ical.Calendar{ProdID: feedProdID, Name: name + " – " + clinic.Name, Timezone: clinic.Timezone}
+	for _, e := range entries {
+		cal.Events = append(cal.Events, feedEvent(e, clinic.Name))
+	}
+	Info("Served %s calendar feed ID %d to user ID %d (%d appointments)", kind, id, user.ID, len(entries))
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="%s-%d.ics"`, kind, id))
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	if r.Method == http.MethodHead {
+		return
+	}
+	if err := cal.Write(w, time.Now()); err != nil {
+		Error("Failed to write calendar feed: %v", err)
+	}
+}
+
+// --- Calendar Feed Functions (internal) ---
+// feedSubject checks that the vet, pet or owner of a feed is visible in scope and returns
+// its name and the appointment filter selecting its appointments
+func (env *Env) feedSubject(w http.ResponseWriter, r *http.Request, scope accessScope, kind string, id int) (string, store.AppointmentFilter, bool) {
+	var filter store.AppointmentFilter
+	switch kind {
+	case "vets":
+		st, err := env.Staff.Get(r.Context(), scope.ClinicID, id)
+		if err == nil && st.Role != models.StaffVet {
+			err = store.ErrNotFound // only vets have appointments booked with them
+		}
+		if err != nil {
+			writeStoreError(w, err, "Vet not found")
+			return "", filter, false
+		}
+		filter.VetID = id
+		return st.Name, filter, true
+	case "pets":
+		pet, err := env.Pets.Get(r.Context(), scope.Scope, id)
+		if err != nil {
+			writeStoreError(w, err, "Pet not found")
+			return "", filter, false
+		}
+		filter.PetID = id
+		return pet.Name, filter, true
+	default:
+		owner, err := env.Owners.Get(r.Context(), scope.Scope, id)
+		if err != nil {
+			writeStoreError(w, err, "Owner not found")
+			return "", filter, false
+		}
+		filter.OwnerID = id
+		return owner.Name, filter, true
+	}
+}
+
+// feedEvent turns an appointment into a calendar event. Cancelled and missed appointments stay
+// in the feed as cancelled events so subscribed calendars update rather than keep a stale copy.
+func feedEvent(e models.CalendarEntry, clinicName string) ical.Event {
+	start, _ := time.Parse(time.RFC3339, e.StartsAt)
+	modified, _ := time.Parse(time.RFC3339, e.UpdatedAt)
+	summary := e.PetName + " appointment"
+	if e.Reason != "" {
+		summary = e.PetName + ": " + e.Reason
+	}
+	description := "Pet: " + e.PetName
+	if e.OwnerName != "" {
+		description += "\nOwner: " + e.OwnerName
+	}
+	if e.VetName != "" {
+		description += "\nVet: " + e.VetName
+	}
+	description += "\nStatus: " + e.Status
+
+	status := ical.StatusConfirmed
+	if e.Status == models.AppointmentCancelled || e.Status == models.AppointmentNoShow {
+		status = ical.StatusCancelled
+	}
+	return ical.Event{
+		UID:         ical.UID("appointment", e.ID, feedUIDDomain),
+		Start:       start,
+		End:         start.Add(time.Duration(e.DurationMinutes) * time.Minute),
+		Summary:     summary,
+		Description: description,
+		Location:    clinicName,
+		Status:      status,
+		Sequence:    e.Revision,
+		Modified:    modified,
+	}
+}
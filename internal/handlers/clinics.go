@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"pets_project/internal/models"
+)
+
+// === Clinic Handlers ==============================================================
+// ClinicsHandler lists clinic locations so new users can pick one at signup.
+// GET /clinics (public)
+func (env *Env) ClinicsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed for /clinics", http.StatusMethodNotAllowed)
+		return
+	}
+
+	clinics, err := env.Clinics.List(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(clinics)
+}
+
+// AdminClinicsHandler registers a new clinic location.
+// POST /admin/clinics {"name": "...", "address": "...", "timezone": "Europe/London"}
+func (env *Env) AdminClinicsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed for /admin/clinics", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var c models.Clinic
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if c.Name == "" {
+		http.Error(w, "name is required", http.StatusBadRequest)
+		return
+	}
+	if c.Timezone == "" {
+		c.Timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(c.Timezone); err != nil {
+		http.Error(w, "timezone must be an IANA time zone name such as Europe/London", http.StatusBadRequest)
+		return
+	}
+	if err := env.Clinics.Create(r.Context(), &c); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	userID, _ := r.Context().Value("userID").(int)
+	Info("Admin user ID %d created clinic %d (%s)", userID, c.ID, c.Name)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(c)
+}
@@ -1,110 +1,176 @@
 package handlers
 
 import (
-	"database/sql"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"mime"
+	"mime/multipart"
 	"net/http"
-	"os"
-	"path/filepath"
 	"strconv"
 	"time"
 
+	"pets_project/internal/blob"
 	"pets_project/internal/models"
+	"pets_project/internal/store"
 )
 
-// UploadFileHandler handles uploading a pet's medical record (PDF/image)
+// defaultMaxUploadBytes limits uploads when Env.MaxUploadBytes is not set
+const defaultMaxUploadBytes = 512 << 20
+
+// UploadFileHandler handles uploading a pet's medical record (PDF/image/DICOM).
+// The multipart body is streamed to the blob store as it arrives, so pet_id has to
+// come before the file part (or be given as ?pet_id=). A document_id field uploads a
+// new version of that document instead, and makes pet_id optional.
 func (env *Env) UploadFileHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	// Parse multipart form (limit 10 MB)
-	if err := r.ParseMultipartForm(10 << 20); err != nil {
-		Error("Failed to parse multipart form: %v", err)
-		http.Error(w, "Error parsing form", http.StatusBadRequest)
+	maxBytes := env.maxUploadBytes()
+	r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+	mr, err := r.MultipartReader()
+	if err != nil {
+		Warn("Upload is not a multipart form: %v", err)
+		http.Error(w, "Expected multipart/form-data", http.StatusBadRequest)
 		return
 	}
 
-	file, handler, err := r.FormFile("file")
-	if err != nil {
-		Error("Error retrieving the file: %v", err)
-		http.Error(w, "Error retrieving file", http.StatusBadRequest)
-		return
+	// Read form fields up to the file part
+	petIDStr := r.URL.Query().Get("pet_id")
+	documentIDStr := r.URL.Query().Get("document_id")
+	var part *multipart.Part
+	for part == nil {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			Warn("Upload without a file part")
+			http.Error(w, "Error retrieving file", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			writeUploadReadError(w, err, maxBytes)
+			return
+		}
+		switch p.FormName() {
+		case "file":
+			part = p
+		case "pet_id", "document_id":
+			value, err := io.ReadAll(io.LimitReader(p, 32))
+			if err != nil {
+				writeUploadReadError(w, err, maxBytes)
+				return
+			}
+			if p.FormName() == "pet_id" {
+				petIDStr = string(value)
+			} else {
+				documentIDStr = string(value)
+			}
+		}
 	}
-	defer file.Close()
+	defer part.Close()
 
-	petIDStr := r.FormValue("pet_id")
-	petID, err := strconv.Atoi(petIDStr)
-	if err != nil || petID <= 0 {
-		Warn("Invalid pet_id provided for upload: %s", petIDStr)
-		http.Error(w, "Invalid pet_id", http.StatusBadRequest)
+	scope, ok := env.requestScope(w, r)
+	if !ok {
 		return
 	}
-
-	// Ensure upload directory exists
-	uploadDir := "./uploads"
-	if _, err := os.Stat(uploadDir); os.IsNotExist(err) {
-		if err := os.MkdirAll(uploadDir, os.ModePerm); err != nil {
-			Error("Failed to create upload directory: %v", err)
-			http.Error(w, "Server error", http.StatusInternalServerError)
+	var petID, documentID int
+	if documentIDStr != "" {
+		documentID, err = strconv.Atoi(documentIDStr)
+		if err != nil || documentID <= 0 {
+			http.Error(w, "Invalid document_id", http.StatusBadRequest)
+			return
+		}
+		if petID, ok = env.documentPetID(w, r, scope, documentID, petIDStr); !ok {
+			return
+		}
+	} else {
+		petID, err = strconv.Atoi(petIDStr)
+		if err != nil || petID <= 0 {
+			Warn("Invalid pet_id provided for upload: %s", petIDStr)
+			http.Error(w, "Invalid pet_id (send it before the file)", http.StatusBadRequest)
 			return
 		}
 	}
+	if !env.checkPetInScope(w, r, scope, petID) {
+		return
+	}
 
-	// Create unique file name with timestamp
-	fileExt := filepath.Ext(handler.Filename)
-	newFileName := fmt.Sprintf("pet%d_%d%s", petID, time.Now().Unix(), fileExt)
-	filePath := filepath.Join(uploadDir, newFileName)
-
-	// Save file to server
-	dst, err := os.Create(filePath)
+	// Detect the type from the content before storing anything
+	contentType, content, err := sniffUpload(part)
 	if err != nil {
-		Error("Failed to create file on disk: %v", err)
-		http.Error(w, "Failed to save file", http.StatusInternalServerError)
+		writeUploadReadError(w, err, maxBytes)
 		return
 	}
-	defer dst.Close()
-
-	if _, err = io.Copy(dst, file); err != nil {
-		Error("Error copying file to disk: %v", err)
-		http.Error(w, "Error saving file", http.StatusInternalServerError)
+	if err := env.checkUploadType(contentType); err != nil {
+		Warn("Upload of %s rejected: %v", part.FileName(), err)
+		http.Error(w, fmt.Sprintf("File type %s is not allowed", contentType), http.StatusUnsupportedMediaType)
 		return
 	}
 
-	// Insert metadata into DB, return id and uploaded_at
-	var recordID int
-	var uploadedAt time.Time
-	sqlStatement := `
-		INSERT INTO file_records (pet_id, file_name, file_path)
-		VALUES ($1, $2, $3)
-		RETURNING id, uploaded_at
-	`
-	err = env.DB.QueryRow(sqlStatement, petID, handler.Filename, filePath).Scan(&recordID, &uploadedAt)
+	key, err := newFileKey(petID, contentType)
 	if err != nil {
-		Error("DB insert failed: %v", err)
-		// attempt to remove saved file if DB insert fails
-		_ = os.Remove(filePath)
-		http.Error(w, "Database error while saving metadata", http.StatusInternalServerError)
+		Error("Failed to generate file key: %v", err)
+		http.Error(w, "Server error", http.StatusInternalServerError)
+		return
+	}
+
+	// Stream file to the blob store, measuring and hashing it on the way
+	hash := sha256.New()
+	counter := &countingReader{r: io.TeeReader(content, hash)}
+	if err := env.Blobs.Put(r.Context(), key, counter, -1, contentType); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeUploadReadError(w, err, maxBytes)
+			return
+		}
+		Error("Failed to store file %s: %v", key, err)
+		http.Error(w, "Failed to save file", http.StatusInternalServerError)
 		return
 	}
 
+	// Insert metadata into DB, filling in id and uploaded_at
 	record := models.FileRecord{
-		ID:         recordID,
-		PetID:      petID,
-		FileName:   handler.Filename,
-		FilePath:   filePath,
-		UploadedAt: uploadedAt.Format(time.RFC3339),
+		PetID:       petID,
+		FileName:    part.FileName(),
+		FilePath:    key,
+		Size:        counter.n,
+		SHA256:      hex.EncodeToString(hash.Sum(nil)),
+		ContentType: contentType,
+		ScanStatus:  env.newFileScanStatus(),
+		DocumentID:  documentID,
+	}
+	if err := env.Files.Create(r.Context(), scope.Scope, &record); err != nil {
+		// attempt to remove saved file if DB insert fails
+		if err := env.Blobs.Delete(r.Context(), key); err != nil {
+			Warn("Could not delete orphaned file %s: %v", key, err)
+		}
+		var refErr *store.ReferenceError
+		switch {
+		case errors.As(err, &refErr):
+			http.Error(w, refErr.Error(), http.StatusBadRequest)
+		case errors.Is(err, store.ErrConflict):
+			http.Error(w, "Another version of this document was uploaded at the same time; try again", http.StatusConflict)
+		default:
+			Error("DB insert failed: %v", err)
+			http.Error(w, "Database error while saving metadata", http.StatusInternalServerError)
+		}
+		return
 	}
 
-	Info("File uploaded successfully: %s (Pet ID: %d)", handler.Filename, petID)
+	Info("File uploaded successfully: %s, %d bytes (Pet ID: %d, version %d)", record.FileName, record.Size, petID, record.Version)
+	env.startScan(record)
+	env.startThumbnails(record)
+	record.ThumbnailURL = env.thumbnailURL(r, record)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(record)
 }
 
-// DownloadFileHandler allows users to download a pet’s file by ID
+// DownloadFileHandler allows users to download a pet’s file by ID. The current version
+// of the file's document is sent unless ?version= asks for an earlier one.
 func (env *Env) DownloadFileHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
@@ -119,12 +185,14 @@ func (env *Env) DownloadFileHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var fileRecord models.FileRecord
-	var uploadedAt time.Time
-	sqlStatement := `SELECT id, pet_id, file_name, file_path, uploaded_at FROM file_records WHERE id = $1`
-	err = env.DB.QueryRow(sqlStatement, id).Scan(&fileRecord.ID, &fileRecord.PetID, &fileRecord.FileName, &fileRecord.FilePath, &uploadedAt)
+	scope, ok := env.requestScope(w, r)
+	if !ok {
+		return
+	}
+
+	versions, err := env.fileVersions(r.Context(), scope, id)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, store.ErrNotFound) {
 			Warn("File not found in DB: id=%d", id)
 			http.Error(w, "File not found", http.StatusNotFound)
 		} else {
@@ -133,17 +201,113 @@ func (env *Env) DownloadFileHandler(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
-	fileRecord.UploadedAt = uploadedAt.Format(time.RFC3339)
+	fileRecord, ok := pickVersion(versions, r.URL.Query().Get("version"))
+	if !ok {
+		http.Error(w, "Version not found", http.StatusNotFound)
+		return
+	}
+	env.serveFile(w, r, fileRecord)
+}
 
-	// Verify file exists on disk
-	if _, err := os.Stat(fileRecord.FilePath); os.IsNotExist(err) {
-		Error("File not found on disk: %s", fileRecord.FilePath)
-		http.Error(w, "File not found on server", http.StatusInternalServerError)
+// serveFile sends the contents of a file unless its scan blocks it
+func (env *Env) serveFile(w http.ResponseWriter, r *http.Request, fileRecord models.FileRecord) {
+	body, info, ok := env.openFile(w, r, fileRecord)
+	if !ok {
 		return
 	}
+	defer body.Close()
+	sendFile(w, r, fileRecord, body, info)
+}
 
-	// Serve file as attachment
-	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", fileRecord.FileName))
-	http.ServeFile(w, r, fileRecord.FilePath)
+// openFile opens the stored contents of a file, writing the error response if its scan
+// blocks it or storage fails. The caller closes the reader.
+func (env *Env) openFile(w http.ResponseWriter, r *http.Request, fileRecord models.FileRecord) (io.ReadCloser, blob.Info, bool) {
+	if writeScanBlocked(w, fileRecord) {
+		return nil, blob.Info{}, false
+	}
+	body, info, err := env.Blobs.Get(r.Context(), fileRecord.FilePath)
+	if err != nil {
+		if errors.Is(err, blob.ErrNotFound) {
+			Error("File not found in storage: %s", fileRecord.FilePath)
+			http.Error(w, "File not found on server", http.StatusInternalServerError)
+		} else {
+			Error("Error reading file %s from storage: %v", fileRecord.FilePath, err)
+			http.Error(w, "Storage error", http.StatusInternalServerError)
+		}
+		return nil, blob.Info{}, false
+	}
+	return body, info, true
+}
+
+// sendFile writes the opened contents of a file as the response
+func sendFile(w http.ResponseWriter, r *http.Request, fileRecord models.FileRecord, body io.ReadCloser, info blob.Info) {
+	// Serve file as attachment with its detected type, which browsers must not second-guess;
+	// older files without one are served as opaque bytes. Local files also support range requests.
+	contentType := fileRecord.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": fileRecord.FileName}))
+	if rs, ok := body.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, fileRecord.FileName, info.ModTime, rs)
+	} else {
+		if info.Size >= 0 {
+			w.Header().Set("Content-Length", strconv.FormatInt(info.Size, 10))
+		}
+		if _, err := io.Copy(w, body); err != nil {
+			Warn("Download of file ID %d interrupted: %v", fileRecord.ID, err)
+			return
+		}
+	}
 	Info("File downloaded: %s (Pet ID: %d)", fileRecord.FileName, fileRecord.PetID)
 }
+
+// maxUploadBytes is the configured upload size limit
+func (env *Env) maxUploadBytes() int64 {
+	if env.MaxUploadBytes > 0 {
+		return env.MaxUploadBytes
+	}
+	return defaultMaxUploadBytes
+}
+
+// writeUploadReadError answers a failure to read the upload body, which is the client's
+// doing: either the body is larger than maxBytes or it is not a well-formed form
+func writeUploadReadError(w http.ResponseWriter, err error, maxBytes int64) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		Warn("Upload rejected: larger than %d bytes", maxBytes)
+		http.Error(w, fmt.Sprintf("File exceeds the upload limit of %d MB", maxBytes>>20), http.StatusRequestEntityTooLarge)
+		return
+	}
+	Warn("Failed to read multipart upload: %v", err)
+	http.Error(w, "Error parsing form", http.StatusBadRequest)
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// petFileKeyPrefix starts the blob keys of every file of a pet
+func petFileKeyPrefix(petID int) string {
+	return fmt.Sprintf("pet%d_", petID)
+}
+
+// newFileKey creates a unique blob key with timestamp for a pet's file of the detected type;
+// the random part keeps replicas from colliding
+func newFileKey(petID int, contentType string) (string, error) {
+	suffix, err := randomToken(6)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s%d_%s%s", petFileKeyPrefix(petID), time.Now().Unix(), suffix, fileExtension(contentType)), nil
+}
@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"pets_project/internal/store"
+)
+
+// Download links let people without an account, such as an owner reading an email or an
+// external specialist, download one file. A link carries its file, clinic, expiry and
+// whether it is single use, signed with HMAC-SHA256; nothing is stored until a single-use
+// link is used.
+const (
+	defaultLinkTTL = 24 * time.Hour
+	maxLinkTTL     = 7 * 24 * time.Hour
+)
+
+// downloadLink is the signed content of a link
+type downloadLink struct {
+	FileID    int
+	ClinicID  int
+	Expires   int64 // unix time
+	Nonce     string
+	SingleUse bool
+}
+
+func (l downloadLink) signature(key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	fmt.Fprintf(mac, "%d\n%d\n%d\n%s\n%t", l.FileID, l.ClinicID, l.Expires, l.Nonce, l.SingleUse)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// === Shared Download Handlers =====================================================
+// SharedDownloadHandler serves GET /shared/files/{id}?clinic=&expires=&nonce=[&once=1]&sig=
+// without a JWT: the signature stands in for the login. Exported to main.go.
+func (env *Env) SharedDownloadHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+
+	link, ok := parseDownloadLink(r)
+	if !ok || !hmac.Equal([]byte(r.URL.Query().Get("sig")), []byte(link.signature(env.linkKey()))) {
+		Warn("Rejected download link with an invalid signature: %s", r.URL.Path)
+		http.Error(w, "Invalid download link", http.StatusForbidden)
+		return
+	}
+	expires := time.Unix(link.Expires, 0)
+	if time.Now().After(expires) {
+		http.Error(w, "Download link has expired", http.StatusGone)
+		return
+	}
+
+	f, err := env.Files.Get(r.Context(), store.Scope{ClinicID: link.ClinicID}, link.FileID)
+	if err != nil {
+		writeStoreError(w, err, "File not found")
+		return
+	}
+	// The file is opened first so that a storage failure does not use up a single-use link
+	body, info, ok := env.openFile(w, r, f)
+	if !ok {
+		return
+	}
+	defer body.Close()
+	if link.SingleUse {
+		fresh, err := env.Links.Use(r.Context(), link.Nonce, expires)
+		if err != nil {
+			Error("Failed to record use of download link: %v", err)
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		if !fresh {
+			Warn("Single-use download link for file ID %d used again", f.ID)
+			http.Error(w, "Download link has already been used", http.StatusGone)
+			return
+		}
+	}
+	sendFile(w, r, f, body, info)
+}
+
+// ExpireDownloadLinks forgets used single-use links once they have expired. main runs it periodically.
+func (env *Env) ExpireDownloadLinks(ctx context.Context) {
+	count, err := env.Links.DeleteExpired(ctx, time.Now())
+	if err != nil {
+		Error("Failed to expire used download links: %v", err)
+		return
+	}
+	if count > 0 {
+		Info("Forgot %d expired download link(s)", count)
+	}
+}
+
+// --- Download Link Functions (internal) ---
+
+// createDownloadLink serves POST /files/{id}/link with an optional body
+// {"expires_in_minutes": 60, "single_use": true, "version": 2}. The link is for the
+// version that is current when it is created, unless another is asked for.
+func (env *Env) createDownloadLink(w http.ResponseWriter, r *http.Request, id int) {
+	var body struct {
+		ExpiresInMinutes int  `json:"expires_in_minutes"`
+		SingleUse        bool `json:"single_use"`
+		Version          int  `json:"version"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+	}
+	ttl := defaultLinkTTL
+	if body.ExpiresInMinutes != 0 {
+		ttl = time.Duration(body.ExpiresInMinutes) * time.Minute
+	}
+	if ttl <= 0 || ttl > maxLinkTTL {
+		http.Error(w, fmt.Sprintf("expires_in_minutes must be between 1 and %d", int(maxLinkTTL.Minutes())), http.StatusBadRequest)
+		return
+	}
+
+	scope, ok := env.requestScope(w, r)
+	if !ok {
+		return
+	}
+	versions, err := env.fileVersions(r.Context(), scope, id)
+	if err != nil {
+		writeStoreError(w, err, "File not found")
+		return
+	}
+	version := ""
+	if body.Version != 0 {
+		version = strconv.Itoa(body.Version)
+	}
+	f, ok := pickVersion(versions, version)
+	if !ok {
+		http.Error(w, "Version not found", http.StatusNotFound)
+		return
+	}
+
+	nonce, err := randomToken(16)
+	if err != nil {
+		Error("Failed to generate download link nonce: %v", err)
+		http.Error(w, "Failed to create download link", http.StatusInternalServerError)
+		return
+	}
+	expires := time.Now().Add(ttl).Truncate(time.Second)
+	link := downloadLink{FileID: f.ID, ClinicID: scope.ClinicID, Expires: expires.Unix(), Nonce: nonce, SingleUse: body.SingleUse}
+	query := url.Values{
+		"clinic":  {strconv.Itoa(link.ClinicID)},
+		"expires": {strconv.FormatInt(link.Expires, 10)},
+		"nonce":   {link.Nonce},
+		"sig":     {link.signature(env.linkKey())},
+	}
+	if link.SingleUse {
+		query.Set("once", "1")
+	}
+
+	userID, _ := r.Context().Value("userID").(int)
+	Info("User ID %d created a download link for file ID %d (expires %s, single use %t)", userID, f.ID,
+		expires.UTC().Format(time.RFC3339), link.SingleUse)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"url":        fmt.Sprintf("%s/shared/files/%d?%s", env.baseURL(r), f.ID, query.Encode()),
+		"file_id":    f.ID,
+		"version":    f.Version,
+		"expires_at": expires.UTC().Format(time.RFC3339),
+		"single_use": link.SingleUse,
+	})
+}
+
+// parseDownloadLink reads the signed fields of a link from the request
+func parseDownloadLink(r *http.Request) (downloadLink, bool) {
+	q := r.URL.Query()
+	fileID, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/shared/files/"))
+	if err != nil {
+		return downloadLink{}, false
+	}
+	clinicID, err := strconv.Atoi(q.Get("clinic"))
+	if err != nil {
+		return downloadLink{}, false
+	}
+	expires, err := strconv.ParseInt(q.Get("expires"), 10, 64)
+	if err != nil || q.Get("nonce") == "" {
+		return downloadLink{}, false
+	}
+	return downloadLink{FileID: fileID, ClinicID: clinicID, Expires: expires, Nonce: q.Get("nonce"), SingleUse: q.Get("once") == "1"}, true
+}
+
+// linkKey signs download links: Env.LinkSecret, or else a key derived from JWT_SECRET so
+// that the same secret does not sign two kinds of token directly
+func (env *Env) linkKey() []byte {
+	if len(env.LinkSecret) > 0 {
+		return env.LinkSecret
+	}
+	mac := hmac.New(sha256.New, []byte(os.Getenv("JWT_SECRET")))
+	mac.Write([]byte("download links"))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"pets_project/internal/blob"
+	"pets_project/internal/models"
+)
+
+// failingBlobs is a blob store whose Get fails while down is set, like S3 during an outage
+type failingBlobs struct {
+	blob.Store
+	down bool
+}
+
+func (b *failingBlobs) Get(ctx context.Context, key string) (io.ReadCloser, blob.Info, error) {
+	if b.down {
+		return nil, blob.Info{}, errors.New("storage unavailable")
+	}
+	return b.Store.Get(ctx, key)
+}
+
+// createLink asks for a download link and returns the path and query it points at
+func (ts *testServer) createLink(t *testing.T, token string, fileID int, body interface{}) string {
+	t.Helper()
+	var resp struct {
+		URL string `json:"url"`
+	}
+	expect(t, ts.do(t, token, "POST", fmt.Sprintf("/files/%d/link", fileID), body), http.StatusCreated, &resp)
+	u, err := url.Parse(resp.URL)
+	if err != nil {
+		t.Fatalf("parse link %q: %v", resp.URL, err)
+	}
+	return u.RequestURI()
+}
+
+// signedPath builds the URL of a link the way createDownloadLink does
+func (ts *testServer) signedPath(link downloadLink) string {
+	q := url.Values{
+		"clinic":  {strconv.Itoa(link.ClinicID)},
+		"expires": {strconv.FormatInt(link.Expires, 10)},
+		"nonce":   {link.Nonce},
+		"sig":     {link.signature(ts.env.linkKey())},
+	}
+	if link.SingleUse {
+		q.Set("once", "1")
+	}
+	return fmt.Sprintf("/shared/files/%d?%s", link.FileID, q.Encode())
+}
+
+func TestSharedDownload(t *testing.T) {
+	ts := newTestServer(t)
+	_, token := ts.login(t, 1, models.RoleVet)
+	pet := ts.newPet(t, 1, "Rex")
+	v1 := ts.newFile(t, 1, pet.ID, 0, "report.txt", "text/plain", []byte("first"))
+
+	path := ts.createLink(t, token, v1.ID, nil)
+	rec := ts.do(t, "", "GET", path, nil)
+	expect(t, rec, http.StatusOK, nil)
+	if rec.Body.String() != "first" || rec.Header().Get("Cache-Control") != "no-store" {
+		t.Errorf("shared download = %q with Cache-Control %q", rec.Body.String(), rec.Header().Get("Cache-Control"))
+	}
+	// Links that are not single use work until they expire
+	expect(t, ts.do(t, "", "GET", path, nil), http.StatusOK, nil)
+
+	// A link keeps pointing at the version current when it was made
+	ts.newFile(t, 1, pet.ID, v1.ID, "report.txt", "text/plain", []byte("second"))
+	rec = ts.do(t, "", "GET", path, nil)
+	expect(t, rec, http.StatusOK, nil)
+	if rec.Body.String() != "first" {
+		t.Errorf("old link serves %q, want the first version", rec.Body.String())
+	}
+	rec = ts.do(t, "", "GET", ts.createLink(t, token, v1.ID, nil), nil)
+	expect(t, rec, http.StatusOK, nil)
+	if rec.Body.String() != "second" {
+		t.Errorf("new link serves %q, want the current version", rec.Body.String())
+	}
+	rec = ts.do(t, "", "GET", ts.createLink(t, token, v1.ID, map[string]int{"version": 1}), nil)
+	expect(t, rec, http.StatusOK, nil)
+	if rec.Body.String() != "first" {
+		t.Errorf("link for version 1 serves %q", rec.Body.String())
+	}
+}
+
+func TestSharedDownloadRejectsTampering(t *testing.T) {
+	ts := newTestServer(t)
+	_, token := ts.login(t, 1, models.RoleVet)
+	pet := ts.newPet(t, 1, "Rex")
+	f := ts.newFile(t, 1, pet.ID, 0, "report.txt", "text/plain", []byte("secret"))
+	other := ts.newFile(t, 1, pet.ID, 0, "other.txt", "text/plain", []byte("other"))
+
+	u, err := url.Parse(ts.createLink(t, token, f.ID, map[string]bool{"single_use": true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		change func(path *string, q url.Values)
+	}{
+		{"other file", func(path *string, q url.Values) { *path = fmt.Sprintf("/shared/files/%d", other.ID) }},
+		{"other clinic", func(path *string, q url.Values) { q.Set("clinic", "2") }},
+		{"later expiry", func(path *string, q url.Values) {
+			expires, _ := strconv.ParseInt(q.Get("expires"), 10, 64)
+			q.Set("expires", strconv.FormatInt(expires+86400, 10))
+		}},
+		{"other nonce", func(path *string, q url.Values) { q.Set("nonce", "AAAAAAAAAAAAAAAAAAAAAA") }},
+		{"reusable", func(path *string, q url.Values) { q.Del("once") }},
+		{"altered signature", func(path *string, q url.Values) {
+			sig := []byte(q.Get("sig"))
+			sig[0] ^= 1
+			q.Set("sig", string(sig))
+		}},
+		{"no signature", func(path *string, q url.Values) { q.Del("sig") }},
+		{"no nonce", func(path *string, q url.Values) { q.Del("nonce") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, q := u.Path, u.Query()
+			tt.change(&path, q)
+			expect(t, ts.do(t, "", "GET", path+"?"+q.Encode(), nil), http.StatusForbidden, nil)
+		})
+	}
+
+	// None of the rejected requests used up the single-use link
+	expect(t, ts.do(t, "", "GET", u.RequestURI(), nil), http.StatusOK, nil)
+
+	// Links signed with another key are rejected too
+	link := downloadLink{FileID: f.ID, ClinicID: 1, Expires: time.Now().Add(time.Hour).Unix(), Nonce: "n"}
+	path := ts.signedPath(link)
+	ts.env.LinkSecret = []byte("another key")
+	expect(t, ts.do(t, "", "GET", path, nil), http.StatusForbidden, nil)
+	expect(t, ts.do(t, "", "GET", ts.signedPath(link), nil), http.StatusOK, nil)
+}
+
+func TestSharedDownloadExpiry(t *testing.T) {
+	ts := newTestServer(t)
+	pet := ts.newPet(t, 1, "Rex")
+	f := ts.newFile(t, 1, pet.ID, 0, "report.txt", "text/plain", []byte("secret"))
+
+	expired := downloadLink{FileID: f.ID, ClinicID: 1, Expires: time.Now().Add(-time.Second).Unix(), Nonce: "n"}
+	expect(t, ts.do(t, "", "GET", ts.signedPath(expired), nil), http.StatusGone, nil)
+	expired.SingleUse = true
+	expect(t, ts.do(t, "", "GET", ts.signedPath(expired), nil), http.StatusGone, nil)
+
+	valid := downloadLink{FileID: f.ID, ClinicID: 1, Expires: time.Now().Add(time.Minute).Unix(), Nonce: "n"}
+	expect(t, ts.do(t, "", "GET", ts.signedPath(valid), nil), http.StatusOK, nil)
+}
+
+func TestSharedDownloadSingleUse(t *testing.T) {
+	ts := newTestServer(t)
+	_, token := ts.login(t, 1, models.RoleVet)
+	pet := ts.newPet(t, 1, "Rex")
+	f := ts.newFile(t, 1, pet.ID, 0, "report.txt", "text/plain", []byte("secret"))
+
+	first := ts.createLink(t, token, f.ID, map[string]bool{"single_use": true})
+	second := ts.createLink(t, token, f.ID, map[string]bool{"single_use": true})
+	expect(t, ts.do(t, "", "GET", first, nil), http.StatusOK, nil)
+	rec := ts.do(t, "", "GET", first, nil)
+	expect(t, rec, http.StatusGone, nil)
+	if rec.Body.String() != "Download link has already been used\n" {
+		t.Errorf("second redemption answered %q", rec.Body.String())
+	}
+	// Each single-use link is used up on its own
+	expect(t, ts.do(t, "", "GET", second, nil), http.StatusOK, nil)
+	expect(t, ts.do(t, "", "GET", second, nil), http.StatusGone, nil)
+}
+
+func TestCreateDownloadLinkValidation(t *testing.T) {
+	ts := newTestServer(t)
+	_, token := ts.login(t, 1, models.RoleVet)
+	pet := ts.newPet(t, 1, "Rex")
+	f := ts.newFile(t, 1, pet.ID, 0, "report.txt", "text/plain", []byte("secret"))
+
+	tests := []struct {
+		name   string
+		id     int
+		body   interface{}
+		status int
+	}{
+		{"negative expiry", f.ID, map[string]int{"expires_in_minutes": -5}, http.StatusBadRequest},
+		{"expiry beyond a week", f.ID, map[string]int{"expires_in_minutes": 7*24*60 + 1}, http.StatusBadRequest},
+		{"missing version", f.ID, map[string]int{"version": 2}, http.StatusNotFound},
+		{"missing file", 999, nil, http.StatusNotFound},
+		{"a week", f.ID, map[string]int{"expires_in_minutes": 7 * 24 * 60}, http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expect(t, ts.do(t, token, "POST", fmt.Sprintf("/files/%d/link", tt.id), tt.body), tt.status, nil)
+		})
+	}
+
+	// Staff of another clinic cannot share the file
+	other := ts.newClinic(t, "Other Clinic")
+	_, otherToken := ts.login(t, other, models.RoleVet)
+	expect(t, ts.do(t, otherToken, "POST", fmt.Sprintf("/files/%d/link", f.ID), nil), http.StatusNotFound, nil)
+}
+
+func TestSingleUseLinkSurvivesStorageFailure(t *testing.T) {
+	ts := newTestServer(t)
+	_, token := ts.login(t, 1, models.RoleVet)
+	pet := ts.newPet(t, 1, "Rex")
+	f := ts.newFile(t, 1, pet.ID, 0, "report.txt", "text/plain", []byte("secret"))
+	path := ts.createLink(t, token, f.ID, map[string]bool{"single_use": true})
+
+	blobs := &failingBlobs{Store: ts.env.Blobs, down: true}
+	ts.env.Blobs = blobs
+	expect(t, ts.do(t, "", "GET", path, nil), http.StatusInternalServerError, nil)
+	expect(t, ts.do(t, "", "GET", path, nil), http.StatusInternalServerError, nil)
+
+	// Once storage is back the link works, once
+	blobs.down = false
+	rec := ts.do(t, "", "GET", path, nil)
+	expect(t, rec, http.StatusOK, nil)
+	if rec.Body.String() != "secret" {
+		t.Errorf("download = %q, want the file", rec.Body.String())
+	}
+	expect(t, ts.do(t, "", "GET", path, nil), http.StatusGone, nil)
+}
@@ -2,16 +2,52 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
-	"os"
 	"strconv"
+	"strings"
 
-	"pets_project/internal/models"
+	"pets_project/internal/store"
 )
 
+// === File Sub-resource Handlers ===================================================
+// FilesHandler is the mini-router for /files/{id}/versions, /files/{id}/link and
+// /files/{id}/thumbnail. Exported to main.go.
+func (env *Env) FilesHandler(w http.ResponseWriter, r *http.Request) {
+	idStr, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/files/"), "/")
+	id, err := strconv.Atoi(idStr)
+	if err != nil || id <= 0 {
+		http.Error(w, "Invalid file ID in path", http.StatusBadRequest)
+		return
+	}
+	switch rest {
+	case "versions":
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed for /files/{id}/versions", http.StatusMethodNotAllowed)
+			return
+		}
+		env.listVersions(w, r, id)
+	case "link":
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed for /files/{id}/link", http.StatusMethodNotAllowed)
+			return
+		}
+		env.createDownloadLink(w, r, id)
+	case "thumbnail":
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed for /files/{id}/thumbnail", http.StatusMethodNotAllowed)
+			return
+		}
+		env.getThumbnail(w, r, id)
+	default:
+		http.Error(w, "Not found", http.StatusNotFound)
+	}
+}
+
 // ================================
 // LIST FILES FOR PET
 // GET /files?pet_id=1
+// Lists the current version of each document
 // ================================
 func (env *Env) ListFilesHandler(w http.ResponseWriter, r *http.Request) {
 	petID := r.URL.Query().Get("pet_id")
@@ -26,25 +62,43 @@ func (env *Env) ListFilesHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	rows, err := env.DB.Query(`SELECT id, pet_id, file_name, file_path, uploaded_at FROM file_records WHERE pet_id = $1`, id)
+	scope, ok := env.requestScope(w, r)
+	if !ok {
+		return
+	}
+	if !env.checkPetInScope(w, r, scope, id) {
+		return
+	}
+
+	files, err := env.Files.ListByPet(r.Context(), scope.Scope, id)
 	if err != nil {
 		Error("Database error while fetching files: %v", err)
 		http.Error(w, "Database error", http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
 
-	files := []models.FileRecord{}
-
-	for rows.Next() {
-		var fr models.FileRecord
-		err := rows.Scan(&fr.ID, &fr.PetID, &fr.FileName, &fr.FilePath, &fr.UploadedAt)
-		if err != nil {
-			Error("Error scanning file record: %v", err)
-			http.Error(w, "Database error", http.StatusInternalServerError)
-			return
+	// Older files have no recorded size, which the blob store provides; the list is
+	// still served without it if the store is unavailable
+	stored, err := env.Blobs.List(r.Context(), petFileKeyPrefix(id))
+	if err != nil {
+		Warn("Could not list stored files of pet ID %d: %v", id, err)
+	} else {
+		sizes := map[string]int64{}
+		for _, info := range stored {
+			sizes[info.Key] = info.Size
+		}
+		for i := range files {
+			size, ok := sizes[files[i].FilePath]
+			if !ok {
+				Warn("File ID %d has no stored contents: %s", files[i].ID, files[i].FilePath)
+			} else if files[i].SHA256 == "" {
+				files[i].Size = size
+			}
 		}
-		files = append(files, fr)
+	}
+
+	for i := range files {
+		files[i].ThumbnailURL = env.thumbnailURL(r, files[i])
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -54,6 +108,7 @@ func (env *Env) ListFilesHandler(w http.ResponseWriter, r *http.Request) {
 // ================================
 // DELETE FILE
 // DELETE /files/delete?id=1
+// Deletes that one version; the previous version becomes current again
 // ================================
 func (env *Env) DeleteFileHandler(w http.ResponseWriter, r *http.Request) {
 	fileID := r.URL.Query().Get("id")
@@ -68,27 +123,28 @@ func (env *Env) DeleteFileHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// First fetch file path
-	var filePath string
-	err = env.DB.QueryRow(`SELECT file_path FROM file_records WHERE id = $1`, id).Scan(&filePath)
-	if err != nil {
-		http.Error(w, "File not found", http.StatusNotFound)
+	scope, ok := env.requestScope(w, r)
+	if !ok {
 		return
 	}
 
-	// Delete DB record
-	_, err = env.DB.Exec(`DELETE FROM file_records WHERE id = $1`, id)
+	// Delete DB record, getting back the path of the stored file
+	record, err := env.Files.Delete(r.Context(), scope.Scope, id)
 	if err != nil {
-		Error("Failed to delete file record: %v", err)
-		http.Error(w, "Database error", http.StatusInternalServerError)
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "File not found", http.StatusNotFound)
+		} else {
+			Error("Failed to delete file record: %v", err)
+			http.Error(w, "Database error", http.StatusInternalServerError)
+		}
 		return
 	}
 
-	// Delete physical file
-	err = os.Remove(filePath)
-	if err != nil {
-		Warn("Could not delete file from disk: %v", err)
+	// Delete stored contents
+	if err := env.Blobs.Delete(r.Context(), record.FilePath); err != nil {
+		Warn("Could not delete file from storage: %v", err)
 	}
+	env.deleteThumbnails(r.Context(), record)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{
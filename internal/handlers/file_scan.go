@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"pets_project/internal/models"
+	"pets_project/internal/scan"
+	"pets_project/internal/store"
+)
+
+// scanRetryAfter is how long a file may stay pending before ScanPendingFiles scans it
+// again; the scan started after its upload normally finishes well within it
+const scanRetryAfter = 5 * time.Minute
+
+// scanBatchSize limits the files ScanPendingFiles handles per run
+const scanBatchSize = 100
+
+// newFileScanStatus is the scan status of a file record about to be created
+func (env *Env) newFileScanStatus() string {
+	if env.Scanner == nil {
+		return models.FileUnscanned
+	}
+	return models.FilePendingScan
+}
+
+// startScan scans a newly created file in the background. Its request may finish first,
+// so the scan does not use the request's context. While every job slot is busy the file
+// is left to ScanPendingFiles.
+func (env *Env) startScan(f models.FileRecord) {
+	if env.Scanner == nil || f.ScanStatus != models.FilePendingScan {
+		return
+	}
+	if !env.startJob(func() { env.scanFile(context.Background(), f) }) {
+		Info("Scan of file %d deferred: scanner busy", f.ID)
+	}
+}
+
+// ScanPendingFiles scans files whose scan failed or was interrupted, e.g. because the
+// scanner was unreachable or the server restarted. main runs it periodically.
+func (env *Env) ScanPendingFiles(ctx context.Context) {
+	files, err := env.Files.PendingScans(ctx, time.Now().Add(-scanRetryAfter), scanBatchSize)
+	if err != nil {
+		Error("Failed to list files awaiting a scan: %v", err)
+		return
+	}
+	for _, f := range files {
+		env.scanFile(ctx, f)
+	}
+}
+
+// scanFile scans a pending file and records the verdict. On failure the file stays
+// pending, and so undownloadable, until a later ScanPendingFiles run succeeds; a file the
+// scanner refuses is marked failed instead, since no run would succeed.
+func (env *Env) scanFile(ctx context.Context, f models.FileRecord) {
+	body, _, err := env.Blobs.Get(ctx, f.FilePath)
+	if err != nil {
+		Error("Failed to read file %d (%s) for scanning: %v", f.ID, f.FilePath, err)
+		return
+	}
+	defer body.Close()
+
+	status, detail := models.FileClean, ""
+	result, err := env.Scanner.Scan(ctx, body)
+	var refused *scan.RefusedError
+	switch {
+	case errors.As(err, &refused):
+		status, detail = models.FileScanFailed, refused.Reason
+	case err != nil:
+		Error("Failed to scan file %d (%s): %v", f.ID, f.FilePath, err)
+		return
+	case result.Infected:
+		status, detail = models.FileInfected, result.Signature
+	}
+	err = env.Files.SetScanResult(ctx, f.ID, status, detail, time.Now())
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		// deleted meanwhile, or already scanned by another run
+	case err != nil:
+		Error("Failed to record scan of file %d: %v", f.ID, err)
+	case status == models.FileInfected:
+		Warn("File %d (%s, pet ID %d) quarantined: %s found", f.ID, f.FileName, f.PetID, detail)
+	case status == models.FileScanFailed:
+		Warn("File %d (%s, pet ID %d) cannot be scanned and stays blocked: %s", f.ID, f.FileName, f.PetID, detail)
+	default:
+		Info("File %d scanned clean", f.ID)
+	}
+}
+
+// writeScanBlocked refuses downloads of files that are not known to be safe and
+// reports whether it did
+func writeScanBlocked(w http.ResponseWriter, f models.FileRecord) bool {
+	switch f.ScanStatus {
+	case models.FilePendingScan:
+		w.Header().Set("Retry-After", "60")
+		http.Error(w, "File is awaiting a virus scan", http.StatusConflict)
+	case models.FileInfected:
+		http.Error(w, "File is quarantined: malware was found", http.StatusForbidden)
+	case models.FileScanFailed:
+		http.Error(w, "File is blocked: it could not be scanned for viruses ("+f.ScanSignature+")", http.StatusForbidden)
+	default:
+		return false
+	}
+	Warn("Download of file %d blocked: scan status %s", f.ID, f.ScanStatus)
+	return true
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"pets_project/internal/blob"
+	"pets_project/internal/models"
+	"pets_project/internal/thumbnail"
+)
+
+// thumbnailSizes are the variants of each image's thumbnail, by the pixels of their
+// longer side; defaultThumbnailSize is used without ?size=
+var thumbnailSizes = map[string]int{"small": 128, "medium": 256, "large": 512}
+
+const defaultThumbnailSize = "medium"
+
+// --- Thumbnail Functions (internal) ---
+
+// getThumbnail serves GET /files/{id}/thumbnail?size=small|medium|large. Thumbnails are
+// made after upload; those of older images, or lost ones, are made on first request.
+func (env *Env) getThumbnail(w http.ResponseWriter, r *http.Request, id int) {
+	sizeName := r.URL.Query().Get("size")
+	if sizeName == "" {
+		sizeName = defaultThumbnailSize
+	}
+	size, ok := thumbnailSizes[sizeName]
+	if !ok {
+		http.Error(w, "size must be small, medium or large", http.StatusBadRequest)
+		return
+	}
+
+	scope, ok := env.requestScope(w, r)
+	if !ok {
+		return
+	}
+	f, err := env.Files.Get(r.Context(), scope.Scope, id)
+	if err != nil {
+		writeStoreError(w, err, "File not found")
+		return
+	}
+	if !hasThumbnails(f) {
+		http.Error(w, "Thumbnails are only available for JPEG and PNG images", http.StatusNotFound)
+		return
+	}
+	if writeScanBlocked(w, f) {
+		return
+	}
+
+	key := thumbnailKey(f, size)
+	body, info, err := env.Blobs.Get(r.Context(), key)
+	if errors.Is(err, blob.ErrNotFound) {
+		// Made here, they take a job slot like those made after upload
+		if !env.acquireJob() {
+			w.Header().Set("Retry-After", "5")
+			http.Error(w, "Thumbnail is not ready yet", http.StatusServiceUnavailable)
+			return
+		}
+		made := env.makeThumbnails(r.Context(), f)
+		env.releaseJob()
+		if made != nil {
+			Error("Failed to make thumbnails of file %d: %v", f.ID, made)
+			http.Error(w, "Could not make a thumbnail of this image", http.StatusUnprocessableEntity)
+			return
+		}
+		body, info, err = env.Blobs.Get(r.Context(), key)
+	}
+	if err != nil {
+		Error("Error reading thumbnail %s from storage: %v", key, err)
+		http.Error(w, "Storage error", http.StatusInternalServerError)
+		return
+	}
+	defer body.Close()
+
+	// A file's contents never change, so browsers may keep its thumbnails
+	w.Header().Set("Content-Type", thumbnailType(f))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	if rs, ok := body.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, "", info.ModTime, rs)
+		return
+	}
+	if _, err := io.Copy(w, body); err != nil {
+		Warn("Sending thumbnail %s interrupted: %v", key, err)
+	}
+}
+
+// hasThumbnails reports whether the file is an image thumbnails can be made of
+func hasThumbnails(f models.FileRecord) bool {
+	switch f.ContentType {
+	case "image/jpeg", "image/png":
+		return true
+	}
+	return false
+}
+
+// thumbnailType keeps photos as JPEG; PNG images may be transparent
+func thumbnailType(f models.FileRecord) string {
+	if f.ContentType == "image/jpeg" {
+		return "image/jpeg"
+	}
+	return "image/png"
+}
+
+// thumbnailKey stores a file's thumbnails under thumbs/, away from the pets' file keys
+func thumbnailKey(f models.FileRecord, size int) string {
+	ext := ".png"
+	if thumbnailType(f) == "image/jpeg" {
+		ext = ".jpg"
+	}
+	return fmt.Sprintf("thumbs/%s_%d%s", f.FilePath, size, ext)
+}
+
+// startThumbnails makes the thumbnails of a newly uploaded image in the background. While
+// every job slot is busy they are left to be made on first request.
+func (env *Env) startThumbnails(f models.FileRecord) {
+	if !hasThumbnails(f) {
+		return
+	}
+	started := env.startJob(func() {
+		if err := env.makeThumbnails(context.Background(), f); err != nil {
+			Warn("Failed to make thumbnails of file %d: %v", f.ID, err)
+		}
+	})
+	if !started {
+		Info("Thumbnails of file %d deferred: too many images in progress", f.ID)
+	}
+}
+
+// makeThumbnails decodes the image once and stores every size of its thumbnail
+func (env *Env) makeThumbnails(ctx context.Context, f models.FileRecord) error {
+	body, _, err := env.Blobs.Get(ctx, f.FilePath)
+	if err != nil {
+		return err
+	}
+	img, err := thumbnail.Decode(body)
+	body.Close()
+	if err != nil {
+		return err
+	}
+	contentType := thumbnailType(f)
+	for _, size := range thumbnailSizes {
+		var buf bytes.Buffer
+		if err := thumbnail.Encode(&buf, thumbnail.Fit(img, size), contentType); err != nil {
+			return err
+		}
+		if err := env.Blobs.Put(ctx, thumbnailKey(f, size), &buf, int64(buf.Len()), contentType); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteThumbnails removes the thumbnails of a deleted file; most files have none
+func (env *Env) deleteThumbnails(ctx context.Context, f models.FileRecord) {
+	if !hasThumbnails(f) {
+		return
+	}
+	for _, size := range thumbnailSizes {
+		key := thumbnailKey(f, size)
+		if err := env.Blobs.Delete(ctx, key); err != nil && !errors.Is(err, blob.ErrNotFound) {
+			Warn("Could not delete thumbnail %s: %v", key, err)
+		}
+	}
+}
+
+// thumbnailURL is where the file's thumbnail is served, or "" if it has none
+func (env *Env) thumbnailURL(r *http.Request, f models.FileRecord) string {
+	if !hasThumbnails(f) {
+		return ""
+	}
+	return fmt.Sprintf("%s/files/%d/thumbnail", env.baseURL(r), f.ID)
+}
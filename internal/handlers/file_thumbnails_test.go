@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"testing"
+
+	"pets_project/internal/models"
+)
+
+func encodePNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, w, h))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestThumbnailSizes(t *testing.T) {
+	ts := newTestServer(t)
+	_, token := ts.login(t, 1, models.RoleVet)
+	pet := ts.newPet(t, 1, "Rex")
+	f := ts.newFile(t, 1, pet.ID, 0, "xray.png", "image/png", encodePNG(t, 1024, 768))
+
+	tests := []struct {
+		size         string
+		wantW, wantH int
+	}{
+		{"small", 128, 96},
+		{"medium", 256, 192},
+		{"", 256, 192},
+		{"large", 512, 384},
+	}
+	for _, tt := range tests {
+		rec := ts.do(t, token, "GET", fmt.Sprintf("/files/%d/thumbnail?size=%s", f.ID, tt.size), nil)
+		expect(t, rec, http.StatusOK, nil)
+		if ct := rec.Header().Get("Content-Type"); ct != "image/png" {
+			t.Errorf("size %q: Content-Type = %q, want image/png", tt.size, ct)
+		}
+		cfg, err := png.DecodeConfig(rec.Body)
+		if err != nil || cfg.Width != tt.wantW || cfg.Height != tt.wantH {
+			t.Errorf("size %q: thumbnail is %dx%d (%v), want %dx%d", tt.size, cfg.Width, cfg.Height, err, tt.wantW, tt.wantH)
+		}
+	}
+	expect(t, ts.do(t, token, "GET", fmt.Sprintf("/files/%d/thumbnail?size=huge", f.ID), nil), http.StatusBadRequest, nil)
+}
+
+func TestThumbnailsOfPhotosAreJPEG(t *testing.T) {
+	ts := newTestServer(t)
+	_, token := ts.login(t, 1, models.RoleVet)
+	pet := ts.newPet(t, 1, "Rex")
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 600, 900)), nil); err != nil {
+		t.Fatal(err)
+	}
+	f := ts.newFile(t, 1, pet.ID, 0, "photo.jpg", "image/jpeg", buf.Bytes())
+
+	rec := ts.do(t, token, "GET", fmt.Sprintf("/files/%d/thumbnail?size=small", f.ID), nil)
+	expect(t, rec, http.StatusOK, nil)
+	cfg, err := jpeg.DecodeConfig(rec.Body)
+	if err != nil || cfg.Width != 85 || cfg.Height != 128 {
+		t.Errorf("thumbnail is %dx%d (%v), want 85x128 JPEG", cfg.Width, cfg.Height, err)
+	}
+	if key := thumbnailKey(f, 128); key != "thumbs/"+f.FilePath+"_128.jpg" {
+		t.Errorf("thumbnailKey = %q", key)
+	}
+}
+
+func TestThumbnailRefusals(t *testing.T) {
+	ts := newTestServer(t)
+	_, token := ts.login(t, 1, models.RoleVet)
+	pet := ts.newPet(t, 1, "Rex")
+
+	doc := ts.newFile(t, 1, pet.ID, 0, "report.pdf", "application/pdf", []byte("%PDF-1.4"))
+	expect(t, ts.do(t, token, "GET", fmt.Sprintf("/files/%d/thumbnail", doc.ID), nil), http.StatusNotFound, nil)
+
+	broken := ts.newFile(t, 1, pet.ID, 0, "broken.png", "image/png", []byte("not a png"))
+	expect(t, ts.do(t, token, "GET", fmt.Sprintf("/files/%d/thumbnail", broken.ID), nil), http.StatusUnprocessableEntity, nil)
+
+	// Other clinics cannot see the file at all
+	other := ts.newClinic(t, "Other Clinic")
+	_, otherToken := ts.login(t, other, models.RoleVet)
+	img := ts.newFile(t, 1, pet.ID, 0, "xray.png", "image/png", encodePNG(t, 64, 64))
+	expect(t, ts.do(t, otherToken, "GET", fmt.Sprintf("/files/%d/thumbnail", img.ID), nil), http.StatusNotFound, nil)
+}
+
+func TestThumbnailWaitsForJobSlot(t *testing.T) {
+	ts := newTestServer(t)
+	_, token := ts.login(t, 1, models.RoleVet)
+	pet := ts.newPet(t, 1, "Rex")
+	f := ts.newFile(t, 1, pet.ID, 0, "xray.png", "image/png", encodePNG(t, 1024, 768))
+	path := fmt.Sprintf("/files/%d/thumbnail", f.ID)
+
+	// With every slot busy a missing thumbnail is not made on request
+	for i := 0; i < maxJobs; i++ {
+		if !ts.env.acquireJob() {
+			t.Fatalf("slot %d not free", i)
+		}
+	}
+	if ts.env.startJob(func() { t.Error("job ran without a free slot") }) {
+		t.Error("startJob reported a job started while every slot is busy")
+	}
+	rec := ts.do(t, token, "GET", path, nil)
+	expect(t, rec, http.StatusServiceUnavailable, nil)
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("503 without Retry-After")
+	}
+
+	ts.env.releaseJob()
+	expect(t, ts.do(t, token, "GET", path, nil), http.StatusOK, nil)
+
+	// The thumbnail made then is served even while the slots are busy again
+	if !ts.env.acquireJob() {
+		t.Fatal("slot not given back after the thumbnail was made")
+	}
+	expect(t, ts.do(t, token, "GET", path+"?size=large", nil), http.StatusOK, nil)
+}
@@ -0,0 +1,528 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"pets_project/internal/blob"
+	"pets_project/internal/models"
+	"pets_project/internal/store"
+)
+
+// Resumable uploads follow the tus 1.0.0 protocol (https://tus.io/protocols/resumable-upload)
+// with the creation, expiration and termination extensions. A client creates an upload with its
+// length and metadata, then PATCHes bytes from the offset HEAD reports until the upload is
+// complete, so a dropped connection only costs the bytes that had not arrived yet.
+//
+// Each PATCH is stored as its own chunk in the blob store, so any replica can take the next
+// one; the last chunk joins them into an ordinary file record.
+const (
+	tusVersion    = "1.0.0"
+	tusExtensions = "creation,expiration,termination"
+	// tusChunkType is the content type of PATCH bodies
+	tusChunkType = "application/offset+octet-stream"
+	// uploadTTL is how long an upload may go without receiving data before it expires
+	uploadTTL = 24 * time.Hour
+	// finishClaimTTL is how long a request's claim to finish an upload holds; an older claim
+	// was left by a request that died, and a retried PATCH may take it over
+	finishClaimTTL = 10 * time.Minute
+)
+
+// errUploadFinishing is returned by finishUpload while another request finishes the upload
+var errUploadFinishing = errors.New("upload is being completed by another request")
+
+// === Resumable Upload Handlers ====================================================
+// TusUploadsHandler is the mini-router of resumable uploads:
+//
+//	OPTIONS /files/uploads        supported version, extensions and Tus-Max-Size (no login needed)
+//	POST    /files/uploads        create an upload from Upload-Length and Upload-Metadata
+//	HEAD    /files/uploads/{id}   Upload-Offset reached so far
+//	PATCH   /files/uploads/{id}   append the body at Upload-Offset
+//	DELETE  /files/uploads/{id}   abandon the upload
+//	GET     /files/uploads/{id}   the upload as JSON, with file_id once complete (not part of tus)
+//
+// Upload-Metadata must hold pet_id, or document_id for a new version of a document, and should
+// hold filename. The file's type is detected from its contents once the upload is complete, and
+// a disallowed type fails the last PATCH.
+func (env *Env) TusUploadsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Tus-Resumable", tusVersion)
+	if r.Method == http.MethodOptions {
+		w.Header().Set("Tus-Version", tusVersion)
+		w.Header().Set("Tus-Extension", tusExtensions)
+		w.Header().Set("Tus-Max-Size", strconv.FormatInt(env.maxUploadBytes(), 10))
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if r.Method != http.MethodGet && r.Header.Get("Tus-Resumable") != tusVersion {
+		w.Header().Set("Tus-Version", tusVersion)
+		http.Error(w, "Unsupported tus version", http.StatusPreconditionFailed)
+		return
+	}
+	scope, ok := env.requestScope(w, r)
+	if !ok {
+		return
+	}
+
+	if r.URL.Path == "/files/uploads" {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed for /files/uploads", http.StatusMethodNotAllowed)
+			return
+		}
+		env.createUpload(w, r, scope)
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/files/uploads/")
+	if id == "" || strings.Contains(id, "/") {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	switch r.Method {
+	case http.MethodHead:
+		env.headUpload(w, r, scope, id)
+	case http.MethodPatch:
+		env.patchUpload(w, r, scope, id)
+	case http.MethodDelete:
+		env.deleteUpload(w, r, scope, id)
+	case http.MethodGet:
+		if u, ok := env.getUpload(w, r, scope, id); ok {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(u)
+		}
+	default:
+		http.Error(w, "Method not allowed for /files/uploads/{id}", http.StatusMethodNotAllowed)
+	}
+}
+
+// ExpireUploads removes uploads that have received nothing for uploadTTL, with their chunks.
+// main runs it periodically.
+func (env *Env) ExpireUploads(ctx context.Context) {
+	uploads, err := env.Uploads.DeleteExpired(ctx, time.Now())
+	if err != nil {
+		Error("Failed to expire uploads: %v", err)
+		return
+	}
+	for _, u := range uploads {
+		env.deleteChunks(ctx, u.ChunkKeys)
+	}
+	if len(uploads) > 0 {
+		Info("Expired %d abandoned upload(s)", len(uploads))
+	}
+}
+
+// --- Resumable Upload Functions (internal) ---
+func (env *Env) createUpload(w http.ResponseWriter, r *http.Request, scope accessScope) {
+	length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		http.Error(w, "Upload-Length is required (deferred lengths are not supported)", http.StatusBadRequest)
+		return
+	}
+	if maxBytes := env.maxUploadBytes(); length > maxBytes {
+		Warn("Upload rejected: %d bytes is larger than %d", length, maxBytes)
+		http.Error(w, fmt.Sprintf("File exceeds the upload limit of %d MB", maxBytes>>20), http.StatusRequestEntityTooLarge)
+		return
+	}
+	meta, err := parseTusMetadata(r.Header.Get("Upload-Metadata"))
+	if err != nil {
+		http.Error(w, "Invalid Upload-Metadata", http.StatusBadRequest)
+		return
+	}
+	var petID, documentID int
+	if meta["document_id"] != "" {
+		documentID, err = strconv.Atoi(meta["document_id"])
+		if err != nil || documentID <= 0 {
+			http.Error(w, "Invalid document_id in Upload-Metadata", http.StatusBadRequest)
+			return
+		}
+		var ok bool
+		if petID, ok = env.documentPetID(w, r, scope, documentID, meta["pet_id"]); !ok {
+			return
+		}
+	} else {
+		petID, err = strconv.Atoi(meta["pet_id"])
+		if err != nil || petID <= 0 {
+			Warn("Invalid pet_id provided for upload: %s", meta["pet_id"])
+			http.Error(w, "Upload-Metadata must include a valid pet_id", http.StatusBadRequest)
+			return
+		}
+	}
+	if !env.checkPetInScope(w, r, scope, petID) {
+		return
+	}
+
+	id, err := randomToken(16)
+	if err != nil {
+		Error("Failed to generate upload ID: %v", err)
+		http.Error(w, "Server error", http.StatusInternalServerError)
+		return
+	}
+	fileName := "upload"
+	if name := filepath.Base(strings.ReplaceAll(meta["filename"], `\`, "/")); name != "." && name != "/" {
+		fileName = name
+	}
+	u := models.Upload{
+		ID:          id,
+		PetID:       petID,
+		FileName:    fileName,
+		ContentType: meta["filetype"],
+		DocumentID:  documentID,
+		Length:      length,
+		ExpiresAt:   time.Now().Add(uploadTTL).UTC().Format(time.RFC3339),
+	}
+	if err := env.Uploads.Create(r.Context(), scope.Scope, &u); err != nil {
+		writeStoreError(w, err, "Pet not found")
+		return
+	}
+	if length == 0 {
+		if err := env.finishUpload(r.Context(), scope, u); err != nil {
+			env.writeFinishError(w, r.Context(), scope, u, err)
+			return
+		}
+	}
+
+	Info("Resumable upload %s created: %s, %d bytes (Pet ID: %d)", id, fileName, length, petID)
+	w.Header().Set("Location", env.baseURL(r)+"/files/uploads/"+id)
+	setUploadExpires(w, u)
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (env *Env) headUpload(w http.ResponseWriter, r *http.Request, scope accessScope, id string) {
+	u, ok := env.getUpload(w, r, scope, id)
+	if !ok {
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Upload-Offset", strconv.FormatInt(u.Offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(u.Length, 10))
+	w.Header().Set("Upload-Metadata", encodeTusMetadata(u))
+	setUploadExpires(w, u)
+	w.WriteHeader(http.StatusOK)
+}
+
+// patchUpload stores the body as the next chunk. Bytes received before the client
+// disconnects are kept, so it can resume from there.
+func (env *Env) patchUpload(w http.ResponseWriter, r *http.Request, scope accessScope, id string) {
+	if r.Header.Get("Content-Type") != tusChunkType {
+		http.Error(w, "Content-Type must be "+tusChunkType, http.StatusUnsupportedMediaType)
+		return
+	}
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		http.Error(w, "Invalid Upload-Offset", http.StatusBadRequest)
+		return
+	}
+	u, ok := env.getUpload(w, r, scope, id)
+	if !ok {
+		return
+	}
+	if offset != u.Offset {
+		http.Error(w, fmt.Sprintf("Upload-Offset %d does not match the upload's offset %d", offset, u.Offset), http.StatusConflict)
+		return
+	}
+	if u.Offset == u.Length {
+		// Every byte arrived but joining the chunks failed; a repeated PATCH tries again
+		if u.FileID == 0 {
+			if err := env.finishUpload(r.Context(), scope, u); err != nil {
+				env.writeFinishError(w, r.Context(), scope, u, err)
+				return
+			}
+		}
+		writeUploadOffset(w, u)
+		return
+	}
+
+	suffix, err := randomToken(6)
+	if err != nil {
+		Error("Failed to generate chunk key: %v", err)
+		http.Error(w, "Server error", http.StatusInternalServerError)
+		return
+	}
+	key := fmt.Sprintf("tus/%s/%d_%s", id, offset, suffix)
+
+	// Keep storing after the client is gone: what arrived is worth recording
+	ctx := context.WithoutCancel(r.Context())
+	body := &clientReader{r: http.MaxBytesReader(w, r.Body, u.Length-u.Offset)}
+	counter := &countingReader{r: body}
+	if err := env.Blobs.Put(ctx, key, counter, -1, "application/octet-stream"); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			Warn("Chunk of upload %s runs past its length of %d bytes", id, u.Length)
+			http.Error(w, "Chunk runs past Upload-Length", http.StatusRequestEntityTooLarge)
+			return
+		}
+		Error("Failed to store chunk %s: %v", key, err)
+		http.Error(w, "Failed to save chunk", http.StatusInternalServerError)
+		return
+	}
+	if counter.n == 0 {
+		env.deleteChunks(ctx, []string{key})
+		writeUploadOffset(w, u)
+		return
+	}
+
+	u, err = env.Uploads.AppendChunk(ctx, scope.Scope, id, offset, counter.n, key, time.Now().Add(uploadTTL))
+	if err != nil {
+		// Another request extended the upload first, or it was removed meanwhile
+		env.deleteChunks(ctx, []string{key})
+		writeStoreError(w, err, "Upload not found")
+		return
+	}
+	if body.err != nil {
+		Warn("Upload %s interrupted at offset %d: %v", id, u.Offset, body.err)
+		return
+	}
+	if u.Offset == u.Length {
+		if err := env.finishUpload(ctx, scope, u); err != nil {
+			env.writeFinishError(w, ctx, scope, u, err)
+			return
+		}
+	}
+	writeUploadOffset(w, u)
+}
+
+func (env *Env) deleteUpload(w http.ResponseWriter, r *http.Request, scope accessScope, id string) {
+	u, err := env.Uploads.Delete(r.Context(), scope.Scope, id)
+	if err != nil {
+		writeStoreError(w, err, "Upload not found")
+		return
+	}
+	env.deleteChunks(r.Context(), u.ChunkKeys)
+	Info("Resumable upload %s terminated", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getUpload loads an upload, answering 404 or 410 Gone if it is missing or expired
+func (env *Env) getUpload(w http.ResponseWriter, r *http.Request, scope accessScope, id string) (models.Upload, bool) {
+	u, err := env.Uploads.Get(r.Context(), scope.Scope, id)
+	if err != nil {
+		writeStoreError(w, err, "Upload not found")
+		return u, false
+	}
+	if expiresAt, _ := time.Parse(time.RFC3339, u.ExpiresAt); time.Now().After(expiresAt) {
+		http.Error(w, "Upload expired", http.StatusGone)
+		return u, false
+	}
+	return u, true
+}
+
+// finishUpload joins the chunks of a complete upload into a new file record, checking the
+// detected type and hashing them on the way as /upload does, then removes the chunks.
+// A disallowed type gives a *fileTypeError. The upload is claimed first, so two PATCHes
+// cannot both create a record; the second gets errUploadFinishing.
+func (env *Env) finishUpload(ctx context.Context, scope accessScope, u models.Upload) error {
+	err := env.Uploads.ClaimFinish(ctx, scope.Scope, u.ID, time.Now().Add(-finishClaimTTL))
+	if errors.Is(err, store.ErrConflict) {
+		return errUploadFinishing
+	}
+	if err != nil {
+		return err
+	}
+	record, err := env.joinUpload(ctx, scope, u)
+	if err != nil {
+		if err := env.Uploads.ReleaseFinish(ctx, scope.Scope, u.ID); err != nil && !errors.Is(err, store.ErrNotFound) {
+			Warn("Could not release upload %s for a retry: %v", u.ID, err)
+		}
+		return err
+	}
+	env.deleteChunks(ctx, u.ChunkKeys)
+	Info("Resumable upload %s complete: %s, %d bytes (Pet ID: %d)", u.ID, record.FileName, record.Size, u.PetID)
+	env.startScan(record)
+	env.startThumbnails(record)
+	return nil
+}
+
+// joinUpload stores the joined chunks of a claimed upload and creates its file record
+func (env *Env) joinUpload(ctx context.Context, scope accessScope, u models.Upload) (models.FileRecord, error) {
+	chunks := &chunkReader{ctx: ctx, blobs: env.Blobs, keys: u.ChunkKeys}
+	defer chunks.Close()
+	contentType, content, err := sniffUpload(chunks)
+	if err != nil {
+		return models.FileRecord{}, err
+	}
+	if err := env.checkUploadType(contentType); err != nil {
+		return models.FileRecord{}, err
+	}
+	key, err := newFileKey(u.PetID, contentType)
+	if err != nil {
+		return models.FileRecord{}, err
+	}
+	hash := sha256.New()
+	counter := &countingReader{r: io.TeeReader(content, hash)}
+	if err := env.Blobs.Put(ctx, key, counter, u.Length, contentType); err != nil {
+		return models.FileRecord{}, err
+	}
+
+	record := models.FileRecord{
+		PetID:       u.PetID,
+		FileName:    u.FileName,
+		FilePath:    key,
+		Size:        counter.n,
+		SHA256:      hex.EncodeToString(hash.Sum(nil)),
+		ContentType: contentType,
+		ScanStatus:  env.newFileScanStatus(),
+		DocumentID:  u.DocumentID,
+	}
+	if err := env.Files.Create(ctx, scope.Scope, &record); err != nil {
+		if err := env.Blobs.Delete(ctx, key); err != nil {
+			Warn("Could not delete orphaned file %s: %v", key, err)
+		}
+		return models.FileRecord{}, err
+	}
+	if err := env.Uploads.Complete(ctx, scope.Scope, u.ID, record.ID); err != nil {
+		// The upload was terminated meanwhile; its record must not outlive it
+		if _, err := env.Files.Delete(ctx, scope.Scope, record.ID); err != nil {
+			Warn("Could not delete orphaned file record %d: %v", record.ID, err)
+		}
+		if err := env.Blobs.Delete(ctx, key); err != nil {
+			Warn("Could not delete orphaned file %s: %v", key, err)
+		}
+		return models.FileRecord{}, err
+	}
+	return record, nil
+}
+
+// writeFinishError answers a failure to complete an upload. Retrying cannot change the type
+// of the contents, so an upload of a disallowed type is removed.
+func (env *Env) writeFinishError(w http.ResponseWriter, ctx context.Context, scope accessScope, u models.Upload, err error) {
+	if errors.Is(err, errUploadFinishing) {
+		w.Header().Set("Retry-After", "5")
+		http.Error(w, "Upload is already being completed", http.StatusConflict)
+		return
+	}
+	var typeErr *fileTypeError
+	if !errors.As(err, &typeErr) {
+		Error("Failed to complete upload %s: %v", u.ID, err)
+		http.Error(w, "Failed to save file", http.StatusInternalServerError)
+		return
+	}
+	Warn("Upload %s of %s rejected: %v", u.ID, u.FileName, err)
+	if removed, err := env.Uploads.Delete(ctx, scope.Scope, u.ID); err == nil {
+		env.deleteChunks(ctx, removed.ChunkKeys)
+	}
+	http.Error(w, fmt.Sprintf("File type %s is not allowed", typeErr.ContentType), http.StatusUnsupportedMediaType)
+}
+
+// deleteChunks removes stored chunks, logging the ones that could not be removed
+func (env *Env) deleteChunks(ctx context.Context, keys []string) {
+	for _, key := range keys {
+		if err := env.Blobs.Delete(ctx, key); err != nil && !errors.Is(err, blob.ErrNotFound) {
+			Warn("Could not delete upload chunk %s: %v", key, err)
+		}
+	}
+}
+
+func writeUploadOffset(w http.ResponseWriter, u models.Upload) {
+	w.Header().Set("Upload-Offset", strconv.FormatInt(u.Offset, 10))
+	setUploadExpires(w, u)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func setUploadExpires(w http.ResponseWriter, u models.Upload) {
+	if expiresAt, err := time.Parse(time.RFC3339, u.ExpiresAt); err == nil {
+		w.Header().Set("Upload-Expires", expiresAt.UTC().Format(http.TimeFormat))
+	}
+}
+
+// parseTusMetadata decodes Upload-Metadata: comma-separated keys, each followed by a space
+// and its base64 value unless it has none
+func parseTusMetadata(header string) (map[string]string, error) {
+	meta := map[string]string{}
+	if strings.TrimSpace(header) == "" {
+		return meta, nil
+	}
+	for _, pair := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			return nil, errors.New("empty metadata key")
+		}
+		decoded, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, err
+		}
+		meta[key] = string(decoded)
+	}
+	return meta, nil
+}
+
+// encodeTusMetadata returns the Upload-Metadata the upload was created with
+func encodeTusMetadata(u models.Upload) string {
+	pairs := []string{
+		"filename " + base64.StdEncoding.EncodeToString([]byte(u.FileName)),
+		"pet_id " + base64.StdEncoding.EncodeToString([]byte(strconv.Itoa(u.PetID))),
+	}
+	if u.ContentType != "" {
+		pairs = append(pairs, "filetype "+base64.StdEncoding.EncodeToString([]byte(u.ContentType)))
+	}
+	if u.DocumentID != 0 {
+		pairs = append(pairs, "document_id "+base64.StdEncoding.EncodeToString([]byte(strconv.Itoa(u.DocumentID))))
+	}
+	return strings.Join(pairs, ",")
+}
+
+// clientReader ends the request body at its first read error, such as a dropped
+// connection, so the bytes before it can still be stored. Exceeding the size limit
+// remains an error.
+type clientReader struct {
+	r   io.Reader
+	err error // the read error that ended the body early
+}
+
+func (c *clientReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	var tooLarge *http.MaxBytesError
+	if err != nil && err != io.EOF && !errors.As(err, &tooLarge) {
+		c.err = err
+		err = io.EOF
+	}
+	return n, err
+}
+
+// chunkReader reads the stored chunks of an upload one after another
+type chunkReader struct {
+	ctx   context.Context
+	blobs blob.Store
+	keys  []string
+	cur   io.ReadCloser
+}
+
+func (c *chunkReader) Read(p []byte) (int, error) {
+	for {
+		if c.cur == nil {
+			if len(c.keys) == 0 {
+				return 0, io.EOF
+			}
+			rc, _, err := c.blobs.Get(c.ctx, c.keys[0])
+			if err != nil {
+				return 0, fmt.Errorf("reading chunk %s: %w", c.keys[0], err)
+			}
+			c.cur, c.keys = rc, c.keys[1:]
+		}
+		n, err := c.cur.Read(p)
+		if err == io.EOF {
+			c.cur.Close()
+			c.cur = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+func (c *chunkReader) Close() error {
+	if c.cur != nil {
+		return c.cur.Close()
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+)
+
+// sniffLen is how much of a file content detection looks at. DICOM files are recognised by
+// the "DICM" marker after their 128-byte preamble; http.DetectContentType handles the rest.
+const sniffLen = 512
+
+// defaultAllowedUploadTypes are the detected types accepted when Env.AllowedUploadTypes is not set
+var defaultAllowedUploadTypes = []string{"application/pdf", "image/jpeg", "image/png", "application/dicom"}
+
+// fileExtensions gives stored files the extension of their detected type
+var fileExtensions = map[string]string{
+	"application/pdf":   ".pdf",
+	"image/jpeg":        ".jpg",
+	"image/png":         ".png",
+	"application/dicom": ".dcm",
+}
+
+// fileTypeError reports an upload whose detected type is not on the allow-list
+type fileTypeError struct {
+	ContentType string
+}
+
+func (e *fileTypeError) Error() string {
+	return fmt.Sprintf("file type %s is not allowed", e.ContentType)
+}
+
+// sniffUpload detects the type of r from its first bytes and returns a reader that
+// still yields all of r. The client's file name and Content-Type are never trusted.
+func sniffUpload(r io.Reader) (string, io.Reader, error) {
+	br := bufio.NewReaderSize(r, sniffLen)
+	head, err := br.Peek(sniffLen)
+	if err != nil && err != io.EOF {
+		return "", nil, err
+	}
+	return detectContentType(head), br, nil
+}
+
+// detectContentType returns the MIME type of content starting with head, without parameters
+func detectContentType(head []byte) string {
+	if len(head) >= 132 && string(head[128:132]) == "DICM" {
+		return "application/dicom"
+	}
+	t, _, err := mime.ParseMediaType(http.DetectContentType(head))
+	if err != nil {
+		return "application/octet-stream"
+	}
+	return t
+}
+
+// checkUploadType returns a *fileTypeError unless contentType is allowed
+func (env *Env) checkUploadType(contentType string) error {
+	allowed := env.AllowedUploadTypes
+	if len(allowed) == 0 {
+		allowed = defaultAllowedUploadTypes
+	}
+	for _, t := range allowed {
+		if t == contentType {
+			return nil
+		}
+	}
+	return &fileTypeError{ContentType: contentType}
+}
+
+// fileExtension is the extension stored files of the type get
+func fileExtension(contentType string) string {
+	if ext, ok := fileExtensions[contentType]; ok {
+		return ext
+	}
+	if exts, _ := mime.ExtensionsByType(contentType); len(exts) > 0 {
+		return exts[0]
+	}
+	return ""
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"pets_project/internal/models"
+	"pets_project/internal/store"
+)
+
+// --- File Version Functions (internal) ---
+
+// listVersions serves GET /files/{id}/versions: every version of the document, oldest first
+func (env *Env) listVersions(w http.ResponseWriter, r *http.Request, id int) {
+	scope, ok := env.requestScope(w, r)
+	if !ok {
+		return
+	}
+	versions, err := env.fileVersions(r.Context(), scope, id)
+	if err != nil {
+		writeStoreError(w, err, "File not found")
+		return
+	}
+	for i := range versions {
+		versions[i].ThumbnailURL = env.thumbnailURL(r, versions[i])
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(versions)
+}
+
+// fileVersions returns the versions of the document that file id belongs to. A document's
+// id is that of its first version, so it still finds the document once that version is deleted.
+func (env *Env) fileVersions(ctx context.Context, scope accessScope, id int) ([]models.FileRecord, error) {
+	f, err := env.Files.Get(ctx, scope.Scope, id)
+	if errors.Is(err, store.ErrNotFound) {
+		return env.Files.Versions(ctx, scope.Scope, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return env.Files.Versions(ctx, scope.Scope, f.DocumentID)
+}
+
+// documentPetID finds the pet whose document a new version is uploaded to. A pet_id sent
+// along must be that pet.
+func (env *Env) documentPetID(w http.ResponseWriter, r *http.Request, scope accessScope, documentID int, petIDStr string) (int, bool) {
+	versions, err := env.Files.Versions(r.Context(), scope.Scope, documentID)
+	if err != nil {
+		writeStoreError(w, err, "Document not found")
+		return 0, false
+	}
+	petID := versions[0].PetID
+	if petIDStr != "" && petIDStr != strconv.Itoa(petID) {
+		http.Error(w, fmt.Sprintf("Document %d belongs to another pet", documentID), http.StatusBadRequest)
+		return 0, false
+	}
+	return petID, true
+}
+
+// pickVersion returns the requested version ("" for the current one) of a document
+func pickVersion(versions []models.FileRecord, version string) (models.FileRecord, bool) {
+	if version == "" {
+		return versions[len(versions)-1], true
+	}
+	for _, v := range versions {
+		if strconv.Itoa(v.Version) == version {
+			return v, true
+		}
+	}
+	return models.FileRecord{}, false
+}
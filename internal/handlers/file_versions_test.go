@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"pets_project/internal/models"
+)
+
+func TestPickVersion(t *testing.T) {
+	versions := []models.FileRecord{
+		{ID: 10, DocumentID: 10, Version: 1},
+		{ID: 14, DocumentID: 10, Version: 2},
+		{ID: 21, DocumentID: 10, Version: 4}, // version 3 was deleted
+	}
+	tests := []struct {
+		version string
+		wantID  int
+		wantOK  bool
+	}{
+		{"", 21, true},
+		{"1", 10, true},
+		{"2", 14, true},
+		{"4", 21, true},
+		{"3", 0, false},
+		{"5", 0, false},
+		{"0", 0, false},
+		{"01", 0, false},
+		{"latest", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := pickVersion(versions, tt.version)
+		if ok != tt.wantOK || got.ID != tt.wantID {
+			t.Errorf("pickVersion(%q) = file %d, %v; want file %d, %v", tt.version, got.ID, ok, tt.wantID, tt.wantOK)
+		}
+	}
+}
+
+func TestDownloadVersions(t *testing.T) {
+	ts := newTestServer(t)
+	_, token := ts.login(t, 1, models.RoleVet)
+	pet := ts.newPet(t, 1, "Rex")
+	v1 := ts.newFile(t, 1, pet.ID, 0, "report.txt", "text/plain", []byte("first"))
+	v2 := ts.newFile(t, 1, pet.ID, v1.ID, "report.txt", "text/plain", []byte("second"))
+	v3 := ts.newFile(t, 1, pet.ID, v1.ID, "report.txt", "text/plain", []byte("third"))
+	if v3.Version != 3 || v3.DocumentID != v1.ID {
+		t.Fatalf("third upload is version %d of document %d", v3.Version, v3.DocumentID)
+	}
+
+	tests := []struct {
+		id       int
+		version  string
+		status   int
+		contents string
+	}{
+		{v1.ID, "", http.StatusOK, "third"}, // any version's ID names the document
+		{v3.ID, "", http.StatusOK, "third"},
+		{v3.ID, "1", http.StatusOK, "first"},
+		{v1.ID, "2", http.StatusOK, "second"},
+		{v1.ID, "4", http.StatusNotFound, ""},
+		{999, "", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		rec := ts.do(t, token, "GET", fmt.Sprintf("/download?id=%d&version=%s", tt.id, tt.version), nil)
+		expect(t, rec, tt.status, nil)
+		if tt.contents != "" && rec.Body.String() != tt.contents {
+			t.Errorf("download of %d version %q = %q, want %q", tt.id, tt.version, rec.Body.String(), tt.contents)
+		}
+	}
+
+	// Deleting the first version keeps the document reachable through its ID
+	expect(t, ts.do(t, token, "DELETE", fmt.Sprintf("/files/delete?id=%d", v1.ID), nil), http.StatusOK, nil)
+	var versions []models.FileRecord
+	expect(t, ts.do(t, token, "GET", fmt.Sprintf("/files/%d/versions", v1.ID), nil), http.StatusOK, &versions)
+	if len(versions) != 2 || versions[0].ID != v2.ID || versions[1].ID != v3.ID {
+		t.Errorf("versions after deleting the first = %+v, want files %d and %d", versions, v2.ID, v3.ID)
+	}
+	expect(t, ts.do(t, token, "GET", fmt.Sprintf("/download?id=%d&version=1", v1.ID), nil), http.StatusNotFound, nil)
+
+	// Other clinics see neither the document nor its versions
+	other := ts.newClinic(t, "Other Clinic")
+	_, otherToken := ts.login(t, other, models.RoleVet)
+	expect(t, ts.do(t, otherToken, "GET", fmt.Sprintf("/download?id=%d", v3.ID), nil), http.StatusNotFound, nil)
+	expect(t, ts.do(t, otherToken, "GET", fmt.Sprintf("/files/%d/versions", v1.ID), nil), http.StatusNotFound, nil)
+}
@@ -1,18 +1,43 @@
 package handlers
 
 import (
-	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"pets_project/internal/blob"
 	"pets_project/internal/models" // Import your models
+	"pets_project/internal/notify"
+	"pets_project/internal/scan"
+	"pets_project/internal/store"
 )
 
-// Env struct will hold dependencies like the database connection
-// This struct is shared by auth.go and handlers.go (since they are in the same package)
+// Env struct holds the dependencies shared by every handler.
+// Handlers only talk to the store interfaces, so they can be tested with store.NewMemory.
 type Env struct {
-	DB *sql.DB
+	store.Stores
+	Notifier notify.Notifier // sends waitlist offers; nil disables them
+	Blobs    blob.Store      // contents of uploaded files
+	Scanner  scan.Scanner    // checks uploaded files for malware; nil leaves them unscanned
+	// MaxUploadBytes limits the size of an upload request; 0 means defaultMaxUploadBytes
+	MaxUploadBytes int64
+	// AllowedUploadTypes lists the MIME types uploads may have; empty means defaultAllowedUploadTypes
+	AllowedUploadTypes []string
+	// LinkSecret signs download links; empty means a key derived from JWT_SECRET
+	LinkSecret []byte
+	// WaitlistOfferTTL is how long owners have to answer a waitlist offer; 0 means defaultWaitlistOfferTTL
+	WaitlistOfferTTL time.Duration
+	// PublicBaseURL (e.g. "https://vet.example.com") starts the feed, upload and download URLs
+	// the API hands out; empty means the request's Host header
+	PublicBaseURL string
+
+	jobsOnce sync.Once
+	jobs     chan struct{} // slots of the scans and thumbnails running at once; see acquireJob
 }
 
 // === Pet Handlers =================================================================
@@ -29,6 +54,20 @@ func (env *Env) PetsHandler(w http.ResponseWriter, r *http.Request) {
 		default:
 			http.Error(w, "Method not allowed for /pets", http.StatusMethodNotAllowed)
 		}
+	} else if idStr, rest, found := strings.Cut(strings.TrimPrefix(path, "/pets/"), "/"); strings.HasPrefix(path, "/pets/") && found {
+		// Sub-resources of a pet: /pets/{id}/medical[/...], /pets/{id}/vaccinations[/...]
+		petID, err := strconv.Atoi(idStr)
+		if err != nil {
+			http.Error(w, "Invalid ID in path", http.StatusBadRequest)
+			return
+		}
+		if sub, ok := subPath(rest, "medical"); ok {
+			env.PetMedicalHandler(w, r, petID, sub)
+		} else if sub, ok := subPath(rest, "vaccinations"); ok {
+			env.PetVaccinationsHandler(w, r, petID, sub)
+		} else {
+			http.Error(w, "Not found", http.StatusNotFound)
+		}
 	} else if strings.HasPrefix(path, "/pets/") {
 		id, err := getIDFromPath(w, r, "/pets/")
 		if err != nil {
@@ -50,28 +89,26 @@ func (env *Env) PetsHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // --- Pet CRUD Functions (internal) ---
+// getAllPets supports ?species=, ?breed=, ?owner_id= and sorting by name, species or breed
 func (env *Env) getAllPets(w http.ResponseWriter, r *http.Request) {
-	rows, err := env.DB.Query("SELECT id, name, species, breed, owner_id, medical_history FROM pets")
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	page, ok := parsePage(w, r)
+	if !ok {
 		return
 	}
-	defer rows.Close()
-	pets := []models.Pet{} // Use models.Pet
-	for rows.Next() {
-		var p models.Pet
-		if err := rows.Scan(&p.ID, &p.Name, &p.Species, &p.Breed, &p.OwnerID, &p.MedicalHistory); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		pets = append(pets, p)
+	filter := store.PetFilter{Species: r.URL.Query().Get("species"), Breed: r.URL.Query().Get("breed")}
+	if filter.OwnerID, ok = queryInt(w, r, "owner_id"); !ok {
+		return
 	}
-	if err = rows.Err(); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	scope, ok := env.requestScope(w, r)
+	if !ok {
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(pets)
+	pets, next, err := env.Pets.List(r.Context(), scope.Scope, filter, page)
+	if err != nil {
+		writeStoreError(w, err, "Pet not found")
+		return
+	}
+	writeList(w, pets, next)
 }
 
 func (env *Env) createPet(w http.ResponseWriter, r *http.Request) {
@@ -80,17 +117,24 @@ func (env *Env) createPet(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	scope, ok := env.requestScope(w, r)
+	if !ok {
+		return
+	}
+	// Pet owners can only register pets under their own owner record
+	if scope.OwnerOnly {
+		if scope.OwnerID == 0 {
+			http.Error(w, "Your account is not linked to an owner record", http.StatusForbidden)
+			return
+		}
+		p.OwnerID = scope.OwnerID
+	}
 	if p.Name == "" || p.OwnerID == 0 {
 		http.Error(w, "Name and owner_id are required fields", http.StatusBadRequest)
 		return
 	}
-	sqlStatement := `
-		INSERT INTO pets (name, species, breed, owner_id, medical_history)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id`
-	err := env.DB.QueryRow(sqlStatement, p.Name, p.Species, p.Breed, p.OwnerID, p.MedicalHistory).Scan(&p.ID)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := env.Pets.Create(r.Context(), scope.Scope, &p); err != nil {
+		writeStoreError(w, err, "Pet not found")
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -99,15 +143,13 @@ func (env *Env) createPet(w http.ResponseWriter, r *http.Request) {
 }
 
 func (env *Env) getPetByID(w http.ResponseWriter, r *http.Request, id int) {
-	var p models.Pet // Use models.Pet
-	sqlStatement := `SELECT id, name, species, breed, owner_id, medical_history FROM pets WHERE id = $1`
-	err := env.DB.QueryRow(sqlStatement, id).Scan(&p.ID, &p.Name, &p.Species, &p.Breed, &p.OwnerID, &p.MedicalHistory)
+	scope, ok := env.requestScope(w, r)
+	if !ok {
+		return
+	}
+	p, err := env.Pets.Get(r.Context(), scope.Scope, id)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			http.Error(w, "Pet not found", http.StatusNotFound)
-		} else {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
+		writeStoreError(w, err, "Pet not found")
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -120,40 +162,30 @@ func (env *Env) updatePet(w http.ResponseWriter, r *http.Request, id int) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	sqlStatement := `
-		UPDATE pets
-		SET name = $1, species = $2, breed = $3, owner_id = $4, medical_history = $5
-		WHERE id = $6
-		RETURNING id`
-	var updatedID int
-	err := env.DB.QueryRow(sqlStatement, p.Name, p.Species, p.Breed, p.OwnerID, p.MedicalHistory, id).Scan(&updatedID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			http.Error(w, "Pet not found", http.StatusNotFound)
-		} else {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
+	scope, ok := env.requestScope(w, r)
+	if !ok {
+		return
+	}
+	// Pet owners cannot hand their pets over to another owner
+	if scope.OwnerOnly {
+		p.OwnerID = scope.OwnerID
+	}
+	p.ID = id
+	if err := env.Pets.Update(r.Context(), scope.Scope, &p); err != nil {
+		writeStoreError(w, err, "Pet not found")
 		return
 	}
-	p.ID = updatedID
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(p)
 }
 
 func (env *Env) deletePet(w http.ResponseWriter, r *http.Request, id int) {
-	sqlStatement := `DELETE FROM pets WHERE id = $1`
-	res, err := env.DB.Exec(sqlStatement, id)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-	count, err := res.RowsAffected()
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	scope, ok := env.requestScope(w, r)
+	if !ok {
 		return
 	}
-	if count == 0 {
-		http.Error(w, "Pet not found", http.StatusNotFound)
+	if err := env.Pets.Delete(r.Context(), scope.Scope, id); err != nil {
+		writeStoreError(w, err, "Pet not found")
 		return
 	}
 	w.WriteHeader(http.StatusOK)
@@ -194,28 +226,23 @@ func (env *Env) OwnersHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // --- Owner CRUD Functions (internal) ---
+// getAllOwners supports ?name= (substring), ?email= and sorting by name or email
 func (env *Env) getAllOwners(w http.ResponseWriter, r *http.Request) {
-	rows, err := env.DB.Query("SELECT id, name, contact, email FROM owners")
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	page, ok := parsePage(w, r)
+	if !ok {
 		return
 	}
-	defer rows.Close()
-	owners := []models.Owner{} // Use models.Owner
-	for rows.Next() {
-		var o models.Owner
-		if err := rows.Scan(&o.ID, &o.Name, &o.Contact, &o.Email); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		owners = append(owners, o)
+	filter := store.OwnerFilter{Name: r.URL.Query().Get("name"), Email: r.URL.Query().Get("email")}
+	scope, ok := env.requestScope(w, r)
+	if !ok {
+		return
 	}
-	if err = rows.Err(); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	owners, next, err := env.Owners.List(r.Context(), scope.Scope, filter, page)
+	if err != nil {
+		writeStoreError(w, err, "Owner not found")
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(owners)
+	writeList(w, owners, next)
 }
 
 func (env *Env) createOwner(w http.ResponseWriter, r *http.Request) {
@@ -228,13 +255,12 @@ func (env *Env) createOwner(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Name and email are required fields", http.StatusBadRequest)
 		return
 	}
-	sqlStatement := `
-		INSERT INTO owners (name, contact, email)
-		VALUES ($1, $2, $3)
-		RETURNING id`
-	err := env.DB.QueryRow(sqlStatement, o.Name, o.Contact, o.Email).Scan(&o.ID)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	scope, ok := env.requestScope(w, r)
+	if !ok {
+		return
+	}
+	if err := env.Owners.Create(r.Context(), scope.Scope, &o); err != nil {
+		writeStoreError(w, err, "Owner not found")
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -243,15 +269,13 @@ func (env *Env) createOwner(w http.ResponseWriter, r *http.Request) {
 }
 
 func (env *Env) getOwnerByID(w http.ResponseWriter, r *http.Request, id int) {
-	var o models.Owner // Use models.Owner
-	sqlStatement := `SELECT id, name, contact, email FROM owners WHERE id = $1`
-	err := env.DB.QueryRow(sqlStatement, id).Scan(&o.ID, &o.Name, &o.Contact, &o.Email)
+	scope, ok := env.requestScope(w, r)
+	if !ok {
+		return
+	}
+	o, err := env.Owners.Get(r.Context(), scope.Scope, id)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			http.Error(w, "Owner not found", http.StatusNotFound)
-		} else {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
+		writeStoreError(w, err, "Owner not found")
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -264,40 +288,26 @@ func (env *Env) updateOwner(w http.ResponseWriter, r *http.Request, id int) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	sqlStatement := `
-		UPDATE owners
-		SET name = $1, contact = $2, email = $3
-		WHERE id = $4
-		RETURNING id`
-	var updatedID int
-	err := env.DB.QueryRow(sqlStatement, o.Name, o.Contact, o.Email, id).Scan(&updatedID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			http.Error(w, "Owner not found", http.StatusNotFound)
-		} else {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
+	scope, ok := env.requestScope(w, r)
+	if !ok {
+		return
+	}
+	o.ID = id
+	if err := env.Owners.Update(r.Context(), scope.Scope, &o); err != nil {
+		writeStoreError(w, err, "Owner not found")
 		return
 	}
-	o.ID = updatedID
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(o)
 }
 
 func (env *Env) deleteOwner(w http.ResponseWriter, r *http.Request, id int) {
-	sqlStatement := `DELETE FROM owners WHERE id = $1`
-	res, err := env.DB.Exec(sqlStatement, id)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-	count, err := res.RowsAffected()
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	scope, ok := env.requestScope(w, r)
+	if !ok {
 		return
 	}
-	if count == 0 {
-		http.Error(w, "Owner not found", http.StatusNotFound)
+	if err := env.Owners.Delete(r.Context(), scope.Scope, id); err != nil {
+		writeStoreError(w, err, "Owner not found")
 		return
 	}
 	w.WriteHeader(http.StatusOK)
@@ -305,6 +315,12 @@ func (env *Env) deleteOwner(w http.ResponseWriter, r *http.Request, id int) {
 }
 
 // === Appointment Handlers =========================================================
+// Appointment lengths in minutes
+const (
+	defaultAppointmentMinutes = 30
+	maxAppointmentMinutes     = 480
+)
+
 // AppointmentsHandler (capitalized) is the mini-router. Exported to main.go.
 func (env *Env) AppointmentsHandler(w http.ResponseWriter, r *http.Request) {
 	path := r.URL.Path
@@ -317,9 +333,21 @@ func (env *Env) AppointmentsHandler(w http.ResponseWriter, r *http.Request) {
 		default:
 			http.Error(w, "Method not allowed for /appointments", http.StatusMethodNotAllowed)
 		}
+	} else if path == "/appointments/availability" {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed for /appointments/availability", http.StatusMethodNotAllowed)
+			return
+		}
+		env.getAvailability(w, r)
 	} else if strings.HasPrefix(path, "/appointments/") {
-		id, err := getIDFromPath(w, r, "/appointments/")
+		idStr, action, _ := strings.Cut(strings.TrimPrefix(path, "/appointments/"), "/")
+		id, err := strconv.Atoi(idStr)
 		if err != nil {
+			http.Error(w, "Invalid ID in path", http.StatusBadRequest)
+			return
+		}
+		if action != "" {
+			env.appointmentActionHandler(w, r, id, action)
 			return
 		}
 		switch r.Method {
@@ -338,47 +366,116 @@ func (env *Env) AppointmentsHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // --- Appointment CRUD Functions (internal) ---
+// getAllAppointments supports ?pet_id=, ?vet_id=, ?owner_id=, ?series_id=, ?status= (comma separated),
+// a ?from=/?to= range and sorting by starts_at
 func (env *Env) getAllAppointments(w http.ResponseWriter, r *http.Request) {
-	rows, err := env.DB.Query("SELECT id, pet_id, appointment_date, appointment_time, reason FROM appointments")
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	page, ok := parsePage(w, r)
+	if !ok {
 		return
 	}
-	defer rows.Close()
-	appointments := []models.Appointment{} // Use models.Appointment
-	for rows.Next() {
-		var a models.Appointment
-		if err := rows.Scan(&a.ID, &a.PetID, &a.AppointmentDate, &a.AppointmentTime, &a.Reason); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		appointments = append(appointments, a)
+	var filter store.AppointmentFilter
+	if filter.PetID, ok = queryInt(w, r, "pet_id"); !ok {
+		return
 	}
-	if err = rows.Err(); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if filter.VetID, ok = queryInt(w, r, "vet_id"); !ok {
+		return
+	}
+	if filter.OwnerID, ok = queryInt(w, r, "owner_id"); !ok {
+		return
+	}
+	if filter.SeriesID, ok = queryInt(w, r, "series_id"); !ok {
+		return
+	}
+	if filter.Statuses, ok = queryStatuses(w, r); !ok {
+		return
+	}
+	if filter.From, ok = queryTime(w, r, "from", false); !ok {
+		return
+	}
+	if filter.To, ok = queryTime(w, r, "to", true); !ok {
+		return
+	}
+	scope, ok := env.requestScope(w, r)
+	if !ok {
+		return
+	}
+	appointments, next, err := env.Appointments.List(r.Context(), scope.Scope, filter, page)
+	if err != nil {
+		writeStoreError(w, err, "Appointment not found")
 		return
 	}
+	writeList(w, appointments, next)
+}
+
+// validateAppointment checks the booking fields and applies the default duration
+func validateAppointment(w http.ResponseWriter, a *models.Appointment) bool {
+	if a.PetID == 0 || a.StartsAt == "" {
+		http.Error(w, "pet_id and starts_at are required", http.StatusBadRequest)
+		return false
+	}
+	if _, err := time.Parse(time.RFC3339, a.StartsAt); err != nil {
+		http.Error(w, "starts_at must be an RFC 3339 time with a zone, e.g. 2025-03-01T09:30:00+01:00", http.StatusBadRequest)
+		return false
+	}
+	if a.DurationMinutes == 0 {
+		a.DurationMinutes = defaultAppointmentMinutes
+	}
+	if a.DurationMinutes < 5 || a.DurationMinutes > maxAppointmentMinutes {
+		http.Error(w, fmt.Sprintf("duration_minutes must be between 5 and %d", maxAppointmentMinutes), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// writeBookingError answers a clashing booking with 409 and the appointment it overlaps.
+// Pet owners only see the time slot of appointments that belong to someone else.
+func (env *Env) writeBookingError(w http.ResponseWriter, r *http.Request, scope accessScope, err error) {
+	var conflict *store.ConflictError
+	if !errors.As(err, &conflict) {
+		writeStoreError(w, err, "Appointment not found")
+		return
+	}
+	clash := conflict.Appointment
+	if scope.OwnerOnly {
+		if _, err := env.Pets.Get(r.Context(), scope.Scope, clash.PetID); err != nil {
+			clash = models.Appointment{StartsAt: clash.StartsAt, DurationMinutes: clash.DurationMinutes, VetID: clash.VetID}
+		}
+	}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(appointments)
+	w.WriteHeader(http.StatusConflict)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":                   fmt.Sprintf("The %s is already booked at that time", conflict.With),
+		"conflicting_appointment": clash,
+	})
 }
 
+// createAppointment books one appointment, or a whole series when the body has an "rrule"
 func (env *Env) createAppointment(w http.ResponseWriter, r *http.Request) {
-	var a models.Appointment // Use models.Appointment
-	if err := json.NewDecoder(r.Body).Decode(&a); err != nil {
+	var body struct {
+		models.Appointment
+		RRule string `json:"rrule"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	if a.PetID == 0 || a.AppointmentDate == "" || a.AppointmentTime == "" {
-		http.Error(w, "pet_id, appointment_date, and appointment_time are required", http.StatusBadRequest)
+	a := body.Appointment
+	if !validateAppointment(w, &a) {
 		return
 	}
-	sqlStatement := `
-		INSERT INTO appointments (pet_id, appointment_date, appointment_time, reason)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id`
-	err := env.DB.QueryRow(sqlStatement, a.PetID, a.AppointmentDate, a.AppointmentTime, a.Reason).Scan(&a.ID)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	scope, ok := env.requestScope(w, r)
+	if !ok {
+		return
+	}
+	if !env.checkPetInScope(w, r, scope, a.PetID) {
+		return
+	}
+	if body.RRule != "" {
+		env.createAppointmentSeries(w, r, scope, a, body.RRule)
+		return
+	}
+	if err := env.Appointments.Create(r.Context(), scope.Scope, &a); err != nil {
+		env.writeBookingError(w, r, scope, err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -387,63 +484,94 @@ func (env *Env) createAppointment(w http.ResponseWriter, r *http.Request) {
 }
 
 func (env *Env) getAppointmentByID(w http.ResponseWriter, r *http.Request, id int) {
-	var a models.Appointment // Use models.Appointment
-	sqlStatement := `SELECT id, pet_id, appointment_date, appointment_time, reason FROM appointments WHERE id = $1`
-	err := env.DB.QueryRow(sqlStatement, id).Scan(&a.ID, &a.PetID, &a.AppointmentDate, &a.AppointmentTime, &a.Reason)
+	scope, ok := env.requestScope(w, r)
+	if !ok {
+		return
+	}
+	a, err := env.Appointments.Get(r.Context(), scope.Scope, id)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			http.Error(w, "Appointment not found", http.StatusNotFound)
-		} else {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
+		writeStoreError(w, err, "Appointment not found")
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(a)
 }
 
+// updateAppointment changes one appointment, or with ?scope=following also the later
+// occurrences of its series
 func (env *Env) updateAppointment(w http.ResponseWriter, r *http.Request, id int) {
+	following, ok := occurrenceScope(w, r)
+	if !ok {
+		return
+	}
 	var a models.Appointment // Use models.Appointment
 	if err := json.NewDecoder(r.Body).Decode(&a); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	sqlStatement := `
-		UPDATE appointments
-		SET pet_id = $1, appointment_date = $2, appointment_time = $3, reason = $4
-		WHERE id = $5
-		RETURNING id`
-	var updatedID int
-	err := env.DB.QueryRow(sqlStatement, a.PetID, a.AppointmentDate, a.AppointmentTime, a.Reason, id).Scan(&updatedID)
+	if !validateAppointment(w, &a) {
+		return
+	}
+	scope, ok := env.requestScope(w, r)
+	if !ok {
+		return
+	}
+	// The appointment may only be moved to another pet the caller can see
+	if !env.checkPetInScope(w, r, scope, a.PetID) {
+		return
+	}
+	if following {
+		env.updateFollowingAppointments(w, r, scope, id, a)
+		return
+	}
+	current, err := env.Appointments.Get(r.Context(), scope.Scope, id)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			http.Error(w, "Appointment not found", http.StatusNotFound)
-		} else {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
+		writeStoreError(w, err, "Appointment not found")
+		return
+	}
+	if current.Status != models.AppointmentScheduled {
+		http.Error(w, "Only scheduled appointments can be changed", http.StatusConflict)
+		return
+	}
+	a.ID = id
+	if err := env.Appointments.Update(r.Context(), scope.Scope, &a); err != nil {
+		env.writeBookingError(w, r, scope, err)
 		return
 	}
-	a.ID = updatedID
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(a)
 }
 
+// deleteAppointment deletes one appointment, or with ?scope=following also the later
+// occurrences of its series. Only scheduled appointments can be deleted: the others keep
+// their status history, and are cancelled through POST /appointments/{id}/cancel instead.
 func (env *Env) deleteAppointment(w http.ResponseWriter, r *http.Request, id int) {
-	sqlStatement := `DELETE FROM appointments WHERE id = $1`
-	res, err := env.DB.Exec(sqlStatement, id)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	following, ok := occurrenceScope(w, r)
+	if !ok {
+		return
+	}
+	scope, ok := env.requestScope(w, r)
+	if !ok {
 		return
 	}
-	count, err := res.RowsAffected()
+	if following {
+		env.deleteFollowingAppointments(w, r, scope, id)
+		return
+	}
+	a, err := env.Appointments.Get(r.Context(), scope.Scope, id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeStoreError(w, err, "Appointment not found")
+		return
+	}
+	if a.Status != models.AppointmentScheduled {
+		http.Error(w, "Only scheduled appointments can be deleted", http.StatusConflict)
 		return
 	}
-	if count == 0 {
-		http.Error(w, "Appointment not found", http.StatusNotFound)
+	if err := env.Appointments.Delete(r.Context(), scope.Scope, id); err != nil {
+		writeStoreError(w, err, "Appointment not found")
 		return
 	}
+	env.offerFreedSlots(r.Context(), scope.ClinicID, []models.Appointment{a})
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"message": "Appointment deleted successfully"})
 }
@@ -0,0 +1,336 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"pets_project/internal/blob"
+	"pets_project/internal/models"
+	"pets_project/internal/store"
+)
+
+func TestMain(m *testing.M) {
+	os.Setenv("JWT_SECRET", "test-secret")
+	os.Exit(m.Run())
+}
+
+// testServer routes requests like main.go does, on top of the in-memory stores
+type testServer struct {
+	env     *Env
+	handler http.Handler
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	env := &Env{Stores: store.NewMemory(), Blobs: blob.Local{Dir: t.TempDir()}}
+
+	api := http.NewServeMux()
+	api.HandleFunc("/pets", env.PetsHandler)
+	api.HandleFunc("/pets/", env.PetsHandler)
+	api.HandleFunc("/owners", env.OwnersHandler)
+	api.HandleFunc("/owners/", env.OwnersHandler)
+	api.HandleFunc("/appointments", env.AppointmentsHandler)
+	api.HandleFunc("/appointments/", env.AppointmentsHandler)
+	api.HandleFunc("/waitlist", env.WaitlistHandler)
+	api.HandleFunc("/waitlist/", env.WaitlistHandler)
+	api.HandleFunc("/download", env.DownloadFileHandler)
+	api.HandleFunc("/files", env.ListFilesHandler)
+	api.HandleFunc("/files/delete", env.DeleteFileHandler)
+	api.HandleFunc("/files/", env.FilesHandler)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/shared/files/", env.SharedDownloadHandler)
+	mux.Handle("/", env.JwtAuthMiddleware(env.AuthorizeMiddleware(api)))
+	return &testServer{env: env, handler: mux}
+}
+
+// newClinic adds a clinic besides the default one (ID 1)
+func (ts *testServer) newClinic(t *testing.T, name string) int {
+	t.Helper()
+	c := models.Clinic{Name: name}
+	if err := ts.env.Clinics.Create(context.Background(), &c); err != nil {
+		t.Fatalf("create clinic: %v", err)
+	}
+	return c.ID
+}
+
+// login creates a user with the role in the clinic and returns it with an access token.
+// Users are stored directly so the tests do not pay for bcrypt.
+func (ts *testServer) login(t *testing.T, clinicID int, role string) (models.User, string) {
+	t.Helper()
+	ctx := context.Background()
+	u := models.User{Email: fmt.Sprintf("%s-%d-%s@example.com", t.Name(), clinicID, randomSuffix(t)), Role: role, ClinicID: clinicID}
+	if err := ts.env.Users.Create(ctx, &u); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	sessionID, _, err := ts.env.createSession(ctx, u.ID)
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	token, err := generateJWT(u, sessionID)
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+	return u, token
+}
+
+// loginOwner creates an owner account linked to a new owner record in the clinic
+func (ts *testServer) loginOwner(t *testing.T, clinicID int, name string) (models.Owner, string) {
+	t.Helper()
+	u, token := ts.login(t, clinicID, models.RoleOwner)
+	o := models.Owner{Name: name, Email: u.Email, UserID: u.ID}
+	if err := ts.env.Owners.Create(context.Background(), store.Scope{ClinicID: clinicID}, &o); err != nil {
+		t.Fatalf("create owner: %v", err)
+	}
+	return o, token
+}
+
+// newPet stores a pet, and an owner for it, in the clinic
+func (ts *testServer) newPet(t *testing.T, clinicID int, name string) models.Pet {
+	t.Helper()
+	ctx := context.Background()
+	scope := store.Scope{ClinicID: clinicID}
+	o := models.Owner{Name: name + "'s owner", Email: name + "@example.com"}
+	if err := ts.env.Owners.Create(ctx, scope, &o); err != nil {
+		t.Fatalf("create owner: %v", err)
+	}
+	p := models.Pet{Name: name, OwnerID: o.ID}
+	if err := ts.env.Pets.Create(ctx, scope, &p); err != nil {
+		t.Fatalf("create pet: %v", err)
+	}
+	return p
+}
+
+// newFile stores the contents as a version of the pet's document; documentID 0 starts a new document
+func (ts *testServer) newFile(t *testing.T, clinicID, petID, documentID int, name, contentType string, data []byte) models.FileRecord {
+	t.Helper()
+	ctx := context.Background()
+	f := models.FileRecord{
+		PetID:       petID,
+		DocumentID:  documentID,
+		FileName:    name,
+		FilePath:    fmt.Sprintf("pets/%d/%s", petID, randomSuffix(t)),
+		Size:        int64(len(data)),
+		ContentType: contentType,
+	}
+	if err := ts.env.Blobs.Put(ctx, f.FilePath, bytes.NewReader(data), f.Size, contentType); err != nil {
+		t.Fatalf("store contents: %v", err)
+	}
+	if err := ts.env.Files.Create(ctx, store.Scope{ClinicID: clinicID}, &f); err != nil {
+		t.Fatalf("create file record: %v", err)
+	}
+	return f
+}
+
+func randomSuffix(t *testing.T) string {
+	t.Helper()
+	s, err := randomToken(4)
+	if err != nil {
+		t.Fatalf("random token: %v", err)
+	}
+	return s
+}
+
+// do sends a request with the token (if any) and the JSON-encoded body (if any)
+func (ts *testServer) do(t *testing.T, token, method, path string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("encode body: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	ts.handler.ServeHTTP(rec, req)
+	return rec
+}
+
+// expect fails the test unless the response has the status, then decodes the body into v (if any)
+func expect(t *testing.T, rec *httptest.ResponseRecorder, status int, v interface{}) {
+	t.Helper()
+	if rec.Code != status {
+		t.Fatalf("status = %d, want %d (body %q)", rec.Code, status, rec.Body.String())
+	}
+	if v != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+			t.Fatalf("decode %q: %v", rec.Body.String(), err)
+		}
+	}
+}
+
+// petList is the shape of a GET /pets response
+type petList struct {
+	Data       []models.Pet `json:"data"`
+	NextCursor string       `json:"next_cursor"`
+}
+
+func TestPetAndOwnerCRUD(t *testing.T) {
+	ts := newTestServer(t)
+	_, token := ts.login(t, 1, models.RoleReceptionist)
+
+	var owner models.Owner
+	expect(t, ts.do(t, token, "POST", "/owners", models.Owner{Name: "Ann", Email: "ann@example.com"}), http.StatusCreated, &owner)
+	if owner.ID == 0 {
+		t.Fatal("created owner has no ID")
+	}
+
+	var pet models.Pet
+	expect(t, ts.do(t, token, "POST", "/pets", models.Pet{Name: "Rex", Species: "dog", OwnerID: owner.ID}), http.StatusCreated, &pet)
+
+	var got models.Pet
+	expect(t, ts.do(t, token, "GET", fmt.Sprintf("/pets/%d", pet.ID), nil), http.StatusOK, &got)
+	if got != pet {
+		t.Errorf("GET pet = %+v, want %+v", got, pet)
+	}
+
+	pet.Breed = "collie"
+	expect(t, ts.do(t, token, "PUT", fmt.Sprintf("/pets/%d", pet.ID), pet), http.StatusOK, nil)
+	expect(t, ts.do(t, token, "GET", fmt.Sprintf("/pets/%d", pet.ID), nil), http.StatusOK, &got)
+	if got.Breed != "collie" {
+		t.Errorf("breed after update = %q, want collie", got.Breed)
+	}
+
+	var list petList
+	expect(t, ts.do(t, token, "GET", "/pets?species=dog", nil), http.StatusOK, &list)
+	if len(list.Data) != 1 || list.Data[0].ID != pet.ID {
+		t.Errorf("GET /pets = %+v, want only pet %d", list.Data, pet.ID)
+	}
+
+	owner.Contact = "555-0100"
+	var updated models.Owner
+	expect(t, ts.do(t, token, "PUT", fmt.Sprintf("/owners/%d", owner.ID), owner), http.StatusOK, &updated)
+	if updated.Contact != "555-0100" {
+		t.Errorf("contact after update = %q", updated.Contact)
+	}
+
+	// Only admins may delete pets
+	expect(t, ts.do(t, token, "DELETE", fmt.Sprintf("/pets/%d", pet.ID), nil), http.StatusForbidden, nil)
+	_, vetToken := ts.login(t, 1, models.RoleVet)
+	expect(t, ts.do(t, vetToken, "DELETE", fmt.Sprintf("/pets/%d", pet.ID), nil), http.StatusForbidden, nil)
+	_, adminToken := ts.login(t, 1, models.RoleAdmin)
+	expect(t, ts.do(t, adminToken, "DELETE", fmt.Sprintf("/pets/%d", pet.ID), nil), http.StatusOK, nil)
+	expect(t, ts.do(t, token, "GET", fmt.Sprintf("/pets/%d", pet.ID), nil), http.StatusNotFound, nil)
+
+	expect(t, ts.do(t, adminToken, "DELETE", fmt.Sprintf("/owners/%d", owner.ID), nil), http.StatusOK, nil)
+	expect(t, ts.do(t, token, "GET", fmt.Sprintf("/owners/%d", owner.ID), nil), http.StatusNotFound, nil)
+}
+
+func TestClinicIsolation(t *testing.T) {
+	ts := newTestServer(t)
+	other := ts.newClinic(t, "Other Clinic")
+	_, token := ts.login(t, 1, models.RoleReceptionist)
+	_, otherToken := ts.login(t, other, models.RoleAdmin)
+
+	var owner models.Owner
+	expect(t, ts.do(t, token, "POST", "/owners", models.Owner{Name: "Ann", Email: "ann@example.com"}), http.StatusCreated, &owner)
+	var pet models.Pet
+	expect(t, ts.do(t, token, "POST", "/pets", models.Pet{Name: "Rex", OwnerID: owner.ID}), http.StatusCreated, &pet)
+
+	var otherOwner models.Owner
+	expect(t, ts.do(t, otherToken, "POST", "/owners", models.Owner{Name: "Eve", Email: "eve@example.com"}), http.StatusCreated, &otherOwner)
+
+	petPath := fmt.Sprintf("/pets/%d", pet.ID)
+	expect(t, ts.do(t, otherToken, "GET", petPath, nil), http.StatusNotFound, nil)
+	expect(t, ts.do(t, otherToken, "PUT", petPath, models.Pet{Name: "Stolen", OwnerID: otherOwner.ID}), http.StatusNotFound, nil)
+	expect(t, ts.do(t, otherToken, "DELETE", petPath, nil), http.StatusNotFound, nil)
+	expect(t, ts.do(t, otherToken, "GET", fmt.Sprintf("/owners/%d", owner.ID), nil), http.StatusNotFound, nil)
+	expect(t, ts.do(t, otherToken, "DELETE", fmt.Sprintf("/owners/%d", owner.ID), nil), http.StatusNotFound, nil)
+
+	// Pets cannot be registered under another clinic's owner
+	expect(t, ts.do(t, otherToken, "POST", "/pets", models.Pet{Name: "Rex", OwnerID: owner.ID}), http.StatusBadRequest, nil)
+
+	var list petList
+	expect(t, ts.do(t, otherToken, "GET", "/pets", nil), http.StatusOK, &list)
+	if len(list.Data) != 0 {
+		t.Errorf("other clinic lists %+v, want no pets", list.Data)
+	}
+
+	expect(t, ts.do(t, token, "GET", petPath, nil), http.StatusOK, &pet)
+	if pet.Name != "Rex" {
+		t.Errorf("pet name = %q after the other clinic's requests", pet.Name)
+	}
+}
+
+func TestOwnerIsolation(t *testing.T) {
+	ts := newTestServer(t)
+	ann, annToken := ts.loginOwner(t, 1, "Ann")
+	bob, bobToken := ts.loginOwner(t, 1, "Bob")
+
+	// Owners register pets under their own record whatever owner_id they send
+	var annPet models.Pet
+	expect(t, ts.do(t, annToken, "POST", "/pets", models.Pet{Name: "Rex", OwnerID: bob.ID}), http.StatusCreated, &annPet)
+	if annPet.OwnerID != ann.ID {
+		t.Errorf("pet owner = %d, want %d", annPet.OwnerID, ann.ID)
+	}
+	var bobPet models.Pet
+	expect(t, ts.do(t, bobToken, "POST", "/pets", models.Pet{Name: "Tom"}), http.StatusCreated, &bobPet)
+
+	var list petList
+	expect(t, ts.do(t, annToken, "GET", "/pets", nil), http.StatusOK, &list)
+	if len(list.Data) != 1 || list.Data[0].ID != annPet.ID {
+		t.Errorf("Ann lists %+v, want only pet %d", list.Data, annPet.ID)
+	}
+
+	bobPath := fmt.Sprintf("/pets/%d", bobPet.ID)
+	expect(t, ts.do(t, annToken, "GET", bobPath, nil), http.StatusNotFound, nil)
+	expect(t, ts.do(t, annToken, "PUT", bobPath, models.Pet{Name: "Mine"}), http.StatusNotFound, nil)
+	expect(t, ts.do(t, annToken, "GET", fmt.Sprintf("/owners/%d", bob.ID), nil), http.StatusNotFound, nil)
+	expect(t, ts.do(t, annToken, "GET", fmt.Sprintf("/owners/%d", ann.ID), nil), http.StatusOK, nil)
+
+	// Owners may not manage owner records or delete pets at all
+	expect(t, ts.do(t, annToken, "POST", "/owners", models.Owner{Name: "Eve", Email: "eve@example.com"}), http.StatusForbidden, nil)
+	expect(t, ts.do(t, annToken, "DELETE", fmt.Sprintf("/pets/%d", annPet.ID), nil), http.StatusForbidden, nil)
+
+	// Staff of the clinic see both owners' pets
+	_, vetToken := ts.login(t, 1, models.RoleVet)
+	expect(t, ts.do(t, vetToken, "GET", "/pets", nil), http.StatusOK, &list)
+	if len(list.Data) != 2 {
+		t.Errorf("vet lists %d pets, want 2", len(list.Data))
+	}
+}
+
+func TestStoreErrorMapping(t *testing.T) {
+	ts := newTestServer(t)
+	user, token := ts.login(t, 1, models.RoleReceptionist)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   interface{}
+		status int
+	}{
+		{"missing pet", "GET", "/pets/999", nil, http.StatusNotFound},
+		{"update missing owner", "PUT", "/owners/999", models.Owner{Name: "Ann"}, http.StatusNotFound},
+		{"pet of unknown owner", "POST", "/pets", models.Pet{Name: "Rex", OwnerID: 999}, http.StatusBadRequest},
+		{"invalid cursor", "GET", "/pets?cursor=garbage", nil, http.StatusBadRequest},
+		{"invalid sort", "GET", "/pets?sort=weight", nil, http.StatusBadRequest},
+		{"invalid ID", "GET", "/pets/abc", nil, http.StatusBadRequest},
+		{"link owner account", "POST", "/owners", models.Owner{Name: "Ann", Email: "ann@example.com", UserID: user.ID}, http.StatusCreated},
+		{"owner account linked twice", "POST", "/owners", models.Owner{Name: "Eve", Email: "eve@example.com", UserID: user.ID}, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expect(t, ts.do(t, token, tt.method, tt.path, tt.body), tt.status, nil)
+		})
+	}
+
+	rec := ts.do(t, token, "GET", "/pets/999", nil)
+	if body := rec.Body.String(); body != "Pet not found\n" {
+		t.Errorf("404 body = %q, want %q", body, "Pet not found\n")
+	}
+	expect(t, ts.do(t, "", "GET", "/pets", nil), http.StatusUnauthorized, nil)
+}
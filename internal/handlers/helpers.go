@@ -1,10 +1,13 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
+
+	"pets_project/internal/store"
 )
 
 // getIDFromPath gets the ID from a URL path like "/pets/1"
@@ -26,3 +29,77 @@ func getIDFromPath(w http.ResponseWriter, r *http.Request, basePath string) (int
 	}
 	return id, nil
 }
+
+// subPath matches rest (the path after "/pets/{id}/") against a sub-resource name
+// and returns what follows it, e.g. "" for "medical" or "/3" for "medical/3"
+func subPath(rest, name string) (string, bool) {
+	if rest != name && !strings.HasPrefix(rest, name+"/") {
+		return "", false
+	}
+	return strings.TrimPrefix(rest, name), true
+}
+
+// writeStoreError maps store errors to HTTP responses.
+// notFoundMsg is used for store.ErrNotFound, e.g. "Pet not found".
+func writeStoreError(w http.ResponseWriter, err error, notFoundMsg string) {
+	var refErr *store.ReferenceError
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		http.Error(w, notFoundMsg, http.StatusNotFound)
+	case errors.As(err, &refErr):
+		http.Error(w, refErr.Error(), http.StatusBadRequest)
+	case errors.Is(err, store.ErrInvalidCursor), errors.Is(err, store.ErrInvalidSort):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, store.ErrConflict):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// baseURL starts the URLs the API hands out: PublicBaseURL, or without it the scheme and host
+// the request reached the server on, honouring a TLS-terminating proxy. The client chooses the
+// Host header, so deployments set PublicBaseURL.
+func (env *Env) baseURL(r *http.Request) string {
+	if env.PublicBaseURL != "" {
+		return strings.TrimSuffix(env.PublicBaseURL, "/")
+	}
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// maxJobs bounds the virus scans and thumbnails running at once. Making a thumbnail may hold
+// a decoded image of up to thumbnail.MaxPixels, about 200 MB, so a burst of uploads must not
+// start one each.
+const maxJobs = 2
+
+// acquireJob takes a job slot if one is free and reports whether it did; releaseJob gives it back
+func (env *Env) acquireJob() bool {
+	env.jobsOnce.Do(func() { env.jobs = make(chan struct{}, maxJobs) })
+	select {
+	case env.jobs <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (env *Env) releaseJob() {
+	<-env.jobs
+}
+
+// startJob runs job in the background in a free slot and reports whether it did. Work that
+// is skipped is not lost: pending scans are retried and missing thumbnails made on request.
+func (env *Env) startJob(job func()) bool {
+	if !env.acquireJob() {
+		return false
+	}
+	go func() {
+		defer env.releaseJob()
+		job()
+	}()
+	return true
+}
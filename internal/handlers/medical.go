@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"pets_project/internal/models"
+)
+
+// === Medical History Handlers =====================================================
+// PetMedicalHandler is the mini-router for a pet's append-only medical history.
+// rest is the path after /pets/{id}/medical.
+//
+//	GET  /pets/{id}/medical            list entries (?sort=occurred_on, paginated)
+//	POST /pets/{id}/medical            append an entry
+//	GET  /pets/{id}/medical/{entryID}  fetch one entry
+//
+// Entries cannot be edited or deleted; a correction is a new entry with "amends_id" set.
+func (env *Env) PetMedicalHandler(w http.ResponseWriter, r *http.Request, petID int, rest string) {
+	if rest == "" || rest == "/" {
+		switch r.Method {
+		case "GET":
+			env.listMedicalEntries(w, r, petID)
+		case "POST":
+			env.createMedicalEntry(w, r, petID)
+		default:
+			http.Error(w, "Method not allowed for /pets/{id}/medical", http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	entryID, err := strconv.Atoi(strings.TrimPrefix(rest, "/"))
+	if err != nil {
+		http.Error(w, "Invalid ID in path", http.StatusBadRequest)
+		return
+	}
+	switch r.Method {
+	case "GET":
+		env.getMedicalEntry(w, r, petID, entryID)
+	case "PUT", "PATCH", "DELETE":
+		http.Error(w, "Medical entries are append-only; post a new entry with amends_id to correct one", http.StatusMethodNotAllowed)
+	default:
+		http.Error(w, "Method not allowed for /pets/{id}/medical/{entryID}", http.StatusMethodNotAllowed)
+	}
+}
+
+// --- Medical Entry Functions (internal) ---
+func (env *Env) listMedicalEntries(w http.ResponseWriter, r *http.Request, petID int) {
+	page, ok := parsePage(w, r)
+	if !ok {
+		return
+	}
+	scope, ok := env.requestScope(w, r)
+	if !ok {
+		return
+	}
+	if !env.checkPetInScope(w, r, scope, petID) {
+		return
+	}
+	entries, next, err := env.Medical.List(r.Context(), scope.Scope, petID, page)
+	if err != nil {
+		writeStoreError(w, err, "Medical entry not found")
+		return
+	}
+	writeList(w, entries, next)
+}
+
+func (env *Env) createMedicalEntry(w http.ResponseWriter, r *http.Request, petID int) {
+	var e models.MedicalEntry
+	if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	switch e.Type {
+	case models.MedicalDiagnosis, models.MedicalTreatment, models.MedicalNote:
+	default:
+		http.Error(w, "type must be one of diagnosis, treatment, note", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(e.Description) == "" {
+		http.Error(w, "description is required", http.StatusBadRequest)
+		return
+	}
+	if e.OccurredOn != "" {
+		if _, err := time.Parse("2006-01-02", e.OccurredOn); err != nil {
+			http.Error(w, "occurred_on must be a date in YYYY-MM-DD format", http.StatusBadRequest)
+			return
+		}
+	}
+	scope, ok := env.requestScope(w, r)
+	if !ok {
+		return
+	}
+	if !env.checkPetInScope(w, r, scope, petID) {
+		return
+	}
+
+	// The author is always the caller, never taken from the body
+	e.PetID = petID
+	e.AuthorUserID = scope.UserID
+	if err := env.Medical.Create(r.Context(), scope.Scope, &e); err != nil {
+		writeStoreError(w, err, "Medical entry not found")
+		return
+	}
+	Info("User ID %d added %s entry ID %d for pet ID %d", scope.UserID, e.Type, e.ID, petID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(e)
+}
+
+func (env *Env) getMedicalEntry(w http.ResponseWriter, r *http.Request, petID int, id int) {
+	scope, ok := env.requestScope(w, r)
+	if !ok {
+		return
+	}
+	e, err := env.Medical.Get(r.Context(), scope.Scope, petID, id)
+	if err != nil {
+		writeStoreError(w, err, "Medical entry not found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(e)
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"pets_project/internal/store"
+)
+
+// listResponse is the envelope returned by every paginated list endpoint.
+// NextCursor is passed back as ?cursor= to fetch the following page and is omitted on the last page.
+type listResponse struct {
+	Data       interface{} `json:"data"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+}
+
+// parsePage reads ?limit=, ?cursor= and ?sort= (e.g. "name" or "-name" for descending).
+// It writes a 400 and returns false if limit is not a positive number.
+func parsePage(w http.ResponseWriter, r *http.Request) (store.Page, bool) {
+	q := r.URL.Query()
+	page := store.Page{Cursor: q.Get("cursor"), Sort: q.Get("sort")}
+	if limitStr := q.Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return page, false
+		}
+		page.Limit = limit
+	}
+	return page, true
+}
+
+// queryInt reads an optional positive integer query parameter; 0 means absent
+func queryInt(w http.ResponseWriter, r *http.Request, name string) (int, bool) {
+	s := r.URL.Query().Get(name)
+	if s == "" {
+		return 0, true
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n <= 0 {
+		http.Error(w, name+" must be a positive integer", http.StatusBadRequest)
+		return 0, false
+	}
+	return n, true
+}
+
+// queryTime reads an optional time query parameter given as RFC 3339 or as a YYYY-MM-DD date (UTC).
+// With inclusiveDate a bare date means the end of that day, so ?to=2025-03-01 covers all of March 1st.
+func queryTime(w http.ResponseWriter, r *http.Request, name string, inclusiveDate bool) (time.Time, bool) {
+	s := r.URL.Query().Get(name)
+	if s == "" {
+		return time.Time{}, true
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, true
+	}
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		http.Error(w, name+" must be a YYYY-MM-DD date or an RFC 3339 time", http.StatusBadRequest)
+		return time.Time{}, false
+	}
+	if inclusiveDate {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, true
+}
+
+func writeList(w http.ResponseWriter, data interface{}, nextCursor string) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(listResponse{Data: data, NextCursor: nextCursor})
+}
@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"pets_project/internal/models"
+)
+
+// Permission names an action on a resource, e.g. "pets:delete"
+type Permission string
+
+// rolePermissions lists what each role may do. Admins are allowed everything.
+var rolePermissions = map[string][]Permission{
+	models.RoleVet: {
+		"pets:read", "pets:write",
+		"medical:read", "medical:write",
+		"vaccinations:read", "vaccinations:write", "vaccinations:delete",
+		"owners:read",
+		"appointments:read", "appointments:write", "appointments:delete", "appointment_status:write",
+		"files:read", "files:write", "files:delete",
+		"staff:read", "schedules:read",
+		"appointment_types:read", "clinic_hours:read", "blocked_periods:read",
+		"waitlist:read", "waitlist:write",
+	},
+	models.RoleReceptionist: {
+		"pets:read", "pets:write",
+		"medical:read",
+		"vaccinations:read",
+		"owners:read", "owners:write",
+		"appointments:read", "appointments:write", "appointments:delete", "appointment_status:write",
+		"files:read", "files:write",
+		"staff:read", "schedules:read",
+		"appointment_types:read", "clinic_hours:read",
+		"blocked_periods:read", "blocked_periods:write", "blocked_periods:delete",
+		"waitlist:read", "waitlist:write", "waitlist:delete",
+	},
+	// Owners are further limited to their own records by accessScope
+	models.RoleOwner: {
+		"pets:read", "pets:write",
+		"medical:read",
+		"vaccinations:read",
+		"owners:read",
+		"appointments:read", "appointments:write",
+		"files:read", "files:write",
+		"staff:read",
+		"appointment_types:read", "clinic_hours:read",
+		"waitlist:read", "waitlist:write", "waitlist:delete",
+	},
+}
+
+// routeResources maps protected route prefixes to the resource they expose.
+// The HTTP method picks the action: GET/HEAD/OPTIONS read, POST/PUT/PATCH write, DELETE deletes.
+// A "*" segment matches any single path segment (such as an ID); the first match wins.
+var routeResources = []struct {
+	prefix   string
+	resource string
+}{
+	{"/pets/*/medical", "medical"},
+	{"/pets/*/vaccinations", "vaccinations"},
+	{"/pets", "pets"},
+	{"/owners", "owners"},
+	{"/appointments/*/check-in", "appointment_status"}, // owners may only cancel
+	{"/appointments/*/start", "appointment_status"},
+	{"/appointments/*/complete", "appointment_status"},
+	{"/appointments/*/no-show", "appointment_status"},
+	{"/appointments", "appointments"},
+	{"/files", "files"},
+	{"/staff/*/schedule", "schedules"}, // lists appointments of every owner
+	{"/staff", "staff"},
+	{"/vaccinations", "vaccinations"},
+	{"/appointment-types", "appointment_types"},
+	{"/clinic-hours", "clinic_hours"},
+	{"/blocked-periods", "blocked_periods"},
+	{"/waitlist", "waitlist"},
+	{"/admin/users", "users"},
+	{"/admin/clinics", "clinics"},
+}
+
+// routeOverrides pins the permission for routes whose method does not describe the action.
+// An empty permission means any authenticated user may call the route.
+var routeOverrides = map[string]Permission{
+	"/logout":        "",
+	"/calendar-feed": "", // feeds check the role's permissions when they are read
+	"/upload":        "files:write",
+	"/download":      "files:read",
+	"/files/delete":  "files:delete",
+}
+
+// methodOverrides pins the permission of one method on the routes below a prefix pattern,
+// checked before routeResources
+var methodOverrides = []struct {
+	method string
+	prefix string
+	perm   Permission
+}{
+	// Whoever may start a resumable upload may abandon it (tus termination); the files
+	// it produces still need files:delete
+	{http.MethodDelete, "/files/uploads/*", "files:write"},
+}
+
+// hasPermission reports whether the role grants the permission
+func hasPermission(role string, perm Permission) bool {
+	if role == models.RoleAdmin {
+		return true
+	}
+	for _, p := range rolePermissions[role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// isValidRole reports whether role is one of the known roles
+func isValidRole(role string) bool {
+	switch role {
+	case models.RoleAdmin, models.RoleVet, models.RoleReceptionist, models.RoleOwner:
+		return true
+	}
+	return false
+}
+
+// requiredPermission resolves the permission needed for a request.
+// ok is false for routes that are not covered, which are denied by default.
+func requiredPermission(method, path string) (Permission, bool) {
+	if perm, found := routeOverrides[path]; found {
+		return perm, true
+	}
+	for _, mo := range methodOverrides {
+		if mo.method == method && matchesPrefix(mo.prefix, path) {
+			return mo.perm, true
+		}
+	}
+
+	var action string
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		action = "read"
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+		action = "write"
+	case http.MethodDelete:
+		action = "delete"
+	default:
+		return "", false
+	}
+
+	for _, rr := range routeResources {
+		if matchesPrefix(rr.prefix, path) {
+			return Permission(rr.resource + ":" + action), true
+		}
+	}
+	return "", false
+}
+
+// matchesPrefix reports whether path equals the prefix pattern or lies below it
+func matchesPrefix(prefix, path string) bool {
+	want := strings.Split(prefix, "/")
+	got := strings.Split(path, "/")
+	if len(got) < len(want) {
+		return false
+	}
+	for i, seg := range want {
+		if seg != got[i] && !(seg == "*" && got[i] != "") {
+			return false
+		}
+	}
+	return true
+}
+
+// --- Middleware ---
+// AuthorizeMiddleware enforces per-route permissions using the role placed in the
+// request context by JwtAuthMiddleware, so it must be wrapped inside it.
+func (env *Env) AuthorizeMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		role, _ := r.Context().Value("role").(string)
+		userID, _ := r.Context().Value("userID").(int)
+
+		perm, ok := requiredPermission(r.Method, r.URL.Path)
+		if !ok {
+			Warn("Denied %s %s for user ID %d: route has no permission rule", r.Method, r.URL.Path, userID)
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		if perm != "" && !hasPermission(role, perm) {
+			Warn("Denied %s %s for user ID %d: role %q lacks %s", r.Method, r.URL.Path, userID, role, perm)
+			http.Error(w, "Forbidden: insufficient permissions", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
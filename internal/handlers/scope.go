@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"pets_project/internal/models"
+	"pets_project/internal/store"
+)
+
+// accessScope describes which records the caller may see and modify.
+// Every caller is confined to the clinic (tenant) named in their token; within it,
+// clinic-wide roles see everything while pet owners only see records tied to their owner row.
+type accessScope struct {
+	store.Scope
+	UserID int
+	Role   string
+}
+
+// hasClinicWideAccess reports whether the role may work with every owner's records
+func hasClinicWideAccess(role string) bool {
+	switch role {
+	case models.RoleAdmin, models.RoleVet, models.RoleReceptionist:
+		return true
+	}
+	return false
+}
+
+// callerScope builds the access scope from the values JwtAuthMiddleware put in the context.
+// An owner account that is not linked to an owner record gets OwnerID 0, which matches nothing.
+func (env *Env) callerScope(r *http.Request) (accessScope, error) {
+	userID, _ := r.Context().Value("userID").(int)
+	role, _ := r.Context().Value("role").(string)
+	clinicID, _ := r.Context().Value("clinicID").(int)
+	return env.userScope(r.Context(), userID, role, clinicID)
+}
+
+// userScope builds the access scope of a user outside of a JWT-authenticated request
+func (env *Env) userScope(ctx context.Context, userID int, role string, clinicID int) (accessScope, error) {
+	scope := accessScope{
+		Scope:  store.Scope{ClinicID: clinicID, OwnerOnly: !hasClinicWideAccess(role)},
+		UserID: userID,
+		Role:   role,
+	}
+	if !scope.OwnerOnly {
+		return scope, nil
+	}
+
+	owner, err := env.Owners.GetByUser(ctx, clinicID, userID)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		return scope, err
+	}
+	scope.OwnerID = owner.ID
+	return scope, nil
+}
+
+// requestScope resolves the caller's scope, writing a 500 response on failure
+func (env *Env) requestScope(w http.ResponseWriter, r *http.Request) (accessScope, bool) {
+	scope, err := env.callerScope(r)
+	if err != nil {
+		Error("Failed to resolve access scope for user ID %d: %v", scope.UserID, err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return scope, false
+	}
+	return scope, true
+}
+
+// checkPetInScope writes a 404 response when the pet is missing or belongs to another owner or clinic
+func (env *Env) checkPetInScope(w http.ResponseWriter, r *http.Request, scope accessScope, petID int) bool {
+	_, err := env.Pets.Get(r.Context(), scope.Scope, petID)
+	if errors.Is(err, store.ErrNotFound) {
+		Warn("User ID %d tried to access pet ID %d outside their scope", scope.UserID, petID)
+		http.Error(w, "Pet not found", http.StatusNotFound)
+		return false
+	}
+	if err != nil {
+		Error("Failed to check access to pet ID %d: %v", petID, err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
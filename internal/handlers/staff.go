@@ -0,0 +1,295 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"pets_project/internal/models"
+	"pets_project/internal/scheduling"
+	"pets_project/internal/store"
+)
+
+// === Staff Handlers ===============================================================
+// StaffHandler (capitalized) is the mini-router. Exported to main.go.
+//
+//	GET    /staff                                   list (?role=, ?specialty=, paginated)
+//	POST   /staff                                   add a staff member with working hours
+//	GET    /staff/{id}                              fetch one
+//	PUT    /staff/{id}                              replace details and working hours
+//	DELETE /staff/{id}                              remove
+//	GET    /staff/{id}/schedule?date=YYYY-MM-DD     working hours and appointments that day
+//	GET    /staff/{id}/free-slots?date=&duration=   bookable slots of a vet that day
+func (env *Env) StaffHandler(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	if path == "/staff" {
+		switch r.Method {
+		case "GET":
+			env.getAllStaff(w, r)
+		case "POST":
+			env.createStaff(w, r)
+		default:
+			http.Error(w, "Method not allowed for /staff", http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	idStr, sub, _ := strings.Cut(strings.TrimPrefix(path, "/staff/"), "/")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid ID in path", http.StatusBadRequest)
+		return
+	}
+	switch sub {
+	case "":
+		switch r.Method {
+		case "GET":
+			env.getStaffByID(w, r, id)
+		case "PUT":
+			env.updateStaff(w, r, id)
+		case "DELETE":
+			env.deleteStaff(w, r, id)
+		default:
+			http.Error(w, "Method not allowed for /staff/{id}", http.StatusMethodNotAllowed)
+		}
+	case "schedule", "free-slots":
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed for /staff/{id}/"+sub, http.StatusMethodNotAllowed)
+			return
+		}
+		if sub == "schedule" {
+			env.getStaffSchedule(w, r, id)
+		} else {
+			env.getStaffFreeSlots(w, r, id)
+		}
+	default:
+		http.Error(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// --- Staff Functions (internal) ---
+func isValidStaffRole(role string) bool {
+	switch role {
+	case models.StaffVet, models.StaffNurse, models.StaffTechnician, models.StaffReceptionist:
+		return true
+	}
+	return false
+}
+
+// decodeStaff reads and validates a staff body
+func decodeStaff(w http.ResponseWriter, r *http.Request) (models.Staff, bool) {
+	var st models.Staff
+	if err := json.NewDecoder(r.Body).Decode(&st); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return st, false
+	}
+	if strings.TrimSpace(st.Name) == "" {
+		http.Error(w, "name is required", http.StatusBadRequest)
+		return st, false
+	}
+	if !isValidStaffRole(st.Role) {
+		http.Error(w, "role must be one of vet, nurse, technician, receptionist", http.StatusBadRequest)
+		return st, false
+	}
+	if err := scheduling.ValidateHours(st.WorkingHours); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return st, false
+	}
+	if st.Specialties == nil {
+		st.Specialties = []string{}
+	}
+	if st.WorkingHours == nil {
+		st.WorkingHours = []models.WorkingHours{}
+	}
+	return st, true
+}
+
+func (env *Env) getAllStaff(w http.ResponseWriter, r *http.Request) {
+	page, ok := parsePage(w, r)
+	if !ok {
+		return
+	}
+	clinicID, _ := r.Context().Value("clinicID").(int)
+	filter := store.StaffFilter{Role: r.URL.Query().Get("role"), Specialty: r.URL.Query().Get("specialty")}
+	staff, next, err := env.Staff.List(r.Context(), clinicID, filter, page)
+	if err != nil {
+		writeStoreError(w, err, "Staff member not found")
+		return
+	}
+	writeList(w, staff, next)
+}
+
+func (env *Env) createStaff(w http.ResponseWriter, r *http.Request) {
+	st, ok := decodeStaff(w, r)
+	if !ok {
+		return
+	}
+	clinicID, _ := r.Context().Value("clinicID").(int)
+	if err := env.Staff.Create(r.Context(), clinicID, &st); err != nil {
+		writeStoreError(w, err, "Staff member not found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(st)
+}
+
+func (env *Env) getStaffByID(w http.ResponseWriter, r *http.Request, id int) {
+	clinicID, _ := r.Context().Value("clinicID").(int)
+	st, err := env.Staff.Get(r.Context(), clinicID, id)
+	if err != nil {
+		writeStoreError(w, err, "Staff member not found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(st)
+}
+
+func (env *Env) updateStaff(w http.ResponseWriter, r *http.Request, id int) {
+	st, ok := decodeStaff(w, r)
+	if !ok {
+		return
+	}
+	clinicID, _ := r.Context().Value("clinicID").(int)
+	st.ID = id
+	if err := env.Staff.Update(r.Context(), clinicID, &st); err != nil {
+		writeStoreError(w, err, "Staff member not found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(st)
+}
+
+func (env *Env) deleteStaff(w http.ResponseWriter, r *http.Request, id int) {
+	clinicID, _ := r.Context().Value("clinicID").(int)
+	if err := env.Staff.Delete(r.Context(), clinicID, id); err != nil {
+		writeStoreError(w, err, "Staff member not found")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Staff member deleted successfully"})
+}
+
+// staffDay loads the staff member, the clinic time zone and the requested ?date= (default today).
+// It writes the error response and returns false on failure.
+func (env *Env) staffDay(w http.ResponseWriter, r *http.Request, id int) (models.Staff, time.Time, bool) {
+	clinicID, _ := r.Context().Value("clinicID").(int)
+	st, err := env.Staff.Get(r.Context(), clinicID, id)
+	if err != nil {
+		writeStoreError(w, err, "Staff member not found")
+		return st, time.Time{}, false
+	}
+	loc, ok := env.clinicLocation(w, r, clinicID)
+	if !ok {
+		return st, time.Time{}, false
+	}
+	date := r.URL.Query().Get("date")
+	if date == "" {
+		date = time.Now().In(loc).Format("2006-01-02")
+	}
+	day, err := scheduling.Day(date, loc)
+	if err != nil {
+		http.Error(w, "date must be in YYYY-MM-DD format", http.StatusBadRequest)
+		return st, time.Time{}, false
+	}
+	return st, day, true
+}
+
+// clinicLocation loads the time zone of the clinic, writing a 500 on failure
+func (env *Env) clinicLocation(w http.ResponseWriter, r *http.Request, clinicID int) (*time.Location, bool) {
+	clinic, err := env.Clinics.Get(r.Context(), clinicID)
+	if err != nil {
+		Error("Failed to load clinic ID %d: %v", clinicID, err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return nil, false
+	}
+	loc, err := time.LoadLocation(clinic.Timezone)
+	if err != nil {
+		Error("Clinic ID %d has an unknown time zone %q: %v", clinicID, clinic.Timezone, err)
+		http.Error(w, "Clinic time zone is misconfigured", http.StatusInternalServerError)
+		return nil, false
+	}
+	return loc, true
+}
+
+// getStaffSchedule returns a staff member's working periods and appointments for one day
+func (env *Env) getStaffSchedule(w http.ResponseWriter, r *http.Request, id int) {
+	st, day, ok := env.staffDay(w, r, id)
+	if !ok {
+		return
+	}
+	clinicID, _ := r.Context().Value("clinicID").(int)
+	appointments, err := env.Appointments.ListForVet(r.Context(), clinicID, id, day, day.AddDate(0, 0, 1))
+	if err != nil {
+		writeStoreError(w, err, "Staff member not found")
+		return
+	}
+
+	working := scheduling.WorkingIntervals(day, st.WorkingHours)
+	if working == nil {
+		working = []scheduling.Interval{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"staff_id":     st.ID,
+		"date":         day.Format("2006-01-02"),
+		"timezone":     day.Location().String(),
+		"working":      working,
+		"appointments": appointments,
+	})
+}
+
+// getStaffFreeSlots returns the slots of ?duration= minutes (default 30) in which the vet
+// is working within clinic hours and neither booked nor blocked
+func (env *Env) getStaffFreeSlots(w http.ResponseWriter, r *http.Request, id int) {
+	duration := defaultAppointmentMinutes
+	if s := r.URL.Query().Get("duration"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 5 || n > maxAppointmentMinutes {
+			http.Error(w, "duration must be a number of minutes between 5 and 480", http.StatusBadRequest)
+			return
+		}
+		duration = n
+	}
+	st, day, ok := env.staffDay(w, r, id)
+	if !ok {
+		return
+	}
+	if st.Role != models.StaffVet {
+		http.Error(w, "Only vets can be booked", http.StatusBadRequest)
+		return
+	}
+	clinicID, _ := r.Context().Value("clinicID").(int)
+	dayEnd := day.AddDate(0, 0, 1)
+	clinicHours, err := env.Clinics.Hours(r.Context(), clinicID)
+	if err != nil {
+		writeStoreError(w, err, "Clinic not found")
+		return
+	}
+	blocked, err := env.Blocked.List(r.Context(), clinicID, day, dayEnd)
+	if err != nil {
+		writeStoreError(w, err, "Blocked period not found")
+		return
+	}
+	cal, err := env.vetCalendar(r.Context(), clinicID, st, clinicHours, blocked, day, dayEnd)
+	if err != nil {
+		writeStoreError(w, err, "Staff member not found")
+		return
+	}
+	free := cal.Open(day)
+	slots := scheduling.Slots(free, time.Duration(duration)*time.Minute, scheduling.DefaultStep)
+	if slots == nil {
+		slots = []scheduling.Interval{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"staff_id":         st.ID,
+		"date":             day.Format("2006-01-02"),
+		"timezone":         day.Location().String(),
+		"duration_minutes": duration,
+		"slots":            slots,
+	})
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"pets_project/internal/store"
+)
+
+// Refresh tokens outlive access tokens; each one can be exchanged exactly once.
+const refreshTokenTTL = 30 * 24 * time.Hour
+
+// randomToken returns n random bytes encoded as URL-safe base64
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken returns the SHA-256 hex digest stored in place of the raw refresh token
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// createSession opens a new login session and issues its first refresh token
+func (env *Env) createSession(ctx context.Context, userID int) (string, string, error) {
+	sessionID, err := randomToken(16)
+	if err != nil {
+		return "", "", err
+	}
+	refreshToken, err := randomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	if err := env.Sessions.Create(ctx, sessionID, userID, hashToken(refreshToken), refreshTokenTTL); err != nil {
+		return "", "", err
+	}
+	return sessionID, refreshToken, nil
+}
+
+// --- Refresh Handler ---
+// POST /token/refresh {"refresh_token": "..."}
+// Rotates the refresh token: the presented token is consumed and a new pair is returned.
+// Presenting an already consumed token is treated as theft and revokes the whole session.
+func (env *Env) RefreshTokenHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.RefreshToken == "" {
+		http.Error(w, "refresh_token is required", http.StatusBadRequest)
+		return
+	}
+
+	newRefreshToken, err := randomToken(32)
+	if err != nil {
+		Error("Failed to generate refresh token: %v", err)
+		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		return
+	}
+
+	user, sessionID, err := env.Sessions.Rotate(r.Context(), hashToken(body.RefreshToken), hashToken(newRefreshToken), refreshTokenTTL)
+	switch {
+	case errors.Is(err, store.ErrTokenInvalid):
+		Warn("Refresh failed: unknown refresh token")
+		http.Error(w, "Invalid refresh token", http.StatusUnauthorized)
+		return
+	case errors.Is(err, store.ErrSessionRevoked):
+		Warn("Refresh failed: session %s is revoked (user ID %d)", sessionID, user.ID)
+		http.Error(w, "Session has been revoked", http.StatusUnauthorized)
+		return
+	case errors.Is(err, store.ErrTokenReused):
+		Warn("Refresh token reuse detected for session %s (user ID %d); session revoked", sessionID, user.ID)
+		http.Error(w, "Refresh token reuse detected; session revoked", http.StatusUnauthorized)
+		return
+	case errors.Is(err, store.ErrTokenExpired):
+		Warn("Refresh failed: expired refresh token for session %s", sessionID)
+		http.Error(w, "Refresh token expired", http.StatusUnauthorized)
+		return
+	case err != nil:
+		Error("Database error during refresh: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	tokenString, err := generateJWT(user, sessionID)
+	if err != nil {
+		Error("Failed to generate JWT: %v", err)
+		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		return
+	}
+
+	Info("Refreshed tokens for user ID %d (session %s)", user.ID, sessionID)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"token":         tokenString,
+		"refresh_token": newRefreshToken,
+	})
+}
+
+// --- Logout Handler ---
+// POST /logout (JWT required)
+// Revokes the caller's session so its access token and refresh tokens stop working immediately.
+func (env *Env) LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sessionID, _ := r.Context().Value("sessionID").(string)
+	userID, _ := r.Context().Value("userID").(int)
+	if err := env.Sessions.Revoke(r.Context(), sessionID); err != nil {
+		Error("Failed to revoke session %s: %v", sessionID, err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	Info("User ID %d logged out (session %s revoked)", userID, sessionID)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Logged out successfully"})
+}
@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"pets_project/internal/models"
+)
+
+// defaultDueWithinDays is the look-ahead of /vaccinations/due when ?within_days= is absent
+const defaultDueWithinDays = 30
+
+// === Vaccination Handlers =========================================================
+// PetVaccinationsHandler is the mini-router for a pet's vaccinations.
+// rest is the path after /pets/{id}/vaccinations.
+//
+//	GET    /pets/{id}/vaccinations        list (?sort=administered_on, paginated)
+//	POST   /pets/{id}/vaccinations        record a vaccination
+//	GET    /pets/{id}/vaccinations/{vid}  fetch one
+//	PUT    /pets/{id}/vaccinations/{vid}  correct a record
+//	DELETE /pets/{id}/vaccinations/{vid}  remove a record
+func (env *Env) PetVaccinationsHandler(w http.ResponseWriter, r *http.Request, petID int, rest string) {
+	if rest == "" || rest == "/" {
+		switch r.Method {
+		case "GET":
+			env.listVaccinations(w, r, petID)
+		case "POST":
+			env.createVaccination(w, r, petID)
+		default:
+			http.Error(w, "Method not allowed for /pets/{id}/vaccinations", http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	id, err := strconv.Atoi(strings.TrimPrefix(rest, "/"))
+	if err != nil {
+		http.Error(w, "Invalid ID in path", http.StatusBadRequest)
+		return
+	}
+	switch r.Method {
+	case "GET":
+		env.getVaccination(w, r, petID, id)
+	case "PUT":
+		env.updateVaccination(w, r, petID, id)
+	case "DELETE":
+		env.deleteVaccination(w, r, petID, id)
+	default:
+		http.Error(w, "Method not allowed for /pets/{id}/vaccinations/{vid}", http.StatusMethodNotAllowed)
+	}
+}
+
+// VaccinationsHandler serves clinic-wide vaccination reports. Exported to main.go.
+//
+//	GET /vaccinations/due?within_days=30   pets overdue or due within N days, soonest first
+func (env *Env) VaccinationsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/vaccinations/due" {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed for /vaccinations/due", http.StatusMethodNotAllowed)
+		return
+	}
+	env.listDueVaccinations(w, r)
+}
+
+// --- Vaccination Functions (internal) ---
+// decodeVaccination reads and validates a vaccination body
+func decodeVaccination(w http.ResponseWriter, r *http.Request) (models.Vaccination, bool) {
+	var v models.Vaccination
+	if err := json.NewDecoder(r.Body).Decode(&v); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return v, false
+	}
+	if strings.TrimSpace(v.Vaccine) == "" || v.AdministeredOn == "" {
+		http.Error(w, "vaccine and administered_on are required", http.StatusBadRequest)
+		return v, false
+	}
+	administered, err := time.Parse("2006-01-02", v.AdministeredOn)
+	if err != nil {
+		http.Error(w, "administered_on must be a date in YYYY-MM-DD format", http.StatusBadRequest)
+		return v, false
+	}
+	if v.NextDueOn != "" {
+		nextDue, err := time.Parse("2006-01-02", v.NextDueOn)
+		if err != nil {
+			http.Error(w, "next_due_on must be a date in YYYY-MM-DD format", http.StatusBadRequest)
+			return v, false
+		}
+		if nextDue.Before(administered) {
+			http.Error(w, "next_due_on cannot be before administered_on", http.StatusBadRequest)
+			return v, false
+		}
+	}
+	return v, true
+}
+
+func (env *Env) listVaccinations(w http.ResponseWriter, r *http.Request, petID int) {
+	page, ok := parsePage(w, r)
+	if !ok {
+		return
+	}
+	scope, ok := env.requestScope(w, r)
+	if !ok {
+		return
+	}
+	if !env.checkPetInScope(w, r, scope, petID) {
+		return
+	}
+	vaccinations, next, err := env.Vaccinations.List(r.Context(), scope.Scope, petID, page)
+	if err != nil {
+		writeStoreError(w, err, "Vaccination not found")
+		return
+	}
+	writeList(w, vaccinations, next)
+}
+
+func (env *Env) createVaccination(w http.ResponseWriter, r *http.Request, petID int) {
+	v, ok := decodeVaccination(w, r)
+	if !ok {
+		return
+	}
+	scope, ok := env.requestScope(w, r)
+	if !ok {
+		return
+	}
+	if !env.checkPetInScope(w, r, scope, petID) {
+		return
+	}
+	v.PetID = petID
+	// Default the administering vet to whoever records the dose
+	if v.AdministeredBy == 0 {
+		v.AdministeredBy = scope.UserID
+	}
+	if err := env.Vaccinations.Create(r.Context(), scope.Scope, &v); err != nil {
+		writeStoreError(w, err, "Vaccination not found")
+		return
+	}
+	Info("User ID %d recorded %s vaccination for pet ID %d", scope.UserID, v.Vaccine, petID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(v)
+}
+
+func (env *Env) getVaccination(w http.ResponseWriter, r *http.Request, petID int, id int) {
+	scope, ok := env.requestScope(w, r)
+	if !ok {
+		return
+	}
+	v, err := env.Vaccinations.Get(r.Context(), scope.Scope, petID, id)
+	if err != nil {
+		writeStoreError(w, err, "Vaccination not found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func (env *Env) updateVaccination(w http.ResponseWriter, r *http.Request, petID int, id int) {
+	v, ok := decodeVaccination(w, r)
+	if !ok {
+		return
+	}
+	scope, ok := env.requestScope(w, r)
+	if !ok {
+		return
+	}
+	if !env.checkPetInScope(w, r, scope, petID) {
+		return
+	}
+	v.ID = id
+	v.PetID = petID
+	if err := env.Vaccinations.Update(r.Context(), scope.Scope, &v); err != nil {
+		writeStoreError(w, err, "Vaccination not found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func (env *Env) deleteVaccination(w http.ResponseWriter, r *http.Request, petID int, id int) {
+	scope, ok := env.requestScope(w, r)
+	if !ok {
+		return
+	}
+	if err := env.Vaccinations.Delete(r.Context(), scope.Scope, petID, id); err != nil {
+		writeStoreError(w, err, "Vaccination not found")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Vaccination deleted successfully"})
+}
+
+func (env *Env) listDueVaccinations(w http.ResponseWriter, r *http.Request) {
+	page, ok := parsePage(w, r)
+	if !ok {
+		return
+	}
+	if page.Sort == "" {
+		page.Sort = "next_due_on"
+	}
+	withinDays := defaultDueWithinDays
+	if s := r.URL.Query().Get("within_days"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 || n > 365 {
+			http.Error(w, "within_days must be an integer between 0 and 365", http.StatusBadRequest)
+			return
+		}
+		withinDays = n
+	}
+	scope, ok := env.requestScope(w, r)
+	if !ok {
+		return
+	}
+
+	today := time.Now().Format("2006-01-02")
+	dueBy := time.Now().AddDate(0, 0, withinDays).Format("2006-01-02")
+	due, next, err := env.Vaccinations.Due(r.Context(), scope.Scope, dueBy, page)
+	if err != nil {
+		writeStoreError(w, err, "Vaccination not found")
+		return
+	}
+	for i := range due {
+		due[i].Overdue = due[i].NextDueOn < today
+	}
+	writeList(w, due, next)
+}
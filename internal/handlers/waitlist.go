@@ -0,0 +1,437 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"pets_project/internal/models"
+	"pets_project/internal/notify"
+	"pets_project/internal/store"
+)
+
+// notifyTimeout bounds sending one notification after the request that caused it has finished
+const notifyTimeout = time.Minute
+
+// defaultWaitlistOfferTTL is how long an owner has to answer an offer when Env.WaitlistOfferTTL is unset
+const defaultWaitlistOfferTTL = 24 * time.Hour
+
+// === Waitlist Handlers ============================================================
+// WaitlistHandler is the mini-router for pets waiting for a slot. When an appointment is
+// cancelled or deleted its slot is offered to the first matching entry, whose owner is notified.
+//
+//	GET    /waitlist                ?pet_id=, ?vet_id=, ?status=waiting|offered|booked
+//	POST   /waitlist
+//	GET, PUT, DELETE /waitlist/{id}
+//	POST   /waitlist/{id}/accept    book the offered slot
+//	POST   /waitlist/{id}/decline   return to waiting; the slot is offered to the next entry
+//
+// Offers that are not answered within the offer TTL are withdrawn by ExpireWaitlistOffers
+// as if they were declined.
+func (env *Env) WaitlistHandler(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	if path == "/waitlist" {
+		switch r.Method {
+		case "GET":
+			env.getWaitlist(w, r)
+		case "POST":
+			env.createWaitlistEntry(w, r)
+		default:
+			http.Error(w, "Method not allowed for /waitlist", http.StatusMethodNotAllowed)
+		}
+	} else if strings.HasPrefix(path, "/waitlist/") {
+		idStr, action, _ := strings.Cut(strings.TrimPrefix(path, "/waitlist/"), "/")
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			http.Error(w, "Invalid ID in path", http.StatusBadRequest)
+			return
+		}
+		if action != "" {
+			if action != "accept" && action != "decline" {
+				http.Error(w, "Not found", http.StatusNotFound)
+				return
+			}
+			if r.Method != http.MethodPost {
+				http.Error(w, "Method not allowed for /waitlist/{id}/"+action, http.StatusMethodNotAllowed)
+				return
+			}
+			if action == "accept" {
+				env.acceptWaitlistOffer(w, r, id)
+			} else {
+				env.declineWaitlistOffer(w, r, id)
+			}
+			return
+		}
+		switch r.Method {
+		case "GET":
+			env.getWaitlistEntryByID(w, r, id)
+		case "PUT":
+			env.updateWaitlistEntry(w, r, id)
+		case "DELETE":
+			env.deleteWaitlistEntry(w, r, id)
+		default:
+			http.Error(w, "Method not allowed for /waitlist/{id}", http.StatusMethodNotAllowed)
+		}
+	} else {
+		http.Error(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// --- Waitlist Functions (internal) ---
+// decodeWaitlistEntry reads and validates a waitlist entry body
+func decodeWaitlistEntry(w http.ResponseWriter, r *http.Request) (models.WaitlistEntry, bool) {
+	var e models.WaitlistEntry
+	if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return e, false
+	}
+	if e.PetID == 0 || e.EarliestDate == "" || e.LatestDate == "" {
+		http.Error(w, "pet_id, earliest_date and latest_date are required", http.StatusBadRequest)
+		return e, false
+	}
+	earliest, err := time.Parse("2006-01-02", e.EarliestDate)
+	if err != nil {
+		http.Error(w, "earliest_date must be a date in YYYY-MM-DD format", http.StatusBadRequest)
+		return e, false
+	}
+	latest, err := time.Parse("2006-01-02", e.LatestDate)
+	if err != nil {
+		http.Error(w, "latest_date must be a date in YYYY-MM-DD format", http.StatusBadRequest)
+		return e, false
+	}
+	if latest.Before(earliest) {
+		http.Error(w, "latest_date cannot be before earliest_date", http.StatusBadRequest)
+		return e, false
+	}
+	if e.DurationMinutes == 0 {
+		e.DurationMinutes = defaultAppointmentMinutes
+	}
+	if e.DurationMinutes < 5 || e.DurationMinutes > maxAppointmentMinutes {
+		http.Error(w, fmt.Sprintf("duration_minutes must be between 5 and %d", maxAppointmentMinutes), http.StatusBadRequest)
+		return e, false
+	}
+	return e, true
+}
+
+func (env *Env) getWaitlist(w http.ResponseWriter, r *http.Request) {
+	var filter store.WaitlistFilter
+	var ok bool
+	if filter.PetID, ok = queryInt(w, r, "pet_id"); !ok {
+		return
+	}
+	if filter.VetID, ok = queryInt(w, r, "vet_id"); !ok {
+		return
+	}
+	switch filter.Status = r.URL.Query().Get("status"); filter.Status {
+	case "", models.WaitlistWaiting, models.WaitlistOffered, models.WaitlistBooked:
+	default:
+		http.Error(w, "status must be waiting, offered or booked", http.StatusBadRequest)
+		return
+	}
+	scope, ok := env.requestScope(w, r)
+	if !ok {
+		return
+	}
+	entries, err := env.Waitlist.List(r.Context(), scope.Scope, filter)
+	if err != nil {
+		writeStoreError(w, err, "Waitlist entry not found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
+
+func (env *Env) getWaitlistEntryByID(w http.ResponseWriter, r *http.Request, id int) {
+	scope, ok := env.requestScope(w, r)
+	if !ok {
+		return
+	}
+	e, err := env.Waitlist.Get(r.Context(), scope.Scope, id)
+	if err != nil {
+		writeStoreError(w, err, "Waitlist entry not found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(e)
+}
+
+// createWaitlistEntry adds a pet to the waitlist. Only clinic staff set priorities.
+func (env *Env) createWaitlistEntry(w http.ResponseWriter, r *http.Request) {
+	e, ok := decodeWaitlistEntry(w, r)
+	if !ok {
+		return
+	}
+	scope, ok := env.requestScope(w, r)
+	if !ok {
+		return
+	}
+	if scope.OwnerOnly {
+		e.Priority = 0
+	}
+	if err := env.Waitlist.Create(r.Context(), scope.Scope, &e); err != nil {
+		writeStoreError(w, err, "Waitlist entry not found")
+		return
+	}
+	Info("User ID %d put pet ID %d on the waitlist (entry ID %d)", scope.UserID, e.PetID, e.ID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(e)
+}
+
+func (env *Env) updateWaitlistEntry(w http.ResponseWriter, r *http.Request, id int) {
+	e, ok := decodeWaitlistEntry(w, r)
+	if !ok {
+		return
+	}
+	scope, ok := env.requestScope(w, r)
+	if !ok {
+		return
+	}
+	if scope.OwnerOnly {
+		existing, err := env.Waitlist.Get(r.Context(), scope.Scope, id)
+		if err != nil {
+			writeStoreError(w, err, "Waitlist entry not found")
+			return
+		}
+		e.Priority = existing.Priority
+	}
+	e.ID = id
+	if err := env.Waitlist.Update(r.Context(), scope.Scope, &e); err != nil {
+		writeStoreError(w, err, "Waitlist entry not found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(e)
+}
+
+func (env *Env) deleteWaitlistEntry(w http.ResponseWriter, r *http.Request, id int) {
+	scope, ok := env.requestScope(w, r)
+	if !ok {
+		return
+	}
+	if err := env.Waitlist.Delete(r.Context(), scope.Scope, id); err != nil {
+		writeStoreError(w, err, "Waitlist entry not found")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Waitlist entry deleted successfully"})
+}
+
+// acceptWaitlistOffer books the offered slot for the entry's pet. The slot may have been
+// taken in the meantime, in which case the booking conflict is returned and the offer stands.
+func (env *Env) acceptWaitlistOffer(w http.ResponseWriter, r *http.Request, id int) {
+	scope, ok := env.requestScope(w, r)
+	if !ok {
+		return
+	}
+	e, err := env.Waitlist.Get(r.Context(), scope.Scope, id)
+	if err != nil {
+		writeStoreError(w, err, "Waitlist entry not found")
+		return
+	}
+	if e.Status != models.WaitlistOffered {
+		writeStoreError(w, &store.WaitlistStateError{EntryID: id, Status: e.Status, Want: models.WaitlistOffered}, "Waitlist entry not found")
+		return
+	}
+	if time.Now().After(env.offerExpiry(*e.Offer)) {
+		// ExpireWaitlistOffers has not got to it yet; the slot is about to go to the next entry
+		http.Error(w, "The offer has expired", http.StatusGone)
+		return
+	}
+
+	a := models.Appointment{
+		PetID:           e.PetID,
+		VetID:           e.Offer.VetID,
+		StartsAt:        e.Offer.StartsAt,
+		DurationMinutes: e.DurationMinutes,
+		Reason:          e.Notes,
+	}
+	if err := env.Appointments.Create(r.Context(), scope.Scope, &a); err != nil {
+		env.writeBookingError(w, r, scope, err)
+		return
+	}
+	e, err = env.Waitlist.MarkBooked(r.Context(), scope.Scope, id, a.ID)
+	if err != nil {
+		// The offer was declined or removed concurrently; give the slot back
+		if delErr := env.Appointments.Delete(r.Context(), scope.Scope, a.ID); delErr != nil {
+			Error("Failed to remove appointment ID %d after waitlist entry ID %d could not be booked: %v", a.ID, id, delErr)
+		}
+		writeStoreError(w, err, "Waitlist entry not found")
+		return
+	}
+
+	Info("User ID %d accepted the waitlist offer for entry ID %d (appointment ID %d)", scope.UserID, id, a.ID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{"waitlist_entry": e, "appointment": a})
+}
+
+// declineWaitlistOffer returns the entry to the waitlist and offers the slot to the next match
+func (env *Env) declineWaitlistOffer(w http.ResponseWriter, r *http.Request, id int) {
+	scope, ok := env.requestScope(w, r)
+	if !ok {
+		return
+	}
+	declined, err := env.Waitlist.Decline(r.Context(), scope.Scope, id)
+	if err != nil {
+		writeStoreError(w, err, "Waitlist entry not found")
+		return
+	}
+	Info("User ID %d declined the waitlist offer for entry ID %d", scope.UserID, id)
+
+	start, _ := time.Parse(time.RFC3339, declined.Offer.StartsAt)
+	env.offerSlot(r.Context(), scope.ClinicID, start, declined.Offer.DurationMinutes, declined.Offer.VetID, []int{id})
+
+	e, err := env.Waitlist.Get(r.Context(), scope.Scope, id)
+	if err != nil {
+		writeStoreError(w, err, "Waitlist entry not found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(e)
+}
+
+// ExpireWaitlistOffers withdraws offers that were not answered in time, or whose slot has
+// started, and offers each slot to the next matching entry as a decline does. main runs it
+// periodically.
+func (env *Env) ExpireWaitlistOffers(ctx context.Context) {
+	now := time.Now()
+	expired, err := env.Waitlist.ExpireOffers(ctx, now.Add(-env.waitlistOfferTTL()), now)
+	if err != nil {
+		Error("Failed to expire waitlist offers: %v", err)
+		return
+	}
+	for _, x := range expired {
+		Info("Waitlist offer to entry ID %d expired unanswered", x.Entry.ID)
+		start, _ := time.Parse(time.RFC3339, x.Entry.Offer.StartsAt)
+		env.offerSlot(ctx, x.ClinicID, start, x.Entry.Offer.DurationMinutes, x.Entry.Offer.VetID, []int{x.Entry.ID})
+	}
+}
+
+func (env *Env) waitlistOfferTTL() time.Duration {
+	if env.WaitlistOfferTTL > 0 {
+		return env.WaitlistOfferTTL
+	}
+	return defaultWaitlistOfferTTL
+}
+
+// offerExpiry is when an offer lapses: after the offer TTL, or when its slot starts if sooner
+func (env *Env) offerExpiry(o models.WaitlistOffer) time.Time {
+	offeredAt, _ := time.Parse(time.RFC3339, o.OfferedAt)
+	startsAt, _ := time.Parse(time.RFC3339, o.StartsAt)
+	expiry := offeredAt.Add(env.waitlistOfferTTL())
+	if startsAt.Before(expiry) {
+		return startsAt
+	}
+	return expiry
+}
+
+// offerFreedSlots offers the slots of appointments that were just cancelled or deleted to the
+// waitlist. Only appointments that occupied their slot and have not started yet free anything.
+// Failures are logged: the change that freed the slots has already been made.
+func (env *Env) offerFreedSlots(ctx context.Context, clinicID int, freed []models.Appointment) {
+	now := time.Now()
+	for _, a := range freed {
+		start, err := time.Parse(time.RFC3339, a.StartsAt)
+		if err != nil || !start.After(now) {
+			continue
+		}
+		env.offerSlot(ctx, clinicID, start, a.DurationMinutes, a.VetID, nil)
+	}
+}
+
+// offerSlot offers one slot to the first matching waitlist entry, skipping those in exclude,
+// and notifies the pet's owner in the background. A slot whose vet has been booked for it
+// again, for instance while an earlier offer stood, is not offered.
+func (env *Env) offerSlot(ctx context.Context, clinicID int, start time.Time, durationMinutes int, vetID int, exclude []int) {
+	if !start.After(time.Now()) {
+		return
+	}
+	if vetID != 0 {
+		booked, err := env.Appointments.ListForVet(ctx, clinicID, vetID, start, start.Add(time.Duration(durationMinutes)*time.Minute))
+		if err != nil {
+			Error("Failed to check vet ID %d is free at %s: %v", vetID, start.UTC().Format(time.RFC3339), err)
+			return
+		}
+		if len(booked) > 0 {
+			Info("The slot at %s has been booked again (appointment ID %d); not offering it", start.UTC().Format(time.RFC3339), booked[0].ID)
+			return
+		}
+	}
+	clinic, err := env.Clinics.Get(ctx, clinicID)
+	if err != nil {
+		Error("Failed to load clinic ID %d to offer a freed slot: %v", clinicID, err)
+		return
+	}
+	loc, err := time.LoadLocation(clinic.Timezone)
+	if err != nil {
+		Error("Clinic ID %d has an unknown time zone %q: %v", clinicID, clinic.Timezone, err)
+		return
+	}
+
+	slot := store.FreedSlot{StartsAt: start, Date: start.In(loc).Format("2006-01-02"), DurationMinutes: durationMinutes, VetID: vetID}
+	e, err := env.Waitlist.Offer(ctx, clinicID, slot, time.Now(), exclude)
+	if errors.Is(err, store.ErrNotFound) {
+		return
+	}
+	if err != nil {
+		Error("Failed to offer the slot at %s to the waitlist: %v", start.UTC().Format(time.RFC3339), err)
+		return
+	}
+	Info("Offered the slot at %s to waitlist entry ID %d", e.Offer.StartsAt, e.ID)
+	go env.notifyWaitlistOffer(clinic, loc, e)
+}
+
+// notifyWaitlistOffer emails the owner of the entry's pet about the offered slot
+func (env *Env) notifyWaitlistOffer(clinic models.Clinic, loc *time.Location, e models.WaitlistEntry) {
+	if env.Notifier == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+
+	clinicScope := store.Scope{ClinicID: clinic.ID}
+	pet, err := env.Pets.Get(ctx, clinicScope, e.PetID)
+	if err != nil {
+		Error("Failed to load pet ID %d for waitlist entry ID %d: %v", e.PetID, e.ID, err)
+		return
+	}
+	owner, err := env.Owners.Get(ctx, clinicScope, pet.OwnerID)
+	if err != nil {
+		Error("Failed to load owner ID %d for waitlist entry ID %d: %v", pet.OwnerID, e.ID, err)
+		return
+	}
+	if owner.Email == "" {
+		Warn("Owner ID %d has no email address; waitlist entry ID %d must be told of its offer another way", owner.ID, e.ID)
+		return
+	}
+	vetName := ""
+	if e.Offer.VetID != 0 {
+		if vet, err := env.Staff.Get(ctx, clinic.ID, e.Offer.VetID); err == nil {
+			vetName = vet.Name
+		}
+	}
+
+	start, _ := time.Parse(time.RFC3339, e.Offer.StartsAt)
+	when := start.In(loc).Format("Monday 2 January 2006 at 15:04 MST")
+	body := fmt.Sprintf("Hello %s,\n\nA slot has opened up for %s at %s on %s", owner.Name, pet.Name, clinic.Name, when)
+	if vetName != "" {
+		body += " with " + vetName
+	}
+	body += fmt.Sprintf(" (%d minutes).\n\nPlease accept or decline the offer for waitlist entry %d by %s; "+
+		"the slot can still be booked by others until you do, and after that it is offered to the next pet waiting.\n\n%s\n",
+		e.DurationMinutes, e.ID, env.offerExpiry(*e.Offer).In(loc).Format("Monday 2 January 15:04 MST"), clinic.Name)
+	msg := notify.Message{
+		To:      owner.Email,
+		Subject: fmt.Sprintf("A slot is free for %s on %s", pet.Name, start.In(loc).Format("Mon 2 Jan 15:04")),
+		Body:    body,
+	}
+	if err := env.Notifier.Send(ctx, msg); err != nil {
+		Error("Failed to notify owner ID %d of the offer for waitlist entry ID %d: %v", owner.ID, e.ID, err)
+	}
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"pets_project/internal/models"
+	"pets_project/internal/store"
+)
+
+func TestDeclinedSlotBookedAgainIsNotOffered(t *testing.T) {
+	ts := newTestServer(t)
+	_, token := ts.login(t, 1, models.RoleReceptionist)
+	ctx := context.Background()
+	scope := store.Scope{ClinicID: 1}
+
+	vet := models.Staff{Name: "Dr. Smith", Role: models.StaffVet}
+	if err := ts.env.Staff.Create(ctx, 1, &vet); err != nil {
+		t.Fatalf("create vet: %v", err)
+	}
+	start := time.Now().Add(48 * time.Hour).UTC().Truncate(time.Minute)
+	booked := models.Appointment{PetID: ts.newPet(t, 1, "Rex").ID, VetID: vet.ID, StartsAt: start.Format(time.RFC3339), DurationMinutes: 30}
+	if err := ts.env.Appointments.Create(ctx, scope, &booked); err != nil {
+		t.Fatalf("create appointment: %v", err)
+	}
+	var entries []models.WaitlistEntry
+	for i, name := range []string{"Tom", "Bob"} {
+		e := models.WaitlistEntry{
+			PetID:           ts.newPet(t, 1, name).ID,
+			EarliestDate:    start.AddDate(0, 0, -1).Format("2006-01-02"),
+			LatestDate:      start.AddDate(0, 0, 1).Format("2006-01-02"),
+			DurationMinutes: 30,
+			Priority:        2 - i,
+		}
+		if err := ts.env.Waitlist.Create(ctx, scope, &e); err != nil {
+			t.Fatalf("create waitlist entry: %v", err)
+		}
+		entries = append(entries, e)
+	}
+
+	// Cancelling offers the slot to Tom, but it is booked again before he answers
+	expect(t, ts.do(t, token, "POST", fmt.Sprintf("/appointments/%d/cancel", booked.ID), map[string]string{"reason": "Owner ill"}), http.StatusOK, nil)
+	var tom models.WaitlistEntry
+	expect(t, ts.do(t, token, "GET", fmt.Sprintf("/waitlist/%d", entries[0].ID), nil), http.StatusOK, &tom)
+	if tom.Status != models.WaitlistOffered {
+		t.Fatalf("first entry status = %q, want offered", tom.Status)
+	}
+	rebooked := models.Appointment{PetID: ts.newPet(t, 1, "Max").ID, VetID: vet.ID, StartsAt: start.Format(time.RFC3339), DurationMinutes: 30}
+	if err := ts.env.Appointments.Create(ctx, scope, &rebooked); err != nil {
+		t.Fatalf("rebook the slot: %v", err)
+	}
+
+	// Declining does not pass the taken slot on to Bob
+	expect(t, ts.do(t, token, "POST", fmt.Sprintf("/waitlist/%d/decline", tom.ID), nil), http.StatusOK, nil)
+	var bob models.WaitlistEntry
+	expect(t, ts.do(t, token, "GET", fmt.Sprintf("/waitlist/%d", entries[1].ID), nil), http.StatusOK, &bob)
+	if bob.Status != models.WaitlistWaiting || bob.Offer != nil {
+		t.Errorf("second entry = %s with offer %+v, want waiting without an offer", bob.Status, bob.Offer)
+	}
+}
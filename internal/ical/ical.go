@@ -0,0 +1,139 @@
+// Package ical writes iCalendar (RFC 5545) feeds of appointments.
+//
+// Feeds are published calendars meant for subscription: every event carries a
+// UID that stays the same for the life of the appointment, and a SEQUENCE and
+// LAST-MODIFIED that change with it, so calendar apps replace the copy they hold
+// when the time changes and strike it through when its STATUS becomes CANCELLED.
+// Times are written in UTC.
+package ical
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Event statuses (STATUS property of a VEVENT)
+const (
+	StatusConfirmed = "CONFIRMED"
+	StatusCancelled = "CANCELLED"
+)
+
+// maxLineOctets is the longest content line RFC 5545 allows before folding
+const maxLineOctets = 75
+
+// utcFormat is the DATE-TIME form with the UTC designator
+const utcFormat = "20060102T150405Z"
+
+// Event is one VEVENT
+type Event struct {
+	UID         string
+	Start       time.Time
+	End         time.Time
+	Summary     string
+	Description string
+	Location    string
+	Status      string    // StatusConfirmed or StatusCancelled
+	Sequence    int       // revision of the event, 0 as first published
+	Modified    time.Time // LAST-MODIFIED, optional
+}
+
+// Calendar is a VCALENDAR object holding events
+type Calendar struct {
+	ProdID   string // e.g. "-//pets_project//Appointments//EN"
+	Name     string // shown by calendar apps (X-WR-CALNAME)
+	Timezone string // IANA zone the events are best shown in (X-WR-TIMEZONE), optional
+	Events   []Event
+}
+
+// Write encodes the calendar to w. stamp is the DTSTAMP of every event, normally now.
+func (c Calendar) Write(w io.Writer, stamp time.Time) error {
+	lw := &lineWriter{w: bufio.NewWriter(w)}
+	lw.line("BEGIN", "VCALENDAR")
+	lw.line("VERSION", "2.0")
+	lw.line("PRODID", c.ProdID)
+	lw.line("CALSCALE", "GREGORIAN")
+	lw.line("METHOD", "PUBLISH")
+	if c.Name != "" {
+		lw.line("X-WR-CALNAME", Escape(c.Name))
+	}
+	if c.Timezone != "" {
+		lw.line("X-WR-TIMEZONE", c.Timezone)
+	}
+	for _, e := range c.Events {
+		lw.line("BEGIN", "VEVENT")
+		lw.line("UID", e.UID)
+		lw.line("DTSTAMP", stamp.UTC().Format(utcFormat))
+		if !e.Modified.IsZero() {
+			lw.line("LAST-MODIFIED", e.Modified.UTC().Format(utcFormat))
+		}
+		lw.line("SEQUENCE", strconv.Itoa(e.Sequence))
+		lw.line("DTSTART", e.Start.UTC().Format(utcFormat))
+		lw.line("DTEND", e.End.UTC().Format(utcFormat))
+		lw.line("SUMMARY", Escape(e.Summary))
+		if e.Description != "" {
+			lw.line("DESCRIPTION", Escape(e.Description))
+		}
+		if e.Location != "" {
+			lw.line("LOCATION", Escape(e.Location))
+		}
+		if e.Status != "" {
+			lw.line("STATUS", e.Status)
+		}
+		if e.Status == StatusCancelled {
+			// Cancelled events no longer block the attendee's time
+			lw.line("TRANSP", "TRANSPARENT")
+		}
+		lw.line("END", "VEVENT")
+	}
+	lw.line("END", "VCALENDAR")
+	if lw.err != nil {
+		return lw.err
+	}
+	return lw.w.Flush()
+}
+
+// Escape escapes a TEXT property value: backslashes, semicolons, commas and line breaks
+func Escape(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`, "\r", `\n`).Replace(s)
+}
+
+// lineWriter writes content lines, folding them at maxLineOctets without splitting
+// UTF-8 sequences. The first error is kept and later writes are skipped.
+type lineWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (lw *lineWriter) line(name, value string) {
+	if lw.err != nil {
+		return
+	}
+	s := name + ":" + value
+	limit := maxLineOctets
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		lw.write(s[:cut] + "\r\n ")
+		s = s[cut:]
+		limit = maxLineOctets - 1 // continuation lines start with a space
+	}
+	lw.write(s + "\r\n")
+}
+
+func (lw *lineWriter) write(s string) {
+	if lw.err == nil {
+		_, lw.err = lw.w.WriteString(s)
+	}
+}
+
+// UID returns the stable identifier of the named object, e.g. UID("appointment", 42, "example.org")
+func UID(kind string, id int, domain string) string {
+	return kind + "-" + strconv.Itoa(id) + "@" + domain
+}
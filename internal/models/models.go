@@ -1,13 +1,108 @@
 package models
 
+// Clinic struct corresponds to the 'clinics' table (one tenant per clinic location)
+type Clinic struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	Address  string `json:"address"`
+	Timezone string `json:"timezone"` // IANA name; working hours and schedules use this local time
+}
+
+// Staff struct corresponds to the 'staff' table (with its 'staff_working_hours')
+type Staff struct {
+	ID           int            `json:"id"`
+	UserID       int            `json:"user_id,omitempty"` // linked login account, if any
+	Name         string         `json:"name"`
+	Role         string         `json:"role"`
+	Specialties  []string       `json:"specialties"`
+	WorkingHours []WorkingHours `json:"working_hours"`
+}
+
+// WorkingHours is one weekly working period in clinic local time
+type WorkingHours struct {
+	Weekday int    `json:"weekday"` // 0 = Sunday ... 6 = Saturday
+	Start   string `json:"start"`   // HH:MM
+	End     string `json:"end"`     // HH:MM
+}
+
+// AppointmentType struct corresponds to the 'appointment_types' table
+type AppointmentType struct {
+	ID              int    `json:"id"`
+	Name            string `json:"name"`
+	DurationMinutes int    `json:"duration_minutes"`
+}
+
+// BlockedPeriod struct corresponds to the 'blocked_periods' table.
+// A period without a staff member closes the whole clinic.
+type BlockedPeriod struct {
+	ID       int    `json:"id"`
+	StaffID  int    `json:"staff_id,omitempty"`
+	StartsAt string `json:"starts_at"` // RFC 3339
+	EndsAt   string `json:"ends_at"`   // RFC 3339
+	Reason   string `json:"reason"`
+}
+
+// Roles a staff member can hold (staff.role). Only vets can be assigned appointments.
+const (
+	StaffVet          = "vet"
+	StaffNurse        = "nurse"
+	StaffTechnician   = "technician"
+	StaffReceptionist = "receptionist"
+)
+
 // Pet struct corresponds to the 'pets' table
 type Pet struct {
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+	Species string `json:"species"`
+	Breed   string `json:"breed"`
+	OwnerID int    `json:"owner_id"`
+}
+
+// MedicalEntry struct corresponds to the 'medical_entries' table.
+// Entries are append-only: a correction is a new entry whose AmendsID points at the one it replaces.
+type MedicalEntry struct {
+	ID           int    `json:"id"`
+	PetID        int    `json:"pet_id"`
+	Type         string `json:"type"` // diagnosis, treatment or note
+	Description  string `json:"description"`
+	OccurredOn   string `json:"occurred_on"` // YYYY-MM-DD
+	AuthorUserID int    `json:"author_user_id,omitempty"`
+	AmendsID     int    `json:"amends_id,omitempty"`
+	CreatedAt    string `json:"created_at"`
+}
+
+// Kinds of medical entries (medical_entries.entry_type)
+const (
+	MedicalDiagnosis = "diagnosis"
+	MedicalTreatment = "treatment"
+	MedicalNote      = "note"
+)
+
+// Vaccination struct corresponds to the 'vaccinations' table
+type Vaccination struct {
 	ID             int    `json:"id"`
-	Name           string `json:"name"`
-	Species        string `json:"species"`
-	Breed          string `json:"breed"`
+	PetID          int    `json:"pet_id"`
+	Vaccine        string `json:"vaccine"`
+	LotNumber      string `json:"lot_number"`
+	AdministeredOn string `json:"administered_on"`       // YYYY-MM-DD
+	NextDueOn      string `json:"next_due_on,omitempty"` // YYYY-MM-DD, empty when no booster is needed
+	AdministeredBy int    `json:"administered_by,omitempty"`
+}
+
+// VaccinationDue is a pet whose latest dose of a vaccine is due, with the owner's contact details
+type VaccinationDue struct {
+	VaccinationID  int    `json:"vaccination_id"`
+	PetID          int    `json:"pet_id"`
+	PetName        string `json:"pet_name"`
 	OwnerID        int    `json:"owner_id"`
-	MedicalHistory string `json:"medical_history"`
+	OwnerName      string `json:"owner_name"`
+	OwnerContact   string `json:"owner_contact"`
+	OwnerEmail     string `json:"owner_email"`
+	Vaccine        string `json:"vaccine"`
+	AdministeredOn string `json:"administered_on"`
+	NextDueOn      string `json:"next_due_on"`
+	Overdue        bool   `json:"overdue"`
 }
 
 // Owner struct corresponds to the 'owners' table
@@ -16,35 +111,170 @@ type Owner struct {
 	Name    string `json:"name"`
 	Contact string `json:"contact"`
 	Email   string `json:"email"`
+	UserID  int    `json:"user_id,omitempty"` // linked login account, if any
 }
 
 // Appointment struct corresponds to the 'appointments' table
 type Appointment struct {
 	ID              int    `json:"id"`
 	PetID           int    `json:"pet_id"`
-	AppointmentDate string `json:"appointment_date"`
-	AppointmentTime string `json:"appointment_time"`
+	VetID           int    `json:"vet_id,omitempty"` // staff member with the vet role, if assigned
+	StartsAt        string `json:"starts_at"`        // RFC 3339, e.g. 2025-03-01T09:30:00Z
+	DurationMinutes int    `json:"duration_minutes"`
+	Reason          string `json:"reason"`
+	SeriesID        int    `json:"series_id,omitempty"` // set for occurrences of a recurring appointment
+	Status          string `json:"status"`
+}
+
+// Appointment statuses (appointments.status). Completed, cancelled and no-show are final;
+// cancelled and no-show appointments no longer occupy their time slot.
+const (
+	AppointmentScheduled  = "scheduled"
+	AppointmentCheckedIn  = "checked_in"
+	AppointmentInProgress = "in_progress"
+	AppointmentCompleted  = "completed"
+	AppointmentCancelled  = "cancelled"
+	AppointmentNoShow     = "no_show"
+)
+
+// AppointmentStatusEvent struct corresponds to the 'appointment_status_events' table
+type AppointmentStatusEvent struct {
+	ID            int    `json:"id"`
+	AppointmentID int    `json:"appointment_id"`
+	FromStatus    string `json:"from_status"`
+	ToStatus      string `json:"to_status"`
+	Reason        string `json:"reason,omitempty"`
+	UserID        int    `json:"user_id,omitempty"`
+	CreatedAt     string `json:"created_at"` // RFC 3339
+}
+
+// AppointmentSeries struct corresponds to the 'appointment_series' table
+// together with the appointments materialised from its rule
+type AppointmentSeries struct {
+	ID           int           `json:"id"`
+	RRule        string        `json:"rrule"` // RFC 5545 subset, e.g. FREQ=WEEKLY;COUNT=8
+	Appointments []Appointment `json:"appointments"`
+}
+
+// Reminder is an upcoming appointment with what is needed to remind its owner
+type Reminder struct {
+	AppointmentID   int    `json:"appointment_id"`
+	StartsAt        string `json:"starts_at"` // RFC 3339
+	DurationMinutes int    `json:"duration_minutes"`
 	Reason          string `json:"reason"`
+	PetName         string `json:"pet_name"`
+	OwnerName       string `json:"owner_name"`
+	OwnerEmail      string `json:"owner_email"`
+	VetName         string `json:"vet_name,omitempty"`
+	ClinicName      string `json:"clinic_name"`
+	ClinicTimezone  string `json:"clinic_timezone"`
+}
+
+// WaitlistEntry struct corresponds to the 'waitlist_entries' table: a pet waiting for a
+// slot within a date range, optionally with a preferred vet
+type WaitlistEntry struct {
+	ID              int            `json:"id"`
+	PetID           int            `json:"pet_id"`
+	VetID           int            `json:"vet_id,omitempty"` // preferred vet; any vet when unset
+	EarliestDate    string         `json:"earliest_date"`    // YYYY-MM-DD in clinic local time
+	LatestDate      string         `json:"latest_date"`      // YYYY-MM-DD, inclusive
+	DurationMinutes int            `json:"duration_minutes"`
+	Priority        int            `json:"priority"` // higher priorities are offered slots first
+	Notes           string         `json:"notes"`
+	Status          string         `json:"status"`
+	Offer           *WaitlistOffer `json:"offer,omitempty"`          // set while offered
+	AppointmentID   int            `json:"appointment_id,omitempty"` // set once booked
+	CreatedAt       string         `json:"created_at"`               // RFC 3339
+}
+
+// WaitlistOffer is a freed slot offered to a waitlist entry
+type WaitlistOffer struct {
+	StartsAt        string `json:"starts_at"`        // RFC 3339
+	DurationMinutes int    `json:"duration_minutes"` // length of the freed slot
+	VetID           int    `json:"vet_id,omitempty"`
+	OfferedAt       string `json:"offered_at"` // RFC 3339
+}
+
+// Waitlist entry statuses (waitlist_entries.status)
+const (
+	WaitlistWaiting = "waiting"
+	WaitlistOffered = "offered"
+	WaitlistBooked  = "booked"
+)
+
+// CalendarEntry is an appointment with the names shown in calendar feeds
+type CalendarEntry struct {
+	Appointment
+	PetName   string `json:"pet_name"`
+	OwnerName string `json:"owner_name,omitempty"` // empty for pets without an owner
+	VetName   string `json:"vet_name,omitempty"`
+	Revision  int    `json:"revision"`   // how many times the appointment was changed
+	UpdatedAt string `json:"updated_at"` // RFC 3339, when it was last changed
 }
 
 // User struct corresponds to the 'users' table
 type User struct {
 	ID           int    `json:"id"`
 	Email        string `json:"email"`
+	Role         string `json:"role"`
+	ClinicID     int    `json:"clinic_id"`
 	PasswordHash string `json:"-"` // never included in JSON output
 }
 
+// Roles a user can hold (users.role)
+const (
+	RoleAdmin        = "admin"
+	RoleVet          = "vet"
+	RoleReceptionist = "receptionist"
+	RoleOwner        = "owner"
+)
+
 // Credentials struct for handling login/signup JSON data
 type Credentials struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	ClinicID int    `json:"clinic_id,omitempty"` // required for signup only
 }
 
 // FileRecord struct corresponds to 'file_records' table
 type FileRecord struct {
-	ID         int    `json:"id"`
-	PetID      int    `json:"pet_id"`
-	FileName   string `json:"file_name"`
-	FilePath   string `json:"file_path"`
-	UploadedAt string `json:"uploaded_at"`
+	ID            int    `json:"id"`
+	PetID         int    `json:"pet_id"`
+	FileName      string `json:"file_name"`
+	FilePath      string `json:"file_path"`   // key in the blob store
+	DocumentID    int    `json:"document_id"` // id of the document's first version
+	Version       int    `json:"version"`     // 1 for the first upload of a document
+	UploadedAt    string `json:"uploaded_at"`
+	Size          int64  `json:"size,omitempty"`
+	SHA256        string `json:"sha256,omitempty"`       // hex digest of the contents; empty for older uploads
+	ContentType   string `json:"content_type,omitempty"` // detected from the contents; empty for older uploads
+	ScanStatus    string `json:"scan_status"`
+	ScanSignature string `json:"scan_signature,omitempty"` // malware found in an infected file, or why a failed one was not scanned
+	ScannedAt     string `json:"scanned_at,omitempty"`     // RFC 3339
+	ThumbnailURL  string `json:"thumbnail_url,omitempty"`  // set in responses for images; not stored
+}
+
+// File scan statuses (file_records.scan_status). Pending, infected (quarantined) and
+// failed files cannot be downloaded; failed files are ones the scanner refused to scan.
+const (
+	FileUnscanned   = "unscanned"
+	FilePendingScan = "pending"
+	FileClean       = "clean"
+	FileInfected    = "infected"
+	FileScanFailed  = "failed"
+)
+
+// Upload is a resumable (tus) upload of a pet's file. Each received chunk is stored on its
+// own until the last one arrives and they are joined into a FileRecord.
+type Upload struct {
+	ID          string   `json:"id"`
+	PetID       int      `json:"pet_id"`
+	FileName    string   `json:"file_name"`
+	ContentType string   `json:"content_type,omitempty"`
+	DocumentID  int      `json:"document_id,omitempty"` // set when the upload is a new version of a document
+	Length      int64    `json:"length"`
+	Offset      int64    `json:"offset"`            // bytes received so far
+	ChunkKeys   []string `json:"-"`                 // blob keys of the chunks, in order
+	FileID      int      `json:"file_id,omitempty"` // set once the upload is complete
+	ExpiresAt   string   `json:"expires_at"`
 }
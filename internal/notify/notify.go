@@ -0,0 +1,102 @@
+// Package notify delivers messages to clinic clients. Notifier implementations
+// are chosen at startup: SMTP for real delivery, log-only for development.
+package notify
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Message is a plain text notification for one recipient
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Notifier sends messages. Send returns an error if the message may not have been delivered.
+type Notifier interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// LogNotifier writes messages to a log instead of delivering them
+type LogNotifier struct {
+	Logf func(format string, args ...interface{})
+}
+
+func (n LogNotifier) Send(ctx context.Context, msg Message) error {
+	n.Logf("Notification to %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// SMTPNotifier sends messages through an SMTP server such as a local MailHog or Mailpit
+// during development. STARTTLS is used whenever the server offers it.
+type SMTPNotifier struct {
+	Addr     string // host:port
+	From     string
+	Username string // optional; PLAIN auth is only attempted over TLS or to localhost
+	Password string
+	Timeout  time.Duration // per message, default 30s
+}
+
+func (n SMTPNotifier) Send(ctx context.Context, msg Message) error {
+	if strings.ContainsAny(msg.To, "\r\n") || strings.ContainsAny(msg.Subject, "\r\n") {
+		return fmt.Errorf("invalid header value")
+	}
+	timeout := n.Timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+
+	dialer := net.Dialer{Deadline: deadline}
+	conn, err := dialer.DialContext(ctx, "tcp", n.Addr)
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(deadline)
+	host, _, _ := net.SplitHostPort(n.Addr)
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if n.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", n.Username, n.Password, host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(n.From); err != nil {
+		return err
+	}
+	if err := c.Rcpt(msg.To); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "From: %s\r\nTo: %s\r\nSubject: %s\r\nDate: %s\r\n", n.From, msg.To, msg.Subject, time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(w, "MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n")
+	// The data writer converts line endings and escapes leading dots
+	fmt.Fprint(w, msg.Body)
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
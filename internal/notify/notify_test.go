@@ -0,0 +1,152 @@
+package notify
+
+import (
+	"context"
+	"encoding/base64"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+)
+
+// smtpSession is what the fake server received in one connection
+type smtpSession struct {
+	auth string // decoded AUTH PLAIN credentials
+	from string
+	to   []string
+	data string
+}
+
+// fakeSMTP serves one SMTP connection on a local port and reports what it received.
+// Recipients in reject are refused with a permanent error.
+func fakeSMTP(t *testing.T, reject ...string) (string, <-chan smtpSession) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	done := make(chan smtpSession, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+		tp := textproto.NewConn(conn)
+		var s smtpSession
+		defer func() { done <- s }()
+
+		tp.PrintfLine("220 localhost ESMTP fake")
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+			verb, arg, _ := strings.Cut(line, " ")
+			switch strings.ToUpper(verb) {
+			case "EHLO":
+				tp.PrintfLine("250-localhost")
+				tp.PrintfLine("250 AUTH PLAIN")
+			case "AUTH":
+				_, encoded, _ := strings.Cut(arg, " ")
+				decoded, _ := base64.StdEncoding.DecodeString(encoded)
+				s.auth = string(decoded)
+				tp.PrintfLine("235 2.7.0 Authentication successful")
+			case "MAIL":
+				s.from = strings.TrimPrefix(arg, "FROM:")
+				tp.PrintfLine("250 OK")
+			case "RCPT":
+				to := strings.TrimPrefix(arg, "TO:")
+				if contains(reject, to) {
+					tp.PrintfLine("550 5.1.1 No such user")
+					continue
+				}
+				s.to = append(s.to, to)
+				tp.PrintfLine("250 OK")
+			case "DATA":
+				tp.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+				data, err := tp.ReadDotBytes()
+				if err != nil {
+					return
+				}
+				s.data = string(data)
+				tp.PrintfLine("250 OK: queued")
+			case "QUIT":
+				tp.PrintfLine("221 Bye")
+				return
+			default:
+				tp.PrintfLine("502 Command not implemented")
+			}
+		}
+	}()
+	return ln.Addr().String(), done
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSMTPNotifierSend(t *testing.T) {
+	addr, done := fakeSMTP(t)
+	n := SMTPNotifier{Addr: addr, From: "clinic@example.com", Username: "clinic", Password: "secret", Timeout: 5 * time.Second}
+
+	msg := Message{To: "ann@example.com", Subject: "Reminder: Rex's appointment", Body: "Hello Ann,\n.\nSee you soon.\n"}
+	if err := n.Send(context.Background(), msg); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	s := <-done
+	if s.auth != "\x00clinic\x00secret" {
+		t.Errorf("AUTH PLAIN credentials = %q", s.auth)
+	}
+	if s.from != "<clinic@example.com>" || len(s.to) != 1 || s.to[0] != "<ann@example.com>" {
+		t.Errorf("envelope from %q to %q", s.from, s.to)
+	}
+	header, body, ok := strings.Cut(s.data, "\n\n")
+	if !ok {
+		t.Fatalf("message has no header/body separator: %q", s.data)
+	}
+	for _, want := range []string{"From: clinic@example.com", "To: ann@example.com", "Subject: Reminder: Rex's appointment", "Content-Type: text/plain; charset=utf-8"} {
+		if !strings.Contains(header+"\n", want+"\n") {
+			t.Errorf("header lacks %q:\n%s", want, header)
+		}
+	}
+	// The lone dot line survives dot-stuffing
+	if body != msg.Body {
+		t.Errorf("body = %q, want %q", body, msg.Body)
+	}
+}
+
+func TestSMTPNotifierRejectedRecipient(t *testing.T) {
+	addr, done := fakeSMTP(t, "<nobody@example.com>")
+	n := SMTPNotifier{Addr: addr, From: "clinic@example.com", Timeout: 5 * time.Second}
+
+	err := n.Send(context.Background(), Message{To: "nobody@example.com", Subject: "Hi", Body: "Hello"})
+	if err == nil || !strings.Contains(err.Error(), "No such user") {
+		t.Errorf("Send = %v, want the server's 550 reply", err)
+	}
+	if s := <-done; s.data != "" {
+		t.Errorf("message data sent despite the rejected recipient: %q", s.data)
+	}
+}
+
+func TestSMTPNotifierRejectsHeaderInjection(t *testing.T) {
+	n := SMTPNotifier{Addr: "127.0.0.1:1", From: "clinic@example.com"}
+	for _, msg := range []Message{
+		{To: "ann@example.com\r\nBcc: eve@example.com", Subject: "Hi"},
+		{To: "ann@example.com", Subject: "Hi\r\nBcc: eve@example.com"},
+	} {
+		if err := n.Send(context.Background(), msg); err == nil || !strings.Contains(err.Error(), "invalid header") {
+			t.Errorf("Send(%q, %q) = %v, want an invalid header error", msg.To, msg.Subject, err)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+// Package recurrence expands the subset of RFC 5545 recurrence rules used for
+// repeat appointments: FREQ=DAILY, WEEKLY or MONTHLY with INTERVAL and either
+// COUNT or UNTIL. Open-ended rules are rejected so every series is finite.
+//
+// Occurrences keep the wall clock time of the first one in its time zone, so a
+// weekly 09:00 appointment stays at 09:00 across daylight saving changes.
+package recurrence
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxOccurrences bounds the size of one series
+const MaxOccurrences = 100
+
+// Supported frequencies
+const (
+	Daily   = "DAILY"
+	Weekly  = "WEEKLY"
+	Monthly = "MONTHLY"
+)
+
+// Rule is a parsed recurrence rule
+type Rule struct {
+	Freq     string
+	Interval int // 1 unless given
+	Count    int // 0 when UNTIL is used
+	// Until is the last moment an occurrence may start. A date-only UNTIL is
+	// kept in UntilDate instead and covers that whole day in the series' zone.
+	Until     time.Time
+	UntilDate string // YYYYMMDD
+}
+
+// Parse reads a rule such as "FREQ=WEEKLY;COUNT=10" or "RRULE:FREQ=DAILY;UNTIL=20250301T000000Z"
+func Parse(s string) (Rule, error) {
+	r := Rule{Interval: 1}
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	if s == "" {
+		return r, fmt.Errorf("empty recurrence rule")
+	}
+	seen := map[string]bool{}
+	for _, part := range strings.Split(s, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		key = strings.ToUpper(strings.TrimSpace(key))
+		value = strings.ToUpper(strings.TrimSpace(value))
+		if !ok || value == "" {
+			return r, fmt.Errorf("invalid rule part %q", part)
+		}
+		if seen[key] {
+			return r, fmt.Errorf("%s is given twice", key)
+		}
+		seen[key] = true
+
+		switch key {
+		case "FREQ":
+			if value != Daily && value != Weekly && value != Monthly {
+				return r, fmt.Errorf("FREQ must be DAILY, WEEKLY or MONTHLY")
+			}
+			r.Freq = value
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return r, fmt.Errorf("INTERVAL must be a positive integer")
+			}
+			r.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return r, fmt.Errorf("COUNT must be a positive integer")
+			}
+			if n > MaxOccurrences {
+				return r, fmt.Errorf("COUNT must be at most %d", MaxOccurrences)
+			}
+			r.Count = n
+		case "UNTIL":
+			if t, err := time.Parse("20060102T150405Z", value); err == nil {
+				r.Until = t
+			} else if _, err := time.Parse("20060102", value); err == nil {
+				r.UntilDate = value
+			} else {
+				return r, fmt.Errorf("UNTIL must be YYYYMMDD or YYYYMMDDTHHMMSSZ")
+			}
+		default:
+			return r, fmt.Errorf("%s is not supported", key)
+		}
+	}
+
+	if r.Freq == "" {
+		return r, fmt.Errorf("FREQ is required")
+	}
+	if seen["COUNT"] == seen["UNTIL"] {
+		return r, fmt.Errorf("exactly one of COUNT or UNTIL is required")
+	}
+	return r, nil
+}
+
+// String returns the rule in canonical form
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	switch {
+	case r.Count > 0:
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	case r.UntilDate != "":
+		parts = append(parts, "UNTIL="+r.UntilDate)
+	case !r.Until.IsZero():
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Expand lists the start times of the series beginning at start, which must be in
+// the series' time zone. Monthly rules skip months without start's day, as RFC 5545 does.
+// It fails when the rule yields no occurrence or more than MaxOccurrences.
+func (r Rule) Expand(start time.Time) ([]time.Time, error) {
+	// within reports whether t is not past UNTIL
+	within := func(t time.Time) bool { return true }
+	if r.UntilDate != "" {
+		d, _ := time.ParseInLocation("20060102", r.UntilDate, start.Location())
+		end := d.AddDate(0, 0, 1)
+		within = func(t time.Time) bool { return t.Before(end) }
+	} else if !r.Until.IsZero() {
+		within = func(t time.Time) bool { return !t.After(r.Until) }
+	}
+
+	var out []time.Time
+	y, m, d := start.Date()
+	hh, mm, ss := start.Clock()
+	// Monthly rules can skip months, so allow a year of misses for every occurrence
+	for i := 0; i < 12*MaxOccurrences; i++ {
+		var t time.Time
+		switch r.Freq {
+		case Daily:
+			t = time.Date(y, m, d+i*r.Interval, hh, mm, ss, 0, start.Location())
+		case Weekly:
+			t = time.Date(y, m, d+7*i*r.Interval, hh, mm, ss, 0, start.Location())
+		case Monthly:
+			t = time.Date(y, m+time.Month(i*r.Interval), d, hh, mm, ss, 0, start.Location())
+			if t.Day() != d {
+				continue
+			}
+		default:
+			return nil, fmt.Errorf("unsupported frequency %q", r.Freq)
+		}
+		if !within(t) {
+			break
+		}
+		if len(out) == MaxOccurrences {
+			return nil, fmt.Errorf("the rule yields more than %d occurrences", MaxOccurrences)
+		}
+		out = append(out, t)
+		if r.Count > 0 && len(out) == r.Count {
+			break
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("the rule yields no occurrences")
+	}
+	return out, nil
+}
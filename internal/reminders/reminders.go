@@ -0,0 +1,110 @@
+// Package reminders runs the background job that emails owners ahead of their
+// pets' appointments.
+//
+// Each run looks for scheduled appointments starting within Lead, claims the
+// reminder in the store, sends it and marks it sent. Claiming first means a
+// restart or a second server instance does not send the same reminder again;
+// a failed send releases the claim so the next run retries it.
+package reminders
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"pets_project/internal/models"
+	"pets_project/internal/notify"
+	"pets_project/internal/store"
+)
+
+// batchSize bounds the reminders handled in one run; the rest wait for the next run
+const batchSize = 100
+
+// Worker sends appointment reminders
+type Worker struct {
+	Store    store.ReminderStore
+	Notifier notify.Notifier
+	Lead     time.Duration // how long before an appointment its reminder goes out
+	Interval time.Duration // time between runs
+
+	// Logging hooks, e.g. handlers.Info and handlers.Error
+	Infof  func(format string, args ...interface{})
+	Errorf func(format string, args ...interface{})
+}
+
+// Run sends reminders every Interval until ctx is cancelled
+func (w *Worker) Run(ctx context.Context) {
+	w.Infof("Reminder worker started: %s before appointments, checking every %s", w.Lead, w.Interval)
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+	for {
+		if _, err := w.RunOnce(ctx, time.Now()); err != nil && ctx.Err() == nil {
+			w.Errorf("Reminder run failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			w.Infof("Reminder worker stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce sends the reminders due at now and returns how many were sent
+func (w *Worker) RunOnce(ctx context.Context, now time.Time) (int, error) {
+	due, err := w.Store.Due(ctx, now, now.Add(w.Lead), batchSize)
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	for _, r := range due {
+		claimed, err := w.Store.Claim(ctx, r, now)
+		if err != nil {
+			return sent, err
+		}
+		if !claimed {
+			continue // another worker has it
+		}
+		if err := w.Notifier.Send(ctx, Message(r)); err != nil {
+			w.Errorf("Failed to send reminder for appointment ID %d: %v", r.AppointmentID, err)
+			if err := w.Store.Release(ctx, r); err != nil {
+				return sent, err
+			}
+			continue
+		}
+		if err := w.Store.MarkSent(ctx, r, now); err != nil {
+			return sent, err
+		}
+		sent++
+	}
+	if sent > 0 {
+		w.Infof("Sent %d appointment reminder(s)", sent)
+	}
+	return sent, nil
+}
+
+// Message renders the reminder email, showing the time in the clinic's time zone
+func Message(r models.Reminder) notify.Message {
+	start, _ := time.Parse(time.RFC3339, r.StartsAt)
+	if loc, err := time.LoadLocation(r.ClinicTimezone); err == nil {
+		start = start.In(loc)
+	}
+	when := start.Format("Monday 2 January 2006 at 15:04 MST")
+
+	body := fmt.Sprintf("Hello %s,\n\nThis is a reminder that %s has an appointment at %s on %s",
+		r.OwnerName, r.PetName, r.ClinicName, when)
+	if r.VetName != "" {
+		body += " with " + r.VetName
+	}
+	body += fmt.Sprintf(" (%d minutes).\n", r.DurationMinutes)
+	if r.Reason != "" {
+		body += "Reason: " + r.Reason + "\n"
+	}
+	body += "\nIf you cannot make it, please let us know so we can offer the time to someone else.\n\n" + r.ClinicName + "\n"
+
+	return notify.Message{
+		To:      r.OwnerEmail,
+		Subject: fmt.Sprintf("Reminder: %s's appointment on %s", r.PetName, start.Format("Mon 2 Jan 15:04")),
+		Body:    body,
+	}
+}
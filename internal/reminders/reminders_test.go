@@ -0,0 +1,150 @@
+package reminders
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"pets_project/internal/models"
+	"pets_project/internal/notify"
+	"pets_project/internal/store"
+)
+
+// fakeNotifier records the messages it is asked to send and fails while err is set
+type fakeNotifier struct {
+	sent []notify.Message
+	err  error
+}
+
+func (n *fakeNotifier) Send(ctx context.Context, msg notify.Message) error {
+	if n.err != nil {
+		return n.err
+	}
+	n.sent = append(n.sent, msg)
+	return nil
+}
+
+// newWorker returns a worker on the memory stores with one appointment starting at startsAt
+func newWorker(t *testing.T, startsAt time.Time) (*Worker, *fakeNotifier) {
+	t.Helper()
+	ctx := context.Background()
+	stores := store.NewMemory()
+	scope := store.Scope{ClinicID: 1}
+
+	owner := models.Owner{Name: "Ann", Email: "ann@example.com"}
+	if err := stores.Owners.Create(ctx, scope, &owner); err != nil {
+		t.Fatalf("create owner: %v", err)
+	}
+	pet := models.Pet{Name: "Rex", OwnerID: owner.ID}
+	if err := stores.Pets.Create(ctx, scope, &pet); err != nil {
+		t.Fatalf("create pet: %v", err)
+	}
+	a := models.Appointment{PetID: pet.ID, StartsAt: startsAt.Format(time.RFC3339), DurationMinutes: 30, Reason: "Checkup"}
+	if err := stores.Appointments.Create(ctx, scope, &a); err != nil {
+		t.Fatalf("create appointment: %v", err)
+	}
+
+	n := &fakeNotifier{}
+	w := &Worker{
+		Store:    stores.Reminders,
+		Notifier: n,
+		Lead:     24 * time.Hour,
+		Infof:    t.Logf,
+		Errorf:   t.Logf,
+	}
+	return w, n
+}
+
+func TestRunOnceSendsEachReminderOnce(t *testing.T) {
+	now := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	w, n := newWorker(t, now.Add(3*time.Hour))
+	ctx := context.Background()
+
+	sent, err := w.RunOnce(ctx, now)
+	if err != nil || sent != 1 {
+		t.Fatalf("RunOnce = %d, %v; want 1, nil", sent, err)
+	}
+	if len(n.sent) != 1 {
+		t.Fatalf("sent %d messages, want 1", len(n.sent))
+	}
+	msg := n.sent[0]
+	if msg.To != "ann@example.com" || !strings.Contains(msg.Subject, "Rex") || !strings.Contains(msg.Body, "Checkup") {
+		t.Errorf("unexpected message %+v", msg)
+	}
+
+	// The reminder is marked sent, so neither a later run nor one after the claim TTL sends it again
+	for _, at := range []time.Time{now.Add(time.Minute), now.Add(store.ReminderClaimTTL + time.Minute)} {
+		if sent, err := w.RunOnce(ctx, at); err != nil || sent != 0 {
+			t.Errorf("RunOnce at %s = %d, %v; want 0, nil", at, sent, err)
+		}
+	}
+	if len(n.sent) != 1 {
+		t.Errorf("sent %d messages in total, want 1", len(n.sent))
+	}
+}
+
+func TestRunOnceReleasesFailedSends(t *testing.T) {
+	now := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	w, n := newWorker(t, now.Add(3*time.Hour))
+	ctx := context.Background()
+
+	n.err = errors.New("connection refused")
+	if sent, err := w.RunOnce(ctx, now); err != nil || sent != 0 {
+		t.Fatalf("RunOnce with a failing notifier = %d, %v; want 0, nil", sent, err)
+	}
+
+	// The failed send released its claim, so the next run retries straight away
+	n.err = nil
+	if sent, err := w.RunOnce(ctx, now.Add(time.Minute)); err != nil || sent != 1 {
+		t.Fatalf("retry RunOnce = %d, %v; want 1, nil", sent, err)
+	}
+	if sent, err := w.RunOnce(ctx, now.Add(2*time.Minute)); err != nil || sent != 0 {
+		t.Errorf("RunOnce after the retry = %d, %v; want 0, nil", sent, err)
+	}
+	if len(n.sent) != 1 {
+		t.Errorf("sent %d messages, want 1", len(n.sent))
+	}
+}
+
+func TestRunOnceSkipsClaimedReminders(t *testing.T) {
+	now := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	w, n := newWorker(t, now.Add(3*time.Hour))
+	ctx := context.Background()
+
+	// Another instance claims the reminder and dies before marking it sent
+	due, err := w.Store.Due(ctx, now, now.Add(w.Lead), batchSize)
+	if err != nil || len(due) != 1 {
+		t.Fatalf("Due = %v, %v; want one reminder", due, err)
+	}
+	if claimed, err := w.Store.Claim(ctx, due[0], now); err != nil || !claimed {
+		t.Fatalf("Claim = %v, %v; want true, nil", claimed, err)
+	}
+
+	if sent, err := w.RunOnce(ctx, now.Add(time.Minute)); err != nil || sent != 0 {
+		t.Errorf("RunOnce while claimed = %d, %v; want 0, nil", sent, err)
+	}
+	// Once the claim is stale the reminder is sent after all
+	if sent, err := w.RunOnce(ctx, now.Add(store.ReminderClaimTTL+time.Minute)); err != nil || sent != 1 {
+		t.Errorf("RunOnce after the claim TTL = %d, %v; want 1, nil", sent, err)
+	}
+	if len(n.sent) != 1 {
+		t.Errorf("sent %d messages, want 1", len(n.sent))
+	}
+}
+
+func TestRunOnceWaitsForLead(t *testing.T) {
+	now := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	w, n := newWorker(t, now.Add(48*time.Hour))
+
+	if sent, err := w.RunOnce(context.Background(), now); err != nil || sent != 0 {
+		t.Errorf("RunOnce = %d, %v; want 0, nil", sent, err)
+	}
+	if sent, err := w.RunOnce(context.Background(), now.Add(25*time.Hour)); err != nil || sent != 1 {
+		t.Errorf("RunOnce within the lead = %d, %v; want 1, nil", sent, err)
+	}
+	if len(n.sent) != 1 {
+		t.Errorf("sent %d messages, want 1", len(n.sent))
+	}
+}
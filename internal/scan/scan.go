@@ -0,0 +1,157 @@
+// Package scan checks uploaded files for malware. The API stores a file first and scans it
+// afterwards, so a Scanner only sees a stream of bytes; ClamAV's clamd is the implementation used.
+package scan
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// Result is the verdict on one file
+type Result struct {
+	Infected  bool
+	Signature string // name of the matched signature when infected
+}
+
+// Scanner checks the contents of r. An error means no verdict was reached and the
+// file should be scanned again later, unless it is a *RefusedError.
+type Scanner interface {
+	Scan(ctx context.Context, r io.Reader) (Result, error)
+}
+
+// RefusedError reports a file the scanner will not scan, such as one larger than clamd's
+// StreamMaxLength; scanning it again would give the same answer
+type RefusedError struct {
+	Reason string
+}
+
+func (e *RefusedError) Error() string { return "clamd: " + e.Reason }
+
+// refusals are the clamd errors about the file itself rather than the daemon's state
+var refusals = []string{"size limit exceeded"}
+
+// Clamd scans with a ClamAV daemon using its INSTREAM command. Files larger than clamd's
+// StreamMaxLength are refused by the daemon, so it should be at least the upload limit.
+type Clamd struct {
+	Network string        // "tcp" or "unix"
+	Address string        // host:port, or the socket path
+	Timeout time.Duration // per file, default 5 minutes
+}
+
+// chunkSize is the size of the chunks streamed to clamd
+const chunkSize = 64 << 10
+
+func (c Clamd) Scan(ctx context.Context, r io.Reader) (Result, error) {
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = 5 * time.Minute
+	}
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+
+	dialer := net.Dialer{Deadline: deadline}
+	conn, err := dialer.DialContext(ctx, c.Network, c.Address)
+	if err != nil {
+		return Result{}, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(deadline)
+
+	sendErr := sendStream(conn, r)
+	reply, readErr := bufio.NewReader(conn).ReadString(0)
+	if sendErr != nil {
+		// clamd answers and hangs up as soon as it refuses a stream, which breaks the
+		// remaining writes; its reply explains why better than the write error does.
+		// A stream that was not sent completely is never reported clean.
+		if _, err := parseReply(reply); err != nil && reply != "" {
+			return Result{}, err
+		}
+		return Result{}, sendErr
+	}
+	if readErr != nil && (readErr != io.EOF || reply == "") {
+		return Result{}, fmt.Errorf("clamd: reading reply: %w", readErr)
+	}
+	return parseReply(reply)
+}
+
+// sendStream writes the INSTREAM command followed by r as length-prefixed chunks and the
+// zero-length chunk that ends the stream
+func sendStream(conn net.Conn, r io.Reader) error {
+	if _, err := io.WriteString(conn, "zINSTREAM\x00"); err != nil {
+		return err
+	}
+	buf := make([]byte, 4+chunkSize)
+	for {
+		n, err := io.ReadFull(r, buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf[:4], uint32(n))
+			if _, err := conn.Write(buf[:4+n]); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("clamd: reading file: %w", err)
+		}
+	}
+	_, err := conn.Write([]byte{0, 0, 0, 0})
+	return err
+}
+
+// parseReply interprets replies such as "stream: OK", "stream: Eicar-Signature FOUND"
+// and "INSTREAM size limit exceeded. ERROR"
+func parseReply(reply string) (Result, error) {
+	reply = strings.TrimSpace(strings.TrimSuffix(reply, "\x00"))
+	verdict := strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case verdict == "OK":
+		return Result{}, nil
+	case strings.HasSuffix(verdict, " FOUND"):
+		return Result{Infected: true, Signature: strings.TrimSuffix(verdict, " FOUND")}, nil
+	case strings.HasSuffix(verdict, " ERROR"):
+		reason := strings.TrimSuffix(verdict, " ERROR")
+		for _, refusal := range refusals {
+			if strings.Contains(reason, refusal) {
+				return Result{}, &RefusedError{Reason: strings.TrimSuffix(reason, ".")}
+			}
+		}
+		return Result{}, errors.New("clamd: " + reason)
+	default:
+		return Result{}, fmt.Errorf("clamd: unexpected reply %q", reply)
+	}
+}
+
+// ParseClamdAddr splits addresses such as "tcp://localhost:3310", "localhost:3310" and
+// "unix:///var/run/clamav/clamd.ctl" into a network and an address for Clamd
+func ParseClamdAddr(addr string) (network, address string, err error) {
+	switch {
+	case strings.HasPrefix(addr, "unix://"):
+		network, address = "unix", strings.TrimPrefix(addr, "unix://")
+	case strings.HasPrefix(addr, "tcp://"):
+		network, address = "tcp", strings.TrimPrefix(addr, "tcp://")
+	case strings.HasPrefix(addr, "/"):
+		network, address = "unix", addr
+	default:
+		network, address = "tcp", addr
+	}
+	if network == "tcp" {
+		if _, _, err := net.SplitHostPort(address); err != nil {
+			return "", "", fmt.Errorf("invalid clamd address %q: %v", addr, err)
+		}
+	}
+	if address == "" {
+		return "", "", fmt.Errorf("invalid clamd address %q", addr)
+	}
+	return network, address, nil
+}
@@ -0,0 +1,208 @@
+// Package scheduling turns weekly working hours and existing bookings into
+// concrete working periods and free appointment slots.
+//
+// All calculations happen in the clinic's time zone so that "09:00" stays
+// 09:00 local time across daylight saving changes.
+package scheduling
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"pets_project/internal/models"
+)
+
+// DefaultStep is the spacing between the start times of offered slots
+const DefaultStep = 15 * time.Minute
+
+// Interval is a half-open period [Start, End)
+type Interval struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// ParseClock parses an "HH:MM" wall clock time into minutes after midnight.
+// "24:00" is accepted as the end of the day.
+func ParseClock(s string) (int, error) {
+	var h, m int
+	if len(s) != 5 || s[2] != ':' {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	if _, err := fmt.Sscanf(s, "%02d:%02d", &h, &m); err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	if h < 0 || m < 0 || m > 59 || h > 24 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	return h*60 + m, nil
+}
+
+// ValidateHours checks that every period has a valid weekday and ends after it starts
+func ValidateHours(hours []models.WorkingHours) error {
+	for _, h := range hours {
+		if h.Weekday < 0 || h.Weekday > 6 {
+			return fmt.Errorf("weekday must be between 0 (Sunday) and 6 (Saturday)")
+		}
+		start, err := ParseClock(h.Start)
+		if err != nil {
+			return err
+		}
+		end, err := ParseClock(h.End)
+		if err != nil {
+			return err
+		}
+		if end <= start {
+			return fmt.Errorf("working hours %s-%s end before they start", h.Start, h.End)
+		}
+	}
+	return nil
+}
+
+// Day returns midnight at the start of date (YYYY-MM-DD) in loc
+func Day(date string, loc *time.Location) (time.Time, error) {
+	return time.ParseInLocation("2006-01-02", date, loc)
+}
+
+// at returns the wall clock time minutes after midnight on day, in day's location
+func at(day time.Time, minutes int) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), 0, minutes, 0, 0, day.Location())
+}
+
+// WorkingIntervals returns the periods of day (midnight in the clinic zone) covered by
+// the weekly hours, merged and in order. Invalid entries are skipped.
+func WorkingIntervals(day time.Time, hours []models.WorkingHours) []Interval {
+	var out []Interval
+	for _, h := range hours {
+		if time.Weekday(h.Weekday) != day.Weekday() {
+			continue
+		}
+		start, err1 := ParseClock(h.Start)
+		end, err2 := ParseClock(h.End)
+		if err1 != nil || err2 != nil || end <= start {
+			continue
+		}
+		out = append(out, Interval{Start: at(day, start), End: at(day, end)})
+	}
+	return Merge(out)
+}
+
+// Merge sorts intervals and joins those that overlap or touch
+func Merge(in []Interval) []Interval {
+	if len(in) == 0 {
+		return nil
+	}
+	sorted := append([]Interval(nil), in...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start.Before(sorted[j].Start) })
+
+	out := []Interval{sorted[0]}
+	for _, iv := range sorted[1:] {
+		last := &out[len(out)-1]
+		if !iv.Start.After(last.End) {
+			if iv.End.After(last.End) {
+				last.End = iv.End
+			}
+			continue
+		}
+		out = append(out, iv)
+	}
+	return out
+}
+
+// Intersect returns the periods covered by both a and b
+func Intersect(a, b []Interval) []Interval {
+	a, b = Merge(a), Merge(b)
+	var out []Interval
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		start, end := later(a[i].Start, b[j].Start), earlier(a[i].End, b[j].End)
+		if start.Before(end) {
+			out = append(out, Interval{Start: start, End: end})
+		}
+		if a[i].End.Before(b[j].End) {
+			i++
+		} else {
+			j++
+		}
+	}
+	return out
+}
+
+// Subtract removes the busy periods from free
+func Subtract(free, busy []Interval) []Interval {
+	busy = Merge(busy)
+	var out []Interval
+	for _, f := range Merge(free) {
+		cur := f.Start
+		for _, b := range busy {
+			if !b.End.After(cur) || !b.Start.Before(f.End) {
+				continue
+			}
+			if b.Start.After(cur) {
+				out = append(out, Interval{Start: cur, End: b.Start})
+			}
+			cur = later(cur, b.End)
+		}
+		if cur.Before(f.End) {
+			out = append(out, Interval{Start: cur, End: f.End})
+		}
+	}
+	return out
+}
+
+// Slots lists every period of the given length that fits inside free, starting on
+// multiples of step (counted from the hour, e.g. :00, :15, :30, :45 for 15 minutes)
+func Slots(free []Interval, length, step time.Duration) []Interval {
+	if step <= 0 {
+		step = DefaultStep
+	}
+	var out []Interval
+	for _, f := range Merge(free) {
+		start := alignUp(f.Start, step)
+		for !start.Add(length).After(f.End) {
+			out = append(out, Interval{Start: start, End: start.Add(length)})
+			start = start.Add(step)
+		}
+	}
+	return out
+}
+
+// alignUp rounds t up to the next multiple of step past the hour in t's location
+func alignUp(t time.Time, step time.Duration) time.Time {
+	hour := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+	offset := t.Sub(hour)
+	if rem := offset % step; rem != 0 {
+		offset += step - rem
+	}
+	return hour.Add(offset)
+}
+
+func later(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func earlier(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+// Calendar gathers what limits the bookings of one staff member
+type Calendar struct {
+	ClinicHours []models.WorkingHours // empty when the clinic has no opening hours set
+	StaffHours  []models.WorkingHours
+	Busy        []Interval // appointments, blocked periods and time already past
+}
+
+// Open returns the periods of day (midnight in the clinic zone) inside both the
+// opening hours and the staff member's working hours that are not busy
+func (c Calendar) Open(day time.Time) []Interval {
+	open := WorkingIntervals(day, c.StaffHours)
+	if len(c.ClinicHours) > 0 {
+		open = Intersect(open, WorkingIntervals(day, c.ClinicHours))
+	}
+	return Subtract(open, c.Busy)
+}
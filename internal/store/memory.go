@@ -0,0 +1,244 @@
+package store
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"pets_project/internal/models"
+)
+
+// NewMemory returns stores that keep everything in process memory.
+// They mirror the Postgres behaviour (tenant scoping, cascades, uniqueness) so
+// handlers can be exercised with httptest alone. A default clinic with ID 1 exists.
+func NewMemory() Stores {
+	m := &memoryDB{
+		nextID:        map[string]int{},
+		clinics:       map[int]models.Clinic{},
+		users:         map[int]models.User{},
+		sessions:      map[string]*memSession{},
+		refreshTokens: map[string]*memRefreshToken{},
+		feedTokens:    map[int]string{},
+		owners:        map[int]memOwner{},
+		pets:          map[int]memPet{},
+		appointments:  map[int]memAppointment{},
+		files:         map[int]memFile{},
+		uploads:       map[string]memUpload{},
+		usedLinks:     map[string]time.Time{},
+		medical:       map[int]memMedicalEntry{},
+		vaccinations:  map[int]memVaccination{},
+		staff:         map[int]memStaff{},
+		types:         map[int]memAppointmentType{},
+		clinicHours:   map[int][]models.WorkingHours{},
+		blocked:       map[int]memBlockedPeriod{},
+		statusEvents:  map[int][]models.AppointmentStatusEvent{},
+		reminders:     map[reminderKey]*memReminder{},
+		waitlist:      map[int]memWaitlistEntry{},
+	}
+	m.clinics[m.newID("clinics")] = models.Clinic{ID: 1, Name: "Main Clinic", Timezone: "UTC"}
+
+	return Stores{
+		Pets:         &memPetStore{m},
+		Owners:       &memOwnerStore{m},
+		Appointments: &memAppointmentStore{m},
+		Files:        &memFileRecordStore{m},
+		Uploads:      &memUploadStore{m},
+		Links:        &memDownloadLinkStore{m},
+		Medical:      &memMedicalEntryStore{m},
+		Vaccinations: &memVaccinationStore{m},
+		Staff:        &memStaffStore{m},
+		Types:        &memAppointmentTypeStore{m},
+		Blocked:      &memBlockedPeriodStore{m},
+		Reminders:    &memReminderStore{m},
+		Waitlist:     &memWaitlistStore{m},
+		Users:        &memUserStore{m},
+		Sessions:     &memSessionStore{m},
+		Clinics:      &memClinicStore{m},
+	}
+}
+
+// memoryDB holds every table behind a single lock
+type memoryDB struct {
+	mu     sync.Mutex
+	nextID map[string]int
+
+	clinics       map[int]models.Clinic
+	users         map[int]models.User
+	sessions      map[string]*memSession
+	refreshTokens map[string]*memRefreshToken // keyed by token hash
+	feedTokens    map[int]string              // calendar feed token hash keyed by user ID
+	owners        map[int]memOwner
+	pets          map[int]memPet
+	appointments  map[int]memAppointment
+	files         map[int]memFile
+	uploads       map[string]memUpload
+	usedLinks     map[string]time.Time // expiry of used single-use download links, keyed by nonce
+	medical       map[int]memMedicalEntry
+	vaccinations  map[int]memVaccination
+	staff         map[int]memStaff
+	types         map[int]memAppointmentType
+	clinicHours   map[int][]models.WorkingHours // keyed by clinic ID
+	blocked       map[int]memBlockedPeriod
+	statusEvents  map[int][]models.AppointmentStatusEvent // keyed by appointment ID
+	reminders     map[reminderKey]*memReminder
+	waitlist      map[int]memWaitlistEntry
+}
+
+// Rows that carry the clinic_id column the models do not expose
+type memOwner struct {
+	models.Owner
+	clinicID int
+}
+
+type memPet struct {
+	models.Pet
+	clinicID int
+}
+
+type memAppointment struct {
+	models.Appointment
+	clinicID  int
+	revision  int // bumped on every change, like the appointments_revise trigger does
+	updatedAt time.Time
+}
+
+// revised returns the appointment changed to a, with its revision bumped
+func (old memAppointment) revised(a models.Appointment) memAppointment {
+	return memAppointment{Appointment: a, clinicID: old.clinicID, revision: old.revision + 1, updatedAt: time.Now()}
+}
+
+type memFile struct {
+	models.FileRecord
+	clinicID        int
+	scanAttemptedAt time.Time // zero until PendingScans returns the file
+}
+
+type memUpload struct {
+	models.Upload
+	clinicID    int
+	finishingAt time.Time // when a request claimed the upload to finish it, or zero
+}
+
+type memMedicalEntry struct {
+	models.MedicalEntry
+	clinicID int
+}
+
+type memVaccination struct {
+	models.Vaccination
+	clinicID int
+}
+
+type memStaff struct {
+	models.Staff
+	clinicID int
+}
+
+type memAppointmentType struct {
+	models.AppointmentType
+	clinicID int
+}
+
+type memBlockedPeriod struct {
+	models.BlockedPeriod
+	clinicID int
+}
+
+type memWaitlistEntry struct {
+	models.WaitlistEntry
+	clinicID int
+}
+
+type memSession struct {
+	userID  int
+	revoked bool
+}
+
+type memRefreshToken struct {
+	sessionID string
+	expiresAt time.Time
+	used      bool
+}
+
+// newID returns the next serial value for a table; callers hold m.mu
+func (m *memoryDB) newID(table string) int {
+	m.nextID[table]++
+	return m.nextID[table]
+}
+
+// sortedIDs returns map keys in ascending order so listings are deterministic
+func sortedIDs[T any](rows map[int]T) []int {
+	ids := make([]int, 0, len(rows))
+	for id := range rows {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// Visibility rules matching the WHERE clauses of the Postgres store; callers hold m.mu
+func (m *memoryDB) ownerVisible(scope Scope, o memOwner) bool {
+	return o.clinicID == scope.ClinicID && (!scope.OwnerOnly || o.ID == scope.OwnerID)
+}
+
+func (m *memoryDB) petVisible(scope Scope, p memPet) bool {
+	return p.clinicID == scope.ClinicID && (!scope.OwnerOnly || p.OwnerID == scope.OwnerID)
+}
+
+func (m *memoryDB) petIDVisible(scope Scope, petID int) bool {
+	p, ok := m.pets[petID]
+	return ok && m.petVisible(scope, p)
+}
+
+// deleteAppointmentLocked removes an appointment, its status events and reminders (ON DELETE CASCADE)
+// and unlinks waitlist entries booked into it (ON DELETE SET NULL)
+func (m *memoryDB) deleteAppointmentLocked(id int) {
+	delete(m.appointments, id)
+	delete(m.statusEvents, id)
+	for key := range m.reminders {
+		if key.appointmentID == id {
+			delete(m.reminders, key)
+		}
+	}
+	for wid, w := range m.waitlist {
+		if w.AppointmentID == id {
+			w.AppointmentID = 0
+			m.waitlist[wid] = w
+		}
+	}
+}
+
+// deletePetLocked removes a pet and the rows that reference it (ON DELETE CASCADE)
+func (m *memoryDB) deletePetLocked(id int) {
+	delete(m.pets, id)
+	for aid, a := range m.appointments {
+		if a.PetID == id {
+			m.deleteAppointmentLocked(aid)
+		}
+	}
+	for fid, f := range m.files {
+		if f.PetID == id {
+			delete(m.files, fid)
+		}
+	}
+	for uid, u := range m.uploads {
+		if u.PetID == id {
+			delete(m.uploads, uid)
+		}
+	}
+	for eid, e := range m.medical {
+		if e.PetID == id {
+			delete(m.medical, eid)
+		}
+	}
+	for vid, v := range m.vaccinations {
+		if v.PetID == id {
+			delete(m.vaccinations, vid)
+		}
+	}
+	for wid, w := range m.waitlist {
+		if w.PetID == id {
+			delete(m.waitlist, wid)
+		}
+	}
+}
@@ -0,0 +1,316 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"pets_project/internal/models"
+)
+
+// === Appointments ==================================================================
+type memAppointmentStore struct{ m *memoryDB }
+
+func (s *memAppointmentStore) visible(scope Scope, a memAppointment) bool {
+	return a.clinicID == scope.ClinicID && (!scope.OwnerOnly || s.m.petIDVisible(scope, a.PetID))
+}
+
+// matchesLocked reports whether the appointment is visible and passes the filter; callers hold m.mu
+func (s *memAppointmentStore) matchesLocked(scope Scope, filter AppointmentFilter, a memAppointment) bool {
+	start, _ := appointmentSpan(a.Appointment)
+	return s.visible(scope, a) &&
+		(filter.PetID == 0 || a.PetID == filter.PetID) &&
+		(filter.VetID == 0 || a.VetID == filter.VetID) &&
+		(filter.OwnerID == 0 || s.m.pets[a.PetID].OwnerID == filter.OwnerID) &&
+		(filter.SeriesID == 0 || a.SeriesID == filter.SeriesID) &&
+		(filter.Statuses == nil || containsString(filter.Statuses, a.Status)) &&
+		(filter.From.IsZero() || !start.Before(filter.From)) &&
+		(filter.To.IsZero() || start.Before(filter.To))
+}
+
+// appointmentActive reports whether an appointment occupies its time slot
+func appointmentActive(a models.Appointment) bool {
+	return a.Status != models.AppointmentCancelled && a.Status != models.AppointmentNoShow
+}
+
+// appointmentSpan returns the start and end of an appointment stored by this package
+func appointmentSpan(a models.Appointment) (time.Time, time.Time) {
+	start, _ := time.Parse(time.RFC3339, a.StartsAt)
+	return start, start.Add(time.Duration(a.DurationMinutes) * time.Minute)
+}
+
+func (s *memAppointmentStore) List(ctx context.Context, scope Scope, filter AppointmentFilter, page Page) ([]models.Appointment, string, error) {
+	k, err := resolvePage(page, appointmentSortFields)
+	if err != nil {
+		return nil, "", err
+	}
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	appointments := []models.Appointment{}
+	for _, a := range s.m.appointments {
+		if s.matchesLocked(scope, filter, a) {
+			appointments = append(appointments, a.Appointment)
+		}
+	}
+	appointments, next := paginate(k, appointments, appointmentSortKey(k.field))
+	return appointments, next, nil
+}
+
+func (s *memAppointmentStore) Get(ctx context.Context, scope Scope, id int) (models.Appointment, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	a, ok := s.m.appointments[id]
+	if !ok || !s.visible(scope, a) {
+		return models.Appointment{}, ErrNotFound
+	}
+	return a.Appointment, nil
+}
+
+// checkBookingLocked mirrors lockForBooking and findConflict of the Postgres store and
+// normalises a.StartsAt. Appointments in exclude are not conflicts; callers hold m.mu.
+func (s *memAppointmentStore) checkBookingLocked(scope Scope, a *models.Appointment, exclude map[int]bool) error {
+	start, err := time.Parse(time.RFC3339, a.StartsAt)
+	if err != nil {
+		return err
+	}
+	if !s.m.petIDVisible(scope, a.PetID) {
+		return &ReferenceError{Entity: "pet", ID: a.PetID}
+	}
+	if a.VetID != 0 {
+		if st, ok := s.m.staff[a.VetID]; !ok || st.clinicID != scope.ClinicID || st.Role != models.StaffVet {
+			return &ReferenceError{Entity: "vet", ID: a.VetID}
+		}
+	}
+	a.StartsAt = start.UTC().Format(time.RFC3339)
+
+	_, end := appointmentSpan(*a)
+	var clash *models.Appointment
+	var clashStart time.Time
+	for _, id := range sortedIDs(s.m.appointments) {
+		other := s.m.appointments[id]
+		if other.clinicID != scope.ClinicID || other.ID == a.ID || exclude[other.ID] || !appointmentActive(other.Appointment) ||
+			(other.PetID != a.PetID && (a.VetID == 0 || other.VetID != a.VetID)) {
+			continue
+		}
+		otherStart, otherEnd := appointmentSpan(other.Appointment)
+		if otherStart.Before(end) && otherEnd.After(start) {
+			if clash == nil || otherStart.Before(clashStart) {
+				clash, clashStart = &other.Appointment, otherStart
+			}
+		}
+	}
+	if clash == nil {
+		return nil
+	}
+	with := "pet"
+	if clash.PetID != a.PetID {
+		with = "vet"
+	}
+	return &ConflictError{With: with, Appointment: *clash}
+}
+
+func (s *memAppointmentStore) Create(ctx context.Context, scope Scope, a *models.Appointment) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	if err := s.checkBookingLocked(scope, a, nil); err != nil {
+		return err
+	}
+	a.ID = s.m.newID("appointments")
+	a.SeriesID = 0
+	a.Status = models.AppointmentScheduled
+	s.m.appointments[a.ID] = memAppointment{Appointment: *a, clinicID: scope.ClinicID, updatedAt: time.Now()}
+	return nil
+}
+
+func (s *memAppointmentStore) Update(ctx context.Context, scope Scope, a *models.Appointment) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	if err := s.checkBookingLocked(scope, a, nil); err != nil {
+		return err
+	}
+	existing, ok := s.m.appointments[a.ID]
+	if !ok || !s.visible(scope, existing) {
+		return ErrNotFound
+	}
+	a.SeriesID, a.Status = existing.SeriesID, existing.Status
+	s.m.appointments[a.ID] = existing.revised(*a)
+	return nil
+}
+
+func (s *memAppointmentStore) Delete(ctx context.Context, scope Scope, id int) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	a, ok := s.m.appointments[id]
+	if !ok || !s.visible(scope, a) {
+		return ErrNotFound
+	}
+	s.m.deleteAppointmentLocked(id)
+	return nil
+}
+
+func (s *memAppointmentStore) CreateSeries(ctx context.Context, scope Scope, series *models.AppointmentSeries) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	// Occurrences are added one by one so later ones are checked against earlier ones,
+	// and removed again if any of them clashes
+	var added []int
+	seriesID := s.m.newID("appointment_series")
+	for i := range series.Appointments {
+		a := &series.Appointments[i]
+		a.ID = 0
+		if err := s.checkBookingLocked(scope, a, nil); err != nil {
+			for _, id := range added {
+				s.m.deleteAppointmentLocked(id)
+			}
+			return err
+		}
+		a.ID = s.m.newID("appointments")
+		a.SeriesID = seriesID
+		a.Status = models.AppointmentScheduled
+		s.m.appointments[a.ID] = memAppointment{Appointment: *a, clinicID: scope.ClinicID, updatedAt: time.Now()}
+		added = append(added, a.ID)
+	}
+	series.ID = seriesID
+	return nil
+}
+
+func (s *memAppointmentStore) Following(ctx context.Context, scope Scope, id int) ([]models.Appointment, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	a, ok := s.m.appointments[id]
+	if !ok || !s.visible(scope, a) {
+		return nil, ErrNotFound
+	}
+	if a.SeriesID == 0 {
+		return []models.Appointment{a.Appointment}, nil
+	}
+	key := sortableTime(a.StartsAt)
+	appointments := []models.Appointment{}
+	for _, other := range s.m.appointments {
+		otherKey := sortableTime(other.StartsAt)
+		if other.SeriesID == a.SeriesID && s.visible(scope, other) && (otherKey > key || (otherKey == key && other.ID >= a.ID)) {
+			appointments = append(appointments, other.Appointment)
+		}
+	}
+	sort.Slice(appointments, func(i, j int) bool {
+		ki, kj := sortableTime(appointments[i].StartsAt), sortableTime(appointments[j].StartsAt)
+		return ki < kj || (ki == kj && appointments[i].ID < appointments[j].ID)
+	})
+	return appointments, nil
+}
+
+func (s *memAppointmentStore) UpdateMany(ctx context.Context, scope Scope, appointments []models.Appointment) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	exclude := map[int]bool{}
+	for _, a := range appointments {
+		exclude[a.ID] = true
+	}
+	for i := range appointments {
+		a := &appointments[i]
+		existing, ok := s.m.appointments[a.ID]
+		if !ok || !s.visible(scope, existing) {
+			return ErrNotFound
+		}
+		if err := s.checkBookingLocked(scope, a, exclude); err != nil {
+			return err
+		}
+		a.SeriesID, a.Status = existing.SeriesID, existing.Status
+	}
+	for _, a := range appointments {
+		s.m.appointments[a.ID] = s.m.appointments[a.ID].revised(a)
+	}
+	return nil
+}
+
+func (s *memAppointmentStore) DeleteMany(ctx context.Context, scope Scope, ids []int) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	for _, id := range ids {
+		if a, ok := s.m.appointments[id]; !ok || !s.visible(scope, a) {
+			return ErrNotFound
+		}
+	}
+	for _, id := range ids {
+		s.m.deleteAppointmentLocked(id)
+	}
+	return nil
+}
+
+func (s *memAppointmentStore) Transition(ctx context.Context, scope Scope, ids []int, from []string, ev models.AppointmentStatusEvent) ([]models.Appointment, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	for _, id := range ids {
+		a, ok := s.m.appointments[id]
+		if !ok || !s.visible(scope, a) {
+			return nil, ErrNotFound
+		}
+		if !containsString(from, a.Status) {
+			return nil, &TransitionError{AppointmentID: id, From: a.Status, To: ev.ToStatus}
+		}
+	}
+	appointments := make([]models.Appointment, 0, len(ids))
+	now := time.Now().UTC().Format(time.RFC3339)
+	for _, id := range ids {
+		a := s.m.appointments[id]
+		event := ev
+		event.ID = s.m.newID("appointment_status_events")
+		event.AppointmentID, event.FromStatus, event.CreatedAt = id, a.Status, now
+		s.m.statusEvents[id] = append(s.m.statusEvents[id], event)
+		a.Status = ev.ToStatus
+		s.m.appointments[id] = a.revised(a.Appointment)
+		appointments = append(appointments, a.Appointment)
+	}
+	return appointments, nil
+}
+
+func (s *memAppointmentStore) History(ctx context.Context, scope Scope, id int) ([]models.AppointmentStatusEvent, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	a, ok := s.m.appointments[id]
+	if !ok || !s.visible(scope, a) {
+		return nil, ErrNotFound
+	}
+	return append([]models.AppointmentStatusEvent{}, s.m.statusEvents[id]...), nil
+}
+
+func (s *memAppointmentStore) ListForVet(ctx context.Context, clinicID int, vetID int, from, to time.Time) ([]models.Appointment, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	appointments := []models.Appointment{}
+	for _, a := range s.m.appointments {
+		start, end := appointmentSpan(a.Appointment)
+		if a.clinicID == clinicID && a.VetID == vetID && appointmentActive(a.Appointment) && start.Before(to) && end.After(from) {
+			appointments = append(appointments, a.Appointment)
+		}
+	}
+	sort.Slice(appointments, func(i, j int) bool {
+		return sortableTime(appointments[i].StartsAt) < sortableTime(appointments[j].StartsAt)
+	})
+	return appointments, nil
+}
+
+func (s *memAppointmentStore) Calendar(ctx context.Context, scope Scope, filter AppointmentFilter) ([]models.CalendarEntry, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	entries := []models.CalendarEntry{}
+	for _, a := range s.m.appointments {
+		if !s.matchesLocked(scope, filter, a) {
+			continue
+		}
+		pet := s.m.pets[a.PetID]
+		entries = append(entries, models.CalendarEntry{
+			Appointment: a.Appointment,
+			PetName:     pet.Name,
+			OwnerName:   s.m.owners[pet.OwnerID].Name,
+			VetName:     s.m.staff[a.VetID].Name,
+			Revision:    a.revision,
+			UpdatedAt:   a.updatedAt.UTC().Format(time.RFC3339),
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		ki, kj := sortableTime(entries[i].StartsAt), sortableTime(entries[j].StartsAt)
+		return ki < kj || (ki == kj && entries[i].ID < entries[j].ID)
+	})
+	return entries, nil
+}
@@ -0,0 +1,126 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"pets_project/internal/models"
+)
+
+// === Appointment Types =============================================================
+type memAppointmentTypeStore struct{ m *memoryDB }
+
+func (s *memAppointmentTypeStore) List(ctx context.Context, clinicID int) ([]models.AppointmentType, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	types := []models.AppointmentType{}
+	for _, id := range sortedIDs(s.m.types) {
+		if t := s.m.types[id]; t.clinicID == clinicID {
+			types = append(types, t.AppointmentType)
+		}
+	}
+	sort.SliceStable(types, func(i, j int) bool { return types[i].Name < types[j].Name })
+	return types, nil
+}
+
+func (s *memAppointmentTypeStore) Get(ctx context.Context, clinicID int, id int) (models.AppointmentType, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	t, ok := s.m.types[id]
+	if !ok || t.clinicID != clinicID {
+		return models.AppointmentType{}, ErrNotFound
+	}
+	return t.AppointmentType, nil
+}
+
+// nameTakenLocked emulates UNIQUE (clinic_id, name); callers hold m.mu
+func (s *memAppointmentTypeStore) nameTakenLocked(clinicID int, t *models.AppointmentType) bool {
+	for _, other := range s.m.types {
+		if other.clinicID == clinicID && other.Name == t.Name && other.ID != t.ID {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *memAppointmentTypeStore) Create(ctx context.Context, clinicID int, t *models.AppointmentType) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	if s.nameTakenLocked(clinicID, t) {
+		return ErrConflict
+	}
+	t.ID = s.m.newID("appointment_types")
+	s.m.types[t.ID] = memAppointmentType{AppointmentType: *t, clinicID: clinicID}
+	return nil
+}
+
+func (s *memAppointmentTypeStore) Update(ctx context.Context, clinicID int, t *models.AppointmentType) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	existing, ok := s.m.types[t.ID]
+	if !ok || existing.clinicID != clinicID {
+		return ErrNotFound
+	}
+	if s.nameTakenLocked(clinicID, t) {
+		return ErrConflict
+	}
+	s.m.types[t.ID] = memAppointmentType{AppointmentType: *t, clinicID: clinicID}
+	return nil
+}
+
+func (s *memAppointmentTypeStore) Delete(ctx context.Context, clinicID int, id int) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	t, ok := s.m.types[id]
+	if !ok || t.clinicID != clinicID {
+		return ErrNotFound
+	}
+	delete(s.m.types, id)
+	return nil
+}
+
+// === Blocked Periods ===============================================================
+type memBlockedPeriodStore struct{ m *memoryDB }
+
+func (s *memBlockedPeriodStore) List(ctx context.Context, clinicID int, from, to time.Time) ([]models.BlockedPeriod, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	periods := []models.BlockedPeriod{}
+	for _, id := range sortedIDs(s.m.blocked) {
+		b := s.m.blocked[id]
+		start, _ := time.Parse(time.RFC3339, b.StartsAt)
+		end, _ := time.Parse(time.RFC3339, b.EndsAt)
+		if b.clinicID == clinicID && start.Before(to) && end.After(from) {
+			periods = append(periods, b.BlockedPeriod)
+		}
+	}
+	sort.SliceStable(periods, func(i, j int) bool {
+		return sortableTime(periods[i].StartsAt) < sortableTime(periods[j].StartsAt)
+	})
+	return periods, nil
+}
+
+func (s *memBlockedPeriodStore) Create(ctx context.Context, clinicID int, b *models.BlockedPeriod) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	if b.StaffID != 0 {
+		if st, ok := s.m.staff[b.StaffID]; !ok || st.clinicID != clinicID {
+			return &ReferenceError{Entity: "staff member", ID: b.StaffID}
+		}
+	}
+	b.ID = s.m.newID("blocked_periods")
+	s.m.blocked[b.ID] = memBlockedPeriod{BlockedPeriod: *b, clinicID: clinicID}
+	return nil
+}
+
+func (s *memBlockedPeriodStore) Delete(ctx context.Context, clinicID int, id int) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	b, ok := s.m.blocked[id]
+	if !ok || b.clinicID != clinicID {
+		return ErrNotFound
+	}
+	delete(s.m.blocked, id)
+	return nil
+}
@@ -0,0 +1,152 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"pets_project/internal/models"
+)
+
+// === File records ==================================================================
+type memFileRecordStore struct{ m *memoryDB }
+
+func (s *memFileRecordStore) visible(scope Scope, f memFile) bool {
+	return f.clinicID == scope.ClinicID && (!scope.OwnerOnly || s.m.petIDVisible(scope, f.PetID))
+}
+
+func (s *memFileRecordStore) ListByPet(ctx context.Context, scope Scope, petID int) ([]models.FileRecord, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	files := []models.FileRecord{}
+	for _, id := range sortedIDs(s.m.files) {
+		if f := s.m.files[id]; f.PetID == petID && s.visible(scope, f) && s.m.latestVersion(f.DocumentID) == f.Version {
+			files = append(files, f.FileRecord)
+		}
+	}
+	return files, nil
+}
+
+func (s *memFileRecordStore) Get(ctx context.Context, scope Scope, id int) (models.FileRecord, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	f, ok := s.m.files[id]
+	if !ok || !s.visible(scope, f) {
+		return models.FileRecord{}, ErrNotFound
+	}
+	return f.FileRecord, nil
+}
+
+func (s *memFileRecordStore) Create(ctx context.Context, scope Scope, f *models.FileRecord) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	if !s.m.petIDVisible(scope, f.PetID) {
+		return &ReferenceError{Entity: "pet", ID: f.PetID}
+	}
+	version := 1
+	if f.DocumentID != 0 {
+		latest := 0
+		for _, other := range s.m.files {
+			if other.DocumentID == f.DocumentID && other.PetID == f.PetID && other.clinicID == scope.ClinicID {
+				latest = max(latest, other.Version)
+			}
+		}
+		if latest == 0 {
+			return &ReferenceError{Entity: "document", ID: f.DocumentID}
+		}
+		version = latest + 1
+	}
+	f.ID = s.m.newID("file_records")
+	if f.DocumentID == 0 {
+		f.DocumentID = f.ID
+	}
+	f.Version = version
+	f.UploadedAt = time.Now().UTC().Format(time.RFC3339)
+	if f.ScanStatus == "" {
+		f.ScanStatus = models.FileUnscanned
+	}
+	s.m.files[f.ID] = memFile{FileRecord: *f, clinicID: scope.ClinicID}
+	return nil
+}
+
+func (s *memFileRecordStore) Versions(ctx context.Context, scope Scope, documentID int) ([]models.FileRecord, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	files := []models.FileRecord{} // later versions have higher IDs
+	for _, id := range sortedIDs(s.m.files) {
+		if f := s.m.files[id]; f.DocumentID == documentID && s.visible(scope, f) {
+			files = append(files, f.FileRecord)
+		}
+	}
+	if len(files) == 0 {
+		return nil, ErrNotFound
+	}
+	return files, nil
+}
+
+// latestVersion is the highest version of a document; the caller holds m.mu
+func (m *memoryDB) latestVersion(documentID int) int {
+	latest := 0
+	for _, f := range m.files {
+		if f.DocumentID == documentID {
+			latest = max(latest, f.Version)
+		}
+	}
+	return latest
+}
+
+func (s *memFileRecordStore) Delete(ctx context.Context, scope Scope, id int) (models.FileRecord, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	f, ok := s.m.files[id]
+	if !ok || !s.visible(scope, f) {
+		return models.FileRecord{}, ErrNotFound
+	}
+	delete(s.m.files, id)
+	for uid, u := range s.m.uploads {
+		if u.FileID == id {
+			delete(s.m.uploads, uid)
+		}
+	}
+	return f.FileRecord, nil
+}
+
+func (s *memFileRecordStore) PendingScans(ctx context.Context, before time.Time, limit int) ([]models.FileRecord, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	pending := []memFile{}
+	for _, id := range sortedIDs(s.m.files) {
+		f := s.m.files[id]
+		if uploadedAt, _ := time.Parse(time.RFC3339, f.UploadedAt); f.ScanStatus == models.FilePendingScan &&
+			uploadedAt.Before(before) && f.scanAttemptedAt.Before(before) {
+			pending = append(pending, f)
+		}
+	}
+	// Never attempted (zero) first, then least recently; the sort is stable, so by id after that
+	sort.SliceStable(pending, func(i, j int) bool { return pending[i].scanAttemptedAt.Before(pending[j].scanAttemptedAt) })
+	files := []models.FileRecord{}
+	now := time.Now()
+	for _, f := range pending {
+		if len(files) == limit {
+			break
+		}
+		f.scanAttemptedAt = now
+		s.m.files[f.ID] = f
+		files = append(files, f.FileRecord)
+	}
+	return files, nil
+}
+
+func (s *memFileRecordStore) SetScanResult(ctx context.Context, id int, status, signature string, scannedAt time.Time) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	f, ok := s.m.files[id]
+	if !ok || f.ScanStatus != models.FilePendingScan {
+		return ErrNotFound
+	}
+	f.ScanStatus = status
+	f.ScanSignature = signature
+	f.ScannedAt = scannedAt.UTC().Format(time.RFC3339)
+	s.m.files[id] = f
+	return nil
+}
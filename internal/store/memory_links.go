@@ -0,0 +1,32 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// === Download links ================================================================
+type memDownloadLinkStore struct{ m *memoryDB }
+
+func (s *memDownloadLinkStore) Use(ctx context.Context, nonce string, expiresAt time.Time) (bool, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	if _, used := s.m.usedLinks[nonce]; used {
+		return false, nil
+	}
+	s.m.usedLinks[nonce] = expiresAt
+	return true, nil
+}
+
+func (s *memDownloadLinkStore) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	count := 0
+	for nonce, expiresAt := range s.m.usedLinks {
+		if expiresAt.Before(now) {
+			delete(s.m.usedLinks, nonce)
+			count++
+		}
+	}
+	return count, nil
+}
@@ -0,0 +1,59 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"pets_project/internal/models"
+)
+
+// === Medical entries ===============================================================
+type memMedicalEntryStore struct{ m *memoryDB }
+
+func (s *memMedicalEntryStore) List(ctx context.Context, scope Scope, petID int, page Page) ([]models.MedicalEntry, string, error) {
+	k, err := resolvePage(page, medicalSortFields)
+	if err != nil {
+		return nil, "", err
+	}
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	entries := []models.MedicalEntry{}
+	for _, e := range s.m.medical {
+		if e.PetID == petID && e.clinicID == scope.ClinicID && s.m.petIDVisible(scope, e.PetID) {
+			entries = append(entries, e.MedicalEntry)
+		}
+	}
+	entries, next := paginate(k, entries, medicalSortKey(k.field))
+	return entries, next, nil
+}
+
+func (s *memMedicalEntryStore) Get(ctx context.Context, scope Scope, petID int, id int) (models.MedicalEntry, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	e, ok := s.m.medical[id]
+	if !ok || e.PetID != petID || e.clinicID != scope.ClinicID || !s.m.petIDVisible(scope, e.PetID) {
+		return models.MedicalEntry{}, ErrNotFound
+	}
+	return e.MedicalEntry, nil
+}
+
+func (s *memMedicalEntryStore) Create(ctx context.Context, scope Scope, e *models.MedicalEntry) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	if !s.m.petIDVisible(scope, e.PetID) {
+		return &ReferenceError{Entity: "pet", ID: e.PetID}
+	}
+	if e.AmendsID != 0 {
+		if amended, ok := s.m.medical[e.AmendsID]; !ok || amended.PetID != e.PetID {
+			return &ReferenceError{Entity: "medical entry", ID: e.AmendsID}
+		}
+	}
+	now := time.Now()
+	if e.OccurredOn == "" {
+		e.OccurredOn = now.Format("2006-01-02")
+	}
+	e.CreatedAt = now.Format(time.RFC3339)
+	e.ID = s.m.newID("medical_entries")
+	s.m.medical[e.ID] = memMedicalEntry{MedicalEntry: *e, clinicID: scope.ClinicID}
+	return nil
+}
@@ -0,0 +1,109 @@
+package store
+
+import (
+	"context"
+	"strings"
+
+	"pets_project/internal/models"
+)
+
+// === Owners ========================================================================
+type memOwnerStore struct{ m *memoryDB }
+
+func (s *memOwnerStore) List(ctx context.Context, scope Scope, filter OwnerFilter, page Page) ([]models.Owner, string, error) {
+	k, err := resolvePage(page, ownerSortFields)
+	if err != nil {
+		return nil, "", err
+	}
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	owners := []models.Owner{}
+	for _, o := range s.m.owners {
+		if !s.m.ownerVisible(scope, o) ||
+			(filter.Name != "" && !strings.Contains(strings.ToLower(o.Name), strings.ToLower(filter.Name))) ||
+			(filter.Email != "" && !strings.EqualFold(o.Email, filter.Email)) {
+			continue
+		}
+		owners = append(owners, o.Owner)
+	}
+	owners, next := paginate(k, owners, ownerSortKey(k.field))
+	return owners, next, nil
+}
+
+func (s *memOwnerStore) Get(ctx context.Context, scope Scope, id int) (models.Owner, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	o, ok := s.m.owners[id]
+	if !ok || !s.m.ownerVisible(scope, o) {
+		return models.Owner{}, ErrNotFound
+	}
+	return o.Owner, nil
+}
+
+func (s *memOwnerStore) GetByUser(ctx context.Context, clinicID int, userID int) (models.Owner, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	for _, o := range s.m.owners {
+		if o.UserID == userID && o.clinicID == clinicID {
+			return o.Owner, nil
+		}
+	}
+	return models.Owner{}, ErrNotFound
+}
+
+// checkOwnerUserLocked validates the linked account of an owner; callers hold m.mu
+func (s *memOwnerStore) checkOwnerUserLocked(scope Scope, o *models.Owner) error {
+	if o.UserID == 0 {
+		return nil
+	}
+	if u, ok := s.m.users[o.UserID]; !ok || u.ClinicID != scope.ClinicID {
+		return &ReferenceError{Entity: "user", ID: o.UserID}
+	}
+	for _, other := range s.m.owners {
+		if other.UserID == o.UserID && other.ID != o.ID {
+			return ErrConflict
+		}
+	}
+	return nil
+}
+
+func (s *memOwnerStore) Create(ctx context.Context, scope Scope, o *models.Owner) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	if err := s.checkOwnerUserLocked(scope, o); err != nil {
+		return err
+	}
+	o.ID = s.m.newID("owners")
+	s.m.owners[o.ID] = memOwner{Owner: *o, clinicID: scope.ClinicID}
+	return nil
+}
+
+func (s *memOwnerStore) Update(ctx context.Context, scope Scope, o *models.Owner) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	if err := s.checkOwnerUserLocked(scope, o); err != nil {
+		return err
+	}
+	existing, ok := s.m.owners[o.ID]
+	if !ok || !s.m.ownerVisible(scope, existing) {
+		return ErrNotFound
+	}
+	s.m.owners[o.ID] = memOwner{Owner: *o, clinicID: existing.clinicID}
+	return nil
+}
+
+func (s *memOwnerStore) Delete(ctx context.Context, scope Scope, id int) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	o, ok := s.m.owners[id]
+	if !ok || !s.m.ownerVisible(scope, o) {
+		return ErrNotFound
+	}
+	delete(s.m.owners, id)
+	for pid, p := range s.m.pets {
+		if p.OwnerID == id {
+			s.m.deletePetLocked(pid)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package store
+
+import (
+	"context"
+	"strings"
+
+	"pets_project/internal/models"
+)
+
+// === Pets ==========================================================================
+type memPetStore struct{ m *memoryDB }
+
+func (s *memPetStore) List(ctx context.Context, scope Scope, filter PetFilter, page Page) ([]models.Pet, string, error) {
+	k, err := resolvePage(page, petSortFields)
+	if err != nil {
+		return nil, "", err
+	}
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	pets := []models.Pet{}
+	for _, p := range s.m.pets {
+		if !s.m.petVisible(scope, p) ||
+			(filter.Species != "" && !strings.EqualFold(p.Species, filter.Species)) ||
+			(filter.Breed != "" && !strings.EqualFold(p.Breed, filter.Breed)) ||
+			(filter.OwnerID != 0 && p.OwnerID != filter.OwnerID) {
+			continue
+		}
+		pets = append(pets, p.Pet)
+	}
+	pets, next := paginate(k, pets, petSortKey(k.field))
+	return pets, next, nil
+}
+
+func (s *memPetStore) Get(ctx context.Context, scope Scope, id int) (models.Pet, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	p, ok := s.m.pets[id]
+	if !ok || !s.m.petVisible(scope, p) {
+		return models.Pet{}, ErrNotFound
+	}
+	return p.Pet, nil
+}
+
+func (s *memPetStore) Create(ctx context.Context, scope Scope, p *models.Pet) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	if o, ok := s.m.owners[p.OwnerID]; !ok || o.clinicID != scope.ClinicID {
+		return &ReferenceError{Entity: "owner", ID: p.OwnerID}
+	}
+	p.ID = s.m.newID("pets")
+	s.m.pets[p.ID] = memPet{Pet: *p, clinicID: scope.ClinicID}
+	return nil
+}
+
+func (s *memPetStore) Update(ctx context.Context, scope Scope, p *models.Pet) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	if o, ok := s.m.owners[p.OwnerID]; !ok || o.clinicID != scope.ClinicID {
+		return &ReferenceError{Entity: "owner", ID: p.OwnerID}
+	}
+	existing, ok := s.m.pets[p.ID]
+	if !ok || !s.m.petVisible(scope, existing) {
+		return ErrNotFound
+	}
+	s.m.pets[p.ID] = memPet{Pet: *p, clinicID: existing.clinicID}
+	return nil
+}
+
+func (s *memPetStore) Delete(ctx context.Context, scope Scope, id int) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	p, ok := s.m.pets[id]
+	if !ok || !s.m.petVisible(scope, p) {
+		return ErrNotFound
+	}
+	s.m.deletePetLocked(id)
+	return nil
+}
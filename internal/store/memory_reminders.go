@@ -0,0 +1,108 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"pets_project/internal/models"
+)
+
+// === Reminders =====================================================================
+type memReminderStore struct{ m *memoryDB }
+
+// reminderKey is the primary key of appointment_reminders
+type reminderKey struct {
+	appointmentID int
+	startsAt      string // sortableTime of the start
+}
+
+type memReminder struct {
+	claimedAt time.Time
+	sentAt    time.Time
+}
+
+func keyOf(r models.Reminder) reminderKey {
+	return reminderKey{appointmentID: r.AppointmentID, startsAt: sortableTime(r.StartsAt)}
+}
+
+// blocksLocked reports whether the reminder was sent or is claimed; callers hold m.mu
+func (s *memReminderStore) blocksLocked(key reminderKey, now time.Time) bool {
+	rem, ok := s.m.reminders[key]
+	return ok && (!rem.sentAt.IsZero() || rem.claimedAt.After(now.Add(-ReminderClaimTTL)))
+}
+
+func (s *memReminderStore) Due(ctx context.Context, now, until time.Time, limit int) ([]models.Reminder, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	reminders := []models.Reminder{}
+	for _, a := range s.m.appointments {
+		start, _ := appointmentSpan(a.Appointment)
+		if a.Status != models.AppointmentScheduled || !start.After(now) || start.After(until) {
+			continue
+		}
+		pet := s.m.pets[a.PetID]
+		owner := s.m.owners[pet.OwnerID]
+		if owner.Email == "" {
+			continue
+		}
+		r := models.Reminder{
+			AppointmentID:   a.ID,
+			StartsAt:        a.StartsAt,
+			DurationMinutes: a.DurationMinutes,
+			Reason:          a.Reason,
+			PetName:         pet.Name,
+			OwnerName:       owner.Name,
+			OwnerEmail:      owner.Email,
+			VetName:         s.m.staff[a.VetID].Name,
+			ClinicName:      s.m.clinics[a.clinicID].Name,
+			ClinicTimezone:  s.m.clinics[a.clinicID].Timezone,
+		}
+		if !s.blocksLocked(keyOf(r), now) {
+			reminders = append(reminders, r)
+		}
+	}
+	sort.Slice(reminders, func(i, j int) bool {
+		ki, kj := sortableTime(reminders[i].StartsAt), sortableTime(reminders[j].StartsAt)
+		return ki < kj || (ki == kj && reminders[i].AppointmentID < reminders[j].AppointmentID)
+	})
+	if len(reminders) > limit {
+		reminders = reminders[:limit]
+	}
+	return reminders, nil
+}
+
+func (s *memReminderStore) Claim(ctx context.Context, r models.Reminder, now time.Time) (bool, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	if _, ok := s.m.appointments[r.AppointmentID]; !ok {
+		return false, &ReferenceError{Entity: "appointment", ID: r.AppointmentID}
+	}
+	key := keyOf(r)
+	if s.blocksLocked(key, now) {
+		return false, nil
+	}
+	s.m.reminders[key] = &memReminder{claimedAt: now}
+	return true, nil
+}
+
+func (s *memReminderStore) MarkSent(ctx context.Context, r models.Reminder, now time.Time) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	rem, ok := s.m.reminders[keyOf(r)]
+	if !ok {
+		return ErrNotFound
+	}
+	rem.sentAt = now
+	return nil
+}
+
+func (s *memReminderStore) Release(ctx context.Context, r models.Reminder) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	key := keyOf(r)
+	if rem, ok := s.m.reminders[key]; ok && rem.sentAt.IsZero() {
+		delete(s.m.reminders, key)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"pets_project/internal/models"
+)
+
+// === Staff =========================================================================
+type memStaffStore struct{ m *memoryDB }
+
+// copyStaff detaches the slices of a stored staff member from the caller's copy
+func copyStaff(st models.Staff) models.Staff {
+	st.Specialties = append([]string{}, st.Specialties...)
+	st.WorkingHours = copyHours(st.WorkingHours)
+	return st
+}
+
+// copyHours returns weekly hours in the order Postgres lists them
+func copyHours(hours []models.WorkingHours) []models.WorkingHours {
+	hours = append([]models.WorkingHours{}, hours...)
+	sort.Slice(hours, func(i, j int) bool {
+		a, b := hours[i], hours[j]
+		return a.Weekday < b.Weekday || (a.Weekday == b.Weekday && a.Start < b.Start)
+	})
+	return hours
+}
+
+func (s *memStaffStore) List(ctx context.Context, clinicID int, filter StaffFilter, page Page) ([]models.Staff, string, error) {
+	k, err := resolvePage(page, staffSortFields)
+	if err != nil {
+		return nil, "", err
+	}
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	staff := []models.Staff{}
+	for _, st := range s.m.staff {
+		if st.clinicID != clinicID || (filter.Role != "" && st.Role != filter.Role) {
+			continue
+		}
+		if filter.Specialty != "" {
+			found := false
+			for _, sp := range st.Specialties {
+				found = found || strings.EqualFold(sp, filter.Specialty)
+			}
+			if !found {
+				continue
+			}
+		}
+		staff = append(staff, copyStaff(st.Staff))
+	}
+	staff, next := paginate(k, staff, staffSortKey(k.field))
+	return staff, next, nil
+}
+
+func (s *memStaffStore) Get(ctx context.Context, clinicID int, id int) (models.Staff, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	st, ok := s.m.staff[id]
+	if !ok || st.clinicID != clinicID {
+		return models.Staff{}, ErrNotFound
+	}
+	return copyStaff(st.Staff), nil
+}
+
+// checkStaffLocked validates the linked account and hours of a staff member; callers hold m.mu
+func (s *memStaffStore) checkStaffLocked(clinicID int, st *models.Staff) error {
+	if st.UserID != 0 {
+		if u, ok := s.m.users[st.UserID]; !ok || u.ClinicID != clinicID {
+			return &ReferenceError{Entity: "user", ID: st.UserID}
+		}
+		for _, other := range s.m.staff {
+			if other.UserID == st.UserID && other.ID != st.ID {
+				return ErrConflict
+			}
+		}
+	}
+	// (staff_id, weekday, start_time) is the primary key of staff_working_hours
+	seen := map[models.WorkingHours]bool{}
+	for _, h := range st.WorkingHours {
+		key := models.WorkingHours{Weekday: h.Weekday, Start: h.Start}
+		if seen[key] {
+			return ErrConflict
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+func (s *memStaffStore) Create(ctx context.Context, clinicID int, st *models.Staff) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	if err := s.checkStaffLocked(clinicID, st); err != nil {
+		return err
+	}
+	st.ID = s.m.newID("staff")
+	s.m.staff[st.ID] = memStaff{Staff: copyStaff(*st), clinicID: clinicID}
+	return nil
+}
+
+func (s *memStaffStore) Update(ctx context.Context, clinicID int, st *models.Staff) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	existing, ok := s.m.staff[st.ID]
+	if !ok || existing.clinicID != clinicID {
+		return ErrNotFound
+	}
+	if err := s.checkStaffLocked(clinicID, st); err != nil {
+		return err
+	}
+	s.m.staff[st.ID] = memStaff{Staff: copyStaff(*st), clinicID: clinicID}
+	return nil
+}
+
+func (s *memStaffStore) Delete(ctx context.Context, clinicID int, id int) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	st, ok := s.m.staff[id]
+	if !ok || st.clinicID != clinicID {
+		return ErrNotFound
+	}
+	delete(s.m.staff, id)
+	// blocked_periods.staff_id is ON DELETE CASCADE
+	for bid, b := range s.m.blocked {
+		if b.StaffID == id {
+			delete(s.m.blocked, bid)
+		}
+	}
+	// appointments.vet_id and the waitlist's vet references are ON DELETE SET NULL
+	for aid, a := range s.m.appointments {
+		if a.VetID == id {
+			a.VetID = 0
+			s.m.appointments[aid] = a.revised(a.Appointment)
+		}
+	}
+	for wid, w := range s.m.waitlist {
+		if w.VetID == id {
+			w.VetID = 0
+		}
+		if w.Offer != nil && w.Offer.VetID == id {
+			offer := *w.Offer
+			offer.VetID = 0
+			w.Offer = &offer
+		}
+		s.m.waitlist[wid] = w
+	}
+	return nil
+}
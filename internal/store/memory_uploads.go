@@ -0,0 +1,125 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"pets_project/internal/models"
+)
+
+// === Uploads =======================================================================
+type memUploadStore struct{ m *memoryDB }
+
+func (s *memUploadStore) visible(scope Scope, u memUpload) bool {
+	return u.clinicID == scope.ClinicID && (!scope.OwnerOnly || s.m.petIDVisible(scope, u.PetID))
+}
+
+// copyUpload returns the upload with its own copy of the chunk keys
+func copyUpload(u models.Upload) models.Upload {
+	u.ChunkKeys = append([]string{}, u.ChunkKeys...)
+	return u
+}
+
+func (s *memUploadStore) Create(ctx context.Context, scope Scope, u *models.Upload) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	if !s.m.petIDVisible(scope, u.PetID) {
+		return &ReferenceError{Entity: "pet", ID: u.PetID}
+	}
+	if _, taken := s.m.uploads[u.ID]; taken {
+		return ErrConflict
+	}
+	u.Offset, u.ChunkKeys, u.FileID = 0, []string{}, 0
+	s.m.uploads[u.ID] = memUpload{Upload: copyUpload(*u), clinicID: scope.ClinicID}
+	return nil
+}
+
+func (s *memUploadStore) Get(ctx context.Context, scope Scope, id string) (models.Upload, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	u, ok := s.m.uploads[id]
+	if !ok || !s.visible(scope, u) {
+		return models.Upload{}, ErrNotFound
+	}
+	return copyUpload(u.Upload), nil
+}
+
+func (s *memUploadStore) AppendChunk(ctx context.Context, scope Scope, id string, offset, size int64, key string, expiresAt time.Time) (models.Upload, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	u, ok := s.m.uploads[id]
+	if !ok || !s.visible(scope, u) {
+		return models.Upload{}, ErrNotFound
+	}
+	if u.Offset != offset {
+		return copyUpload(u.Upload), &UploadOffsetError{UploadID: id, Offset: u.Offset}
+	}
+	u.Offset += size
+	u.ChunkKeys = append(copyUpload(u.Upload).ChunkKeys, key)
+	u.ExpiresAt = expiresAt.UTC().Format(time.RFC3339)
+	s.m.uploads[id] = u
+	return copyUpload(u.Upload), nil
+}
+
+func (s *memUploadStore) ClaimFinish(ctx context.Context, scope Scope, id string, staleBefore time.Time) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	u, ok := s.m.uploads[id]
+	if !ok || !s.visible(scope, u) {
+		return ErrNotFound
+	}
+	if u.Offset != u.Length || u.FileID != 0 || (!u.finishingAt.IsZero() && !u.finishingAt.Before(staleBefore)) {
+		return ErrConflict
+	}
+	u.finishingAt = time.Now()
+	s.m.uploads[id] = u
+	return nil
+}
+
+func (s *memUploadStore) ReleaseFinish(ctx context.Context, scope Scope, id string) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	u, ok := s.m.uploads[id]
+	if !ok || !s.visible(scope, u) || u.FileID != 0 {
+		return ErrNotFound
+	}
+	u.finishingAt = time.Time{}
+	s.m.uploads[id] = u
+	return nil
+}
+
+func (s *memUploadStore) Complete(ctx context.Context, scope Scope, id string, fileID int) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	u, ok := s.m.uploads[id]
+	if !ok || !s.visible(scope, u) || u.FileID != 0 {
+		return ErrNotFound
+	}
+	u.FileID, u.ChunkKeys, u.finishingAt = fileID, []string{}, time.Time{}
+	s.m.uploads[id] = u
+	return nil
+}
+
+func (s *memUploadStore) Delete(ctx context.Context, scope Scope, id string) (models.Upload, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	u, ok := s.m.uploads[id]
+	if !ok || !s.visible(scope, u) {
+		return models.Upload{}, ErrNotFound
+	}
+	delete(s.m.uploads, id)
+	return u.Upload, nil
+}
+
+func (s *memUploadStore) DeleteExpired(ctx context.Context, now time.Time) ([]models.Upload, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	uploads := []models.Upload{}
+	for id, u := range s.m.uploads {
+		if expiresAt, _ := time.Parse(time.RFC3339, u.ExpiresAt); expiresAt.Before(now) {
+			uploads = append(uploads, u.Upload)
+			delete(s.m.uploads, id)
+		}
+	}
+	return uploads, nil
+}
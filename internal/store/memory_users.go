@@ -0,0 +1,231 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"pets_project/internal/models"
+)
+
+// === Users =========================================================================
+type memUserStore struct{ m *memoryDB }
+
+func (s *memUserStore) Create(ctx context.Context, u *models.User) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	for _, existing := range s.m.users {
+		if existing.Email == u.Email {
+			return ErrConflict
+		}
+	}
+	if u.Role == "" {
+		u.Role = models.RoleOwner
+	}
+	u.ID = s.m.newID("users")
+	s.m.users[u.ID] = *u
+	return nil
+}
+
+func (s *memUserStore) GetByEmail(ctx context.Context, email string) (models.User, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	for _, u := range s.m.users {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+	return models.User{}, ErrNotFound
+}
+
+func (s *memUserStore) ListByClinic(ctx context.Context, clinicID int) ([]models.User, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	users := []models.User{}
+	for _, id := range sortedIDs(s.m.users) {
+		if u := s.m.users[id]; u.ClinicID == clinicID {
+			u.PasswordHash = ""
+			users = append(users, u)
+		}
+	}
+	return users, nil
+}
+
+func (s *memUserStore) UpdateRole(ctx context.Context, clinicID int, id int, role string) (models.User, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	u, ok := s.m.users[id]
+	if !ok || u.ClinicID != clinicID {
+		return models.User{}, ErrNotFound
+	}
+	u.Role = role
+	s.m.users[id] = u
+	u.PasswordHash = ""
+	return u, nil
+}
+
+func (s *memUserStore) SetFeedToken(ctx context.Context, userID int, tokenHash string) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	if _, ok := s.m.users[userID]; !ok {
+		return &ReferenceError{Entity: "user", ID: userID}
+	}
+	s.m.feedTokens[userID] = tokenHash
+	return nil
+}
+
+func (s *memUserStore) DeleteFeedToken(ctx context.Context, userID int) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	if _, ok := s.m.feedTokens[userID]; !ok {
+		return ErrNotFound
+	}
+	delete(s.m.feedTokens, userID)
+	return nil
+}
+
+func (s *memUserStore) GetByFeedToken(ctx context.Context, tokenHash string) (models.User, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	for userID, hash := range s.m.feedTokens {
+		if hash == tokenHash {
+			u := s.m.users[userID]
+			u.PasswordHash = ""
+			return u, nil
+		}
+	}
+	return models.User{}, ErrNotFound
+}
+
+// === Sessions ======================================================================
+type memSessionStore struct{ m *memoryDB }
+
+func (s *memSessionStore) Create(ctx context.Context, sessionID string, userID int, tokenHash string, ttl time.Duration) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	if _, ok := s.m.users[userID]; !ok {
+		return &ReferenceError{Entity: "user", ID: userID}
+	}
+	s.m.sessions[sessionID] = &memSession{userID: userID}
+	s.m.refreshTokens[tokenHash] = &memRefreshToken{sessionID: sessionID, expiresAt: time.Now().Add(ttl)}
+	return nil
+}
+
+func (s *memSessionStore) Rotate(ctx context.Context, tokenHash string, newTokenHash string, ttl time.Duration) (models.User, string, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	rt, ok := s.m.refreshTokens[tokenHash]
+	if !ok {
+		return models.User{}, "", ErrTokenInvalid
+	}
+	session := s.m.sessions[rt.sessionID]
+	user := s.m.users[session.userID]
+	user.PasswordHash = ""
+
+	if session.revoked {
+		return user, rt.sessionID, ErrSessionRevoked
+	}
+	if rt.used {
+		session.revoked = true
+		return user, rt.sessionID, ErrTokenReused
+	}
+	if !time.Now().Before(rt.expiresAt) {
+		return user, rt.sessionID, ErrTokenExpired
+	}
+
+	rt.used = true
+	s.m.refreshTokens[newTokenHash] = &memRefreshToken{sessionID: rt.sessionID, expiresAt: time.Now().Add(ttl)}
+	return user, rt.sessionID, nil
+}
+
+func (s *memSessionStore) IsActive(ctx context.Context, sessionID string, userID int) (bool, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	session, ok := s.m.sessions[sessionID]
+	return ok && session.userID == userID && !session.revoked, nil
+}
+
+func (s *memSessionStore) Revoke(ctx context.Context, sessionID string) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	if session, ok := s.m.sessions[sessionID]; ok {
+		session.revoked = true
+	}
+	return nil
+}
+
+func (s *memSessionStore) RevokeAllForUser(ctx context.Context, userID int) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	for _, session := range s.m.sessions {
+		if session.userID == userID {
+			session.revoked = true
+		}
+	}
+	return nil
+}
+
+// === Clinics =======================================================================
+type memClinicStore struct{ m *memoryDB }
+
+func (s *memClinicStore) List(ctx context.Context) ([]models.Clinic, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	clinics := []models.Clinic{}
+	for _, id := range sortedIDs(s.m.clinics) {
+		clinics = append(clinics, s.m.clinics[id])
+	}
+	return clinics, nil
+}
+
+func (s *memClinicStore) Get(ctx context.Context, id int) (models.Clinic, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	c, ok := s.m.clinics[id]
+	if !ok {
+		return models.Clinic{}, ErrNotFound
+	}
+	return c, nil
+}
+
+func (s *memClinicStore) Hours(ctx context.Context, clinicID int) ([]models.WorkingHours, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	return copyHours(s.m.clinicHours[clinicID]), nil
+}
+
+func (s *memClinicStore) SetHours(ctx context.Context, clinicID int, hours []models.WorkingHours) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	if _, ok := s.m.clinics[clinicID]; !ok {
+		return ErrNotFound
+	}
+	// (clinic_id, weekday, start_time) is the primary key of clinic_hours
+	seen := map[models.WorkingHours]bool{}
+	for _, h := range hours {
+		key := models.WorkingHours{Weekday: h.Weekday, Start: h.Start}
+		if seen[key] {
+			return ErrConflict
+		}
+		seen[key] = true
+	}
+	s.m.clinicHours[clinicID] = append([]models.WorkingHours{}, hours...)
+	return nil
+}
+
+func (s *memClinicStore) Exists(ctx context.Context, id int) (bool, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	_, ok := s.m.clinics[id]
+	return ok, nil
+}
+
+func (s *memClinicStore) Create(ctx context.Context, c *models.Clinic) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	if c.Timezone == "" {
+		c.Timezone = "UTC"
+	}
+	c.ID = s.m.newID("clinics")
+	s.m.clinics[c.ID] = *c
+	return nil
+}
@@ -0,0 +1,140 @@
+package store
+
+import (
+	"context"
+	"strings"
+
+	"pets_project/internal/models"
+)
+
+// === Vaccinations ==================================================================
+type memVaccinationStore struct{ m *memoryDB }
+
+func (s *memVaccinationStore) visible(scope Scope, petID int, v memVaccination) bool {
+	return v.PetID == petID && v.clinicID == scope.ClinicID && s.m.petIDVisible(scope, v.PetID)
+}
+
+func (s *memVaccinationStore) List(ctx context.Context, scope Scope, petID int, page Page) ([]models.Vaccination, string, error) {
+	k, err := resolvePage(page, vaccinationSortFields)
+	if err != nil {
+		return nil, "", err
+	}
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	vaccinations := []models.Vaccination{}
+	for _, v := range s.m.vaccinations {
+		if s.visible(scope, petID, v) {
+			vaccinations = append(vaccinations, v.Vaccination)
+		}
+	}
+	vaccinations, next := paginate(k, vaccinations, vaccinationSortKey(k.field))
+	return vaccinations, next, nil
+}
+
+func (s *memVaccinationStore) Get(ctx context.Context, scope Scope, petID int, id int) (models.Vaccination, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	v, ok := s.m.vaccinations[id]
+	if !ok || !s.visible(scope, petID, v) {
+		return models.Vaccination{}, ErrNotFound
+	}
+	return v.Vaccination, nil
+}
+
+// checkRefsLocked validates the pet and administering user; callers hold m.mu
+func (s *memVaccinationStore) checkRefsLocked(scope Scope, v *models.Vaccination) error {
+	if !s.m.petIDVisible(scope, v.PetID) {
+		return &ReferenceError{Entity: "pet", ID: v.PetID}
+	}
+	if v.AdministeredBy != 0 {
+		if u, ok := s.m.users[v.AdministeredBy]; !ok || u.ClinicID != scope.ClinicID {
+			return &ReferenceError{Entity: "user", ID: v.AdministeredBy}
+		}
+	}
+	return nil
+}
+
+func (s *memVaccinationStore) Create(ctx context.Context, scope Scope, v *models.Vaccination) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	if err := s.checkRefsLocked(scope, v); err != nil {
+		return err
+	}
+	v.ID = s.m.newID("vaccinations")
+	s.m.vaccinations[v.ID] = memVaccination{Vaccination: *v, clinicID: scope.ClinicID}
+	return nil
+}
+
+func (s *memVaccinationStore) Update(ctx context.Context, scope Scope, v *models.Vaccination) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	if err := s.checkRefsLocked(scope, v); err != nil {
+		return err
+	}
+	existing, ok := s.m.vaccinations[v.ID]
+	if !ok || !s.visible(scope, v.PetID, existing) {
+		return ErrNotFound
+	}
+	s.m.vaccinations[v.ID] = memVaccination{Vaccination: *v, clinicID: existing.clinicID}
+	return nil
+}
+
+func (s *memVaccinationStore) Delete(ctx context.Context, scope Scope, petID int, id int) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	v, ok := s.m.vaccinations[id]
+	if !ok || !s.visible(scope, petID, v) {
+		return ErrNotFound
+	}
+	delete(s.m.vaccinations, id)
+	return nil
+}
+
+func (s *memVaccinationStore) Due(ctx context.Context, scope Scope, dueBy string, page Page) ([]models.VaccinationDue, string, error) {
+	k, err := resolvePage(page, vaccinationDueSortFields)
+	if err != nil {
+		return nil, "", err
+	}
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	// Keep the latest dose per pet and vaccine
+	type doseKey struct {
+		petID   int
+		vaccine string
+	}
+	latest := map[doseKey]models.Vaccination{}
+	for _, v := range s.m.vaccinations {
+		if v.clinicID != scope.ClinicID || !s.m.petIDVisible(scope, v.PetID) {
+			continue
+		}
+		key := doseKey{v.PetID, strings.ToLower(v.Vaccine)}
+		if cur, ok := latest[key]; !ok || v.AdministeredOn > cur.AdministeredOn ||
+			(v.AdministeredOn == cur.AdministeredOn && v.ID > cur.ID) {
+			latest[key] = v.Vaccination
+		}
+	}
+
+	due := []models.VaccinationDue{}
+	for _, v := range latest {
+		if v.NextDueOn == "" || v.NextDueOn > dueBy {
+			continue
+		}
+		pet := s.m.pets[v.PetID]
+		owner := s.m.owners[pet.OwnerID]
+		due = append(due, models.VaccinationDue{
+			VaccinationID:  v.ID,
+			PetID:          v.PetID,
+			PetName:        pet.Name,
+			OwnerID:        owner.ID,
+			OwnerName:      owner.Name,
+			OwnerContact:   owner.Contact,
+			OwnerEmail:     owner.Email,
+			Vaccine:        v.Vaccine,
+			AdministeredOn: v.AdministeredOn,
+			NextDueOn:      v.NextDueOn,
+		})
+	}
+	due, next := paginate(k, due, vaccinationDueSortKey(k.field))
+	return due, next, nil
+}
@@ -0,0 +1,206 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"pets_project/internal/models"
+)
+
+// === Waitlist ======================================================================
+type memWaitlistStore struct{ m *memoryDB }
+
+func (s *memWaitlistStore) visible(scope Scope, e memWaitlistEntry) bool {
+	return e.clinicID == scope.ClinicID && (!scope.OwnerOnly || s.m.petIDVisible(scope, e.PetID))
+}
+
+// sortWaitlist orders entries as List does: highest priority, then oldest
+func sortWaitlist(entries []models.WaitlistEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+		if a.CreatedAt != b.CreatedAt {
+			return a.CreatedAt < b.CreatedAt
+		}
+		return a.ID < b.ID
+	})
+}
+
+// copyEntry returns the entry with its own copy of the offer
+func copyEntry(e models.WaitlistEntry) models.WaitlistEntry {
+	if e.Offer != nil {
+		offer := *e.Offer
+		e.Offer = &offer
+	}
+	return e
+}
+
+func (s *memWaitlistStore) List(ctx context.Context, scope Scope, filter WaitlistFilter) ([]models.WaitlistEntry, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	entries := []models.WaitlistEntry{}
+	for _, id := range sortedIDs(s.m.waitlist) {
+		e := s.m.waitlist[id]
+		if !s.visible(scope, e) ||
+			(filter.PetID != 0 && e.PetID != filter.PetID) ||
+			(filter.VetID != 0 && e.VetID != filter.VetID) ||
+			(filter.Status != "" && e.Status != filter.Status) {
+			continue
+		}
+		entries = append(entries, copyEntry(e.WaitlistEntry))
+	}
+	sortWaitlist(entries)
+	return entries, nil
+}
+
+func (s *memWaitlistStore) Get(ctx context.Context, scope Scope, id int) (models.WaitlistEntry, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	e, ok := s.m.waitlist[id]
+	if !ok || !s.visible(scope, e) {
+		return models.WaitlistEntry{}, ErrNotFound
+	}
+	return copyEntry(e.WaitlistEntry), nil
+}
+
+// checkRefsLocked validates the pet and preferred vet; callers hold m.mu
+func (s *memWaitlistStore) checkRefsLocked(scope Scope, e *models.WaitlistEntry) error {
+	if !s.m.petIDVisible(scope, e.PetID) {
+		return &ReferenceError{Entity: "pet", ID: e.PetID}
+	}
+	if e.VetID != 0 {
+		if st, ok := s.m.staff[e.VetID]; !ok || st.clinicID != scope.ClinicID || st.Role != models.RoleVet {
+			return &ReferenceError{Entity: "vet", ID: e.VetID}
+		}
+	}
+	return nil
+}
+
+func (s *memWaitlistStore) Create(ctx context.Context, scope Scope, e *models.WaitlistEntry) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	if err := s.checkRefsLocked(scope, e); err != nil {
+		return err
+	}
+	e.ID = s.m.newID("waitlist_entries")
+	e.Status = models.WaitlistWaiting
+	e.Offer = nil
+	e.AppointmentID = 0
+	e.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	s.m.waitlist[e.ID] = memWaitlistEntry{WaitlistEntry: *e, clinicID: scope.ClinicID}
+	return nil
+}
+
+func (s *memWaitlistStore) Update(ctx context.Context, scope Scope, e *models.WaitlistEntry) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	existing, ok := s.m.waitlist[e.ID]
+	if !ok || !s.visible(scope, existing) {
+		return ErrNotFound
+	}
+	if existing.Status != models.WaitlistWaiting {
+		return &WaitlistStateError{EntryID: e.ID, Status: existing.Status, Want: models.WaitlistWaiting}
+	}
+	if err := s.checkRefsLocked(scope, e); err != nil {
+		return err
+	}
+	e.Status, e.Offer, e.AppointmentID, e.CreatedAt = existing.Status, nil, 0, existing.CreatedAt
+	s.m.waitlist[e.ID] = memWaitlistEntry{WaitlistEntry: *e, clinicID: existing.clinicID}
+	return nil
+}
+
+func (s *memWaitlistStore) Delete(ctx context.Context, scope Scope, id int) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	e, ok := s.m.waitlist[id]
+	if !ok || !s.visible(scope, e) {
+		return ErrNotFound
+	}
+	delete(s.m.waitlist, id)
+	return nil
+}
+
+func (s *memWaitlistStore) Offer(ctx context.Context, clinicID int, slot FreedSlot, now time.Time, exclude []int) (models.WaitlistEntry, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	var candidates []models.WaitlistEntry
+	for _, e := range s.m.waitlist {
+		if e.clinicID == clinicID && e.Status == models.WaitlistWaiting &&
+			e.EarliestDate <= slot.Date && slot.Date <= e.LatestDate &&
+			e.DurationMinutes <= slot.DurationMinutes &&
+			(e.VetID == 0 || e.VetID == slot.VetID) &&
+			!containsInt(exclude, e.ID) {
+			candidates = append(candidates, e.WaitlistEntry)
+		}
+	}
+	if len(candidates) == 0 {
+		return models.WaitlistEntry{}, ErrNotFound
+	}
+	sortWaitlist(candidates)
+
+	row := s.m.waitlist[candidates[0].ID]
+	row.Status = models.WaitlistOffered
+	row.Offer = &models.WaitlistOffer{
+		StartsAt:        slot.StartsAt.UTC().Format(time.RFC3339),
+		DurationMinutes: slot.DurationMinutes,
+		VetID:           slot.VetID,
+		OfferedAt:       now.UTC().Format(time.RFC3339),
+	}
+	s.m.waitlist[row.ID] = row
+	return copyEntry(row.WaitlistEntry), nil
+}
+
+func (s *memWaitlistStore) Decline(ctx context.Context, scope Scope, id int) (models.WaitlistEntry, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	row, ok := s.m.waitlist[id]
+	if !ok || !s.visible(scope, row) {
+		return models.WaitlistEntry{}, ErrNotFound
+	}
+	declined := copyEntry(row.WaitlistEntry)
+	if row.Status != models.WaitlistOffered {
+		return declined, &WaitlistStateError{EntryID: id, Status: row.Status, Want: models.WaitlistOffered}
+	}
+	row.Status, row.Offer = models.WaitlistWaiting, nil
+	s.m.waitlist[id] = row
+	return declined, nil
+}
+
+func (s *memWaitlistStore) MarkBooked(ctx context.Context, scope Scope, id int, appointmentID int) (models.WaitlistEntry, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	row, ok := s.m.waitlist[id]
+	if !ok || !s.visible(scope, row) {
+		return models.WaitlistEntry{}, ErrNotFound
+	}
+	if row.Status != models.WaitlistOffered {
+		return models.WaitlistEntry{}, &WaitlistStateError{EntryID: id, Status: row.Status, Want: models.WaitlistOffered}
+	}
+	row.Status, row.Offer, row.AppointmentID = models.WaitlistBooked, nil, appointmentID
+	s.m.waitlist[id] = row
+	return copyEntry(row.WaitlistEntry), nil
+}
+
+func (s *memWaitlistStore) ExpireOffers(ctx context.Context, offeredBefore, now time.Time) ([]ExpiredOffer, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	expired := []ExpiredOffer{}
+	for _, id := range sortedIDs(s.m.waitlist) {
+		row := s.m.waitlist[id]
+		if row.Status != models.WaitlistOffered {
+			continue
+		}
+		offeredAt, _ := time.Parse(time.RFC3339, row.Offer.OfferedAt)
+		startsAt, _ := time.Parse(time.RFC3339, row.Offer.StartsAt)
+		if !offeredAt.Before(offeredBefore) && !startsAt.Before(now) {
+			continue
+		}
+		expired = append(expired, ExpiredOffer{ClinicID: row.clinicID, Entry: copyEntry(row.WaitlistEntry)})
+		row.Status, row.Offer = models.WaitlistWaiting, nil
+		s.m.waitlist[id] = row
+	}
+	return expired, nil
+}
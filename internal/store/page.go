@@ -0,0 +1,286 @@
+package store
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"pets_project/internal/models"
+)
+
+// Page size limits for list queries
+const (
+	DefaultPageLimit = 50
+	MaxPageLimit     = 200
+)
+
+var (
+	// ErrInvalidCursor is returned for a cursor that is malformed or was issued for another sort order
+	ErrInvalidCursor = errors.New("invalid cursor")
+	// ErrInvalidSort is returned when the requested sort field is not whitelisted
+	ErrInvalidSort = errors.New("invalid sort field")
+)
+
+// Page selects one page of a list query.
+// Sort names a whitelisted field, prefixed with "-" for descending order; empty sorts by id.
+// Cursor is the next_cursor returned with the previous page.
+type Page struct {
+	Limit  int
+	Cursor string
+	Sort   string
+}
+
+// cursor is the position after the last row of a page: its sort value and id
+type cursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v,omitempty"`
+	ID    int    `json:"id"`
+}
+
+func encodeCursor(c cursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(b, &c); err != nil || c.ID <= 0 {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// keyset is a validated Page: rows are ordered by (field, id) and start after the cursor
+type keyset struct {
+	sort  string // Page.Sort as requested, stored in issued cursors
+	field string // sort field without the direction prefix
+	expr  string // SQL expression ordering by field
+	desc  bool
+	after *cursor
+	limit int
+}
+
+// resolvePage validates page against the sort fields whitelisted for a table.
+// fields maps public field names to SQL expressions; "id" is always allowed.
+func resolvePage(page Page, fields map[string]string) (keyset, error) {
+	k := keyset{sort: page.Sort, field: strings.TrimPrefix(page.Sort, "-"), desc: strings.HasPrefix(page.Sort, "-")}
+	if k.field == "" {
+		k.field = "id"
+	}
+	if k.field == "id" {
+		k.expr = "id"
+	} else if expr, ok := fields[k.field]; ok {
+		k.expr = expr
+	} else {
+		return k, ErrInvalidSort
+	}
+
+	k.limit = page.Limit
+	if k.limit <= 0 {
+		k.limit = DefaultPageLimit
+	}
+	if k.limit > MaxPageLimit {
+		k.limit = MaxPageLimit
+	}
+
+	if page.Cursor != "" {
+		c, err := decodeCursor(page.Cursor)
+		if err != nil {
+			return k, err
+		}
+		if c.Sort != page.Sort {
+			return k, ErrInvalidCursor
+		}
+		k.after = &c
+	}
+	return k, nil
+}
+
+// sqlClauses appends the keyset condition and ORDER BY/LIMIT to a query whose
+// WHERE clause already uses args. One row more than the limit is fetched so
+// the caller can tell whether another page follows.
+func (k keyset) sqlClauses(args []interface{}) (string, []interface{}) {
+	dir, cmp := "ASC", ">"
+	if k.desc {
+		dir, cmp = "DESC", "<"
+	}
+
+	var b strings.Builder
+	if k.after != nil {
+		if k.field == "id" {
+			args = append(args, k.after.ID)
+			fmt.Fprintf(&b, " AND id %s $%d", cmp, len(args))
+		} else {
+			args = append(args, k.after.Value, k.after.ID)
+			fmt.Fprintf(&b, " AND (%s, id) %s ($%d, $%d)", k.expr, cmp, len(args)-1, len(args))
+		}
+	}
+	if k.field == "id" {
+		fmt.Fprintf(&b, " ORDER BY id %s", dir)
+	} else {
+		fmt.Fprintf(&b, " ORDER BY %s %s, id %s", k.expr, dir, dir)
+	}
+	fmt.Fprintf(&b, " LIMIT %d", k.limit+1)
+	return b.String(), args
+}
+
+// trimPage cuts rows fetched with sqlClauses down to the page and returns the
+// cursor of the following page, or "" when this is the last one.
+// key returns the sort value and id of a row.
+func trimPage[T any](k keyset, rows []T, key func(T) (string, int)) ([]T, string) {
+	if len(rows) <= k.limit {
+		return rows, ""
+	}
+	rows = rows[:k.limit]
+	value, id := key(rows[len(rows)-1])
+	if k.field == "id" {
+		value = ""
+	}
+	return rows, encodeCursor(cursor{Sort: k.sort, Value: value, ID: id})
+}
+
+// paginate is the in-memory equivalent of sqlClauses followed by trimPage
+func paginate[T any](k keyset, rows []T, key func(T) (string, int)) ([]T, string) {
+	less := func(a, b T) bool {
+		av, aid := key(a)
+		bv, bid := key(b)
+		if k.field == "id" || av == bv {
+			return aid < bid
+		}
+		return av < bv
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if k.desc {
+			return less(rows[j], rows[i])
+		}
+		return less(rows[i], rows[j])
+	})
+
+	start := 0
+	if k.after != nil {
+		pivot := func(row T) bool {
+			v, id := key(row)
+			if k.field == "id" {
+				v = ""
+			}
+			if k.desc {
+				return v < k.after.Value || (v == k.after.Value && id < k.after.ID)
+			}
+			return v > k.after.Value || (v == k.after.Value && id > k.after.ID)
+		}
+		start = sort.Search(len(rows), func(i int) bool { return pivot(rows[i]) })
+	}
+	end := start + k.limit + 1
+	if end > len(rows) {
+		end = len(rows)
+	}
+	return trimPage(k, rows[start:end], key)
+}
+
+// === Whitelisted sort fields =======================================================
+var petSortFields = map[string]string{"name": "name", "species": "species", "breed": "breed"}
+
+func petSortKey(field string) func(models.Pet) (string, int) {
+	return func(p models.Pet) (string, int) {
+		switch field {
+		case "name":
+			return p.Name, p.ID
+		case "species":
+			return p.Species, p.ID
+		case "breed":
+			return p.Breed, p.ID
+		}
+		return "", p.ID
+	}
+}
+
+var ownerSortFields = map[string]string{"name": "name", "email": "email"}
+
+func ownerSortKey(field string) func(models.Owner) (string, int) {
+	return func(o models.Owner) (string, int) {
+		switch field {
+		case "name":
+			return o.Name, o.ID
+		case "email":
+			return o.Email, o.ID
+		}
+		return "", o.ID
+	}
+}
+
+var appointmentSortFields = map[string]string{"starts_at": "starts_at"}
+
+func appointmentSortKey(field string) func(models.Appointment) (string, int) {
+	return func(a models.Appointment) (string, int) {
+		if field == "starts_at" {
+			return sortableTime(a.StartsAt), a.ID
+		}
+		return "", a.ID
+	}
+}
+
+// sortableTime rewrites an RFC 3339 time as fixed-width UTC so that string order is time order.
+// Postgres reads the result back as a timestamptz cursor value.
+func sortableTime(s string) string {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return s
+	}
+	return t.UTC().Format("2006-01-02T15:04:05.000000Z")
+}
+
+var medicalSortFields = map[string]string{"occurred_on": "occurred_on"}
+
+func medicalSortKey(field string) func(models.MedicalEntry) (string, int) {
+	return func(e models.MedicalEntry) (string, int) {
+		if field == "occurred_on" {
+			return e.OccurredOn, e.ID
+		}
+		return "", e.ID
+	}
+}
+
+var vaccinationSortFields = map[string]string{"administered_on": "administered_on"}
+
+func vaccinationSortKey(field string) func(models.Vaccination) (string, int) {
+	return func(v models.Vaccination) (string, int) {
+		if field == "administered_on" {
+			return v.AdministeredOn, v.ID
+		}
+		return "", v.ID
+	}
+}
+
+// The due list always runs soonest first; its rows are keyed by vaccination id
+var vaccinationDueSortFields = map[string]string{"next_due_on": "next_due_on"}
+
+func vaccinationDueSortKey(field string) func(models.VaccinationDue) (string, int) {
+	return func(d models.VaccinationDue) (string, int) {
+		if field == "next_due_on" {
+			return d.NextDueOn, d.VaccinationID
+		}
+		return "", d.VaccinationID
+	}
+}
+
+var staffSortFields = map[string]string{"name": "name", "role": "role"}
+
+func staffSortKey(field string) func(models.Staff) (string, int) {
+	return func(st models.Staff) (string, int) {
+		switch field {
+		case "name":
+			return st.Name, st.ID
+		case "role":
+			return st.Role, st.ID
+		}
+		return "", st.ID
+	}
+}
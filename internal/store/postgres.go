@@ -0,0 +1,149 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// NewPostgres returns stores backed by a lib/pq connection pool
+func NewPostgres(db *sql.DB) Stores {
+	return Stores{
+		Pets:         &pgPetStore{db: db},
+		Owners:       &pgOwnerStore{db: db},
+		Appointments: &pgAppointmentStore{db: db},
+		Files:        &pgFileRecordStore{db: db},
+		Uploads:      &pgUploadStore{db: db},
+		Links:        &pgDownloadLinkStore{db: db},
+		Medical:      &pgMedicalEntryStore{db: db},
+		Vaccinations: &pgVaccinationStore{db: db},
+		Staff:        &pgStaffStore{db: db},
+		Types:        &pgAppointmentTypeStore{db: db},
+		Blocked:      &pgBlockedPeriodStore{db: db},
+		Reminders:    &pgReminderStore{db: db},
+		Waitlist:     &pgWaitlistStore{db: db},
+		Users:        &pgUserStore{db: db},
+		Sessions:     &pgSessionStore{db: db},
+		Clinics:      &pgClinicStore{db: db},
+	}
+}
+
+// ownerFilter is bound to "($n::int IS NULL OR owner_id = $n)" style conditions:
+// nil lets clinic-wide callers through, otherwise rows must belong to the scoped owner.
+func ownerFilter(scope Scope) interface{} {
+	if !scope.OwnerOnly {
+		return nil
+	}
+	return scope.OwnerID
+}
+
+// nullableID maps an unset (zero) foreign key to SQL NULL
+func nullableID(id int) interface{} {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+// escapeLike escapes the LIKE wildcards in a user supplied substring
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// nullableTime maps an unset (zero) time to SQL NULL
+func nullableTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
+
+// nullableString maps an unset (empty) string to SQL NULL
+func nullableString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// containsString reports whether list holds s
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// containsInt reports whether list holds n
+func containsInt(list []int, n int) bool {
+	for _, v := range list {
+		if v == n {
+			return true
+		}
+	}
+	return false
+}
+
+// isUniqueViolation reports whether err is a Postgres unique constraint violation
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// notFound translates sql.ErrNoRows into ErrNotFound
+func notFound(err error) error {
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
+	return err
+}
+
+// checkAffected returns ErrNotFound when an UPDATE or DELETE touched no rows
+func checkAffected(res sql.Result) error {
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// queryer is satisfied by *sql.DB and *sql.Tx
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// checkInClinic returns a ReferenceError unless the row exists in the clinic.
+// table must be a trusted constant such as "owners" or "users".
+func checkInClinic(ctx context.Context, q queryer, table string, entity string, id int, clinicID int) error {
+	var exists bool
+	sqlStatement := `SELECT EXISTS (SELECT 1 FROM ` + table + ` WHERE id = $1 AND clinic_id = $2)`
+	if err := q.QueryRowContext(ctx, sqlStatement, id, clinicID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return &ReferenceError{Entity: entity, ID: id}
+	}
+	return nil
+}
+
+// checkPetInScope returns a ReferenceError unless the pet is visible within the scope
+func checkPetInScope(ctx context.Context, q queryer, scope Scope, petID int) error {
+	var exists bool
+	sqlStatement := `SELECT EXISTS (SELECT 1 FROM pets WHERE id = $1 AND clinic_id = $2 AND ($3::int IS NULL OR owner_id = $3))`
+	if err := q.QueryRowContext(ctx, sqlStatement, petID, scope.ClinicID, ownerFilter(scope)).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return &ReferenceError{Entity: "pet", ID: petID}
+	}
+	return nil
+}
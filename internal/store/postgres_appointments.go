@@ -0,0 +1,449 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+
+	"pets_project/internal/models"
+)
+
+type pgAppointmentStore struct {
+	db *sql.DB
+}
+
+const appointmentColumns = `id, pet_id, vet_id, starts_at, duration_minutes, reason, series_id, status`
+
+// activeAppointment is the SQL condition for appointments that occupy their time slot
+const activeAppointment = `status NOT IN ('cancelled', 'no_show')`
+
+// scanAppointment reads the columns listed in appointmentColumns
+func scanAppointment(row interface{ Scan(...interface{}) error }, a *models.Appointment) error {
+	var vetID, seriesID sql.NullInt64
+	var startsAt time.Time
+	var reason sql.NullString
+	if err := row.Scan(&a.ID, &a.PetID, &vetID, &startsAt, &a.DurationMinutes, &reason, &seriesID, &a.Status); err != nil {
+		return err
+	}
+	a.VetID = int(vetID.Int64)
+	a.SeriesID = int(seriesID.Int64)
+	a.StartsAt = startsAt.UTC().Format(time.RFC3339)
+	a.Reason = reason.String
+	return nil
+}
+
+func (s *pgAppointmentStore) List(ctx context.Context, scope Scope, filter AppointmentFilter, page Page) ([]models.Appointment, string, error) {
+	k, err := resolvePage(page, appointmentSortFields)
+	if err != nil {
+		return nil, "", err
+	}
+	sqlStatement := `
+		SELECT ` + appointmentColumns + ` FROM appointments
+		WHERE clinic_id = $1 AND ($2::int IS NULL OR pet_id IN (SELECT id FROM pets WHERE owner_id = $2))
+		AND ($3::int IS NULL OR pet_id = $3)
+		AND ($4::int IS NULL OR vet_id = $4)
+		AND ($5::timestamptz IS NULL OR starts_at >= $5)
+		AND ($6::timestamptz IS NULL OR starts_at < $6)
+		AND ($7::int IS NULL OR series_id = $7)
+		AND ($8::text[] IS NULL OR status = ANY($8))
+		AND ($9::int IS NULL OR pet_id IN (SELECT id FROM pets WHERE owner_id = $9))`
+	clauses, args := k.sqlClauses([]interface{}{scope.ClinicID, ownerFilter(scope), nullableID(filter.PetID), nullableID(filter.VetID),
+		nullableTime(filter.From), nullableTime(filter.To), nullableID(filter.SeriesID), pq.Array(filter.Statuses), nullableID(filter.OwnerID)})
+	rows, err := s.db.QueryContext(ctx, sqlStatement+clauses, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+	appointments := []models.Appointment{}
+	for rows.Next() {
+		var a models.Appointment
+		if err := scanAppointment(rows, &a); err != nil {
+			return nil, "", err
+		}
+		appointments = append(appointments, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+	appointments, next := trimPage(k, appointments, appointmentSortKey(k.field))
+	return appointments, next, nil
+}
+
+func (s *pgAppointmentStore) Get(ctx context.Context, scope Scope, id int) (models.Appointment, error) {
+	var a models.Appointment
+	sqlStatement := `
+		SELECT ` + appointmentColumns + ` FROM appointments
+		WHERE id = $1 AND clinic_id = $2 AND ($3::int IS NULL OR pet_id IN (SELECT id FROM pets WHERE owner_id = $3))`
+	err := scanAppointment(s.db.QueryRowContext(ctx, sqlStatement, id, scope.ClinicID, ownerFilter(scope)), &a)
+	return a, notFound(err)
+}
+
+// lockForBooking validates the pet and vet of a booking and locks their rows until the
+// transaction ends, so concurrent bookings for the same pet or vet are checked one at a time
+func lockForBooking(ctx context.Context, tx *sql.Tx, scope Scope, a *models.Appointment) error {
+	var id int
+	sqlStatement := `
+		SELECT id FROM pets
+		WHERE id = $1 AND clinic_id = $2 AND ($3::int IS NULL OR owner_id = $3)
+		FOR NO KEY UPDATE`
+	err := tx.QueryRowContext(ctx, sqlStatement, a.PetID, scope.ClinicID, ownerFilter(scope)).Scan(&id)
+	if err == sql.ErrNoRows {
+		return &ReferenceError{Entity: "pet", ID: a.PetID}
+	}
+	if err != nil {
+		return err
+	}
+	if a.VetID == 0 {
+		return nil
+	}
+	sqlStatement = `
+		SELECT id FROM staff
+		WHERE id = $1 AND clinic_id = $2 AND role = 'vet'
+		FOR NO KEY UPDATE`
+	err = tx.QueryRowContext(ctx, sqlStatement, a.VetID, scope.ClinicID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return &ReferenceError{Entity: "vet", ID: a.VetID}
+	}
+	return err
+}
+
+// findConflict returns a *ConflictError if an appointment of the pet or vet, other than
+// those in exclude, overlaps a
+func findConflict(ctx context.Context, tx *sql.Tx, scope Scope, a *models.Appointment, start time.Time, exclude []int64) error {
+	end := start.Add(time.Duration(a.DurationMinutes) * time.Minute)
+	sqlStatement := `
+		SELECT ` + appointmentColumns + ` FROM appointments
+		WHERE clinic_id = $1 AND id <> ALL($2) AND ` + activeAppointment + `
+		AND (pet_id = $3 OR ($4::int IS NOT NULL AND vet_id = $4))
+		AND starts_at < $6 AND starts_at + make_interval(mins => duration_minutes) > $5
+		ORDER BY starts_at, id
+		LIMIT 1`
+	var clash models.Appointment
+	err := scanAppointment(tx.QueryRowContext(ctx, sqlStatement, scope.ClinicID, pq.Array(exclude), a.PetID, nullableID(a.VetID), start, end), &clash)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	with := "pet"
+	if clash.PetID != a.PetID {
+		with = "vet"
+	}
+	return &ConflictError{With: with, Appointment: clash}
+}
+
+// book runs the checks shared by Create and Update and then write for each appointment of
+// the batch, all inside one transaction. The stored rows of the batch are not treated as
+// conflicts since they are being replaced.
+func (s *pgAppointmentStore) book(ctx context.Context, scope Scope, batch []*models.Appointment, write func(tx *sql.Tx, a *models.Appointment, start time.Time) error) error {
+	starts := make([]time.Time, len(batch))
+	var exclude []int64
+	for i, a := range batch {
+		start, err := time.Parse(time.RFC3339, a.StartsAt)
+		if err != nil {
+			return err
+		}
+		starts[i] = start
+		if a.ID != 0 {
+			exclude = append(exclude, int64(a.ID))
+		}
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for i, a := range batch {
+		if err := lockForBooking(ctx, tx, scope, a); err != nil {
+			return err
+		}
+		if err := findConflict(ctx, tx, scope, a, starts[i], exclude); err != nil {
+			return err
+		}
+		if err := write(tx, a, starts[i]); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	for i, a := range batch {
+		a.StartsAt = starts[i].UTC().Format(time.RFC3339)
+	}
+	return nil
+}
+
+// insertAppointment writes a new scheduled appointment row and fills in its id
+func insertAppointment(ctx context.Context, tx *sql.Tx, scope Scope, a *models.Appointment, start time.Time) error {
+	a.Status = models.AppointmentScheduled
+	sqlStatement := `
+		INSERT INTO appointments (pet_id, vet_id, starts_at, duration_minutes, reason, series_id, clinic_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id`
+	return tx.QueryRowContext(ctx, sqlStatement, a.PetID, nullableID(a.VetID), start, a.DurationMinutes, a.Reason, nullableID(a.SeriesID), scope.ClinicID).Scan(&a.ID)
+}
+
+// updateAppointment rewrites an appointment row; its series and status are kept
+func updateAppointment(ctx context.Context, tx *sql.Tx, scope Scope, a *models.Appointment, start time.Time) error {
+	var seriesID sql.NullInt64
+	sqlStatement := `
+		UPDATE appointments
+		SET pet_id = $1, vet_id = $2, starts_at = $3, duration_minutes = $4, reason = $5
+		WHERE id = $6 AND clinic_id = $7 AND ($8::int IS NULL OR pet_id IN (SELECT id FROM pets WHERE owner_id = $8))
+		RETURNING series_id, status`
+	err := tx.QueryRowContext(ctx, sqlStatement, a.PetID, nullableID(a.VetID), start, a.DurationMinutes, a.Reason, a.ID, scope.ClinicID, ownerFilter(scope)).Scan(&seriesID, &a.Status)
+	if err != nil {
+		return notFound(err)
+	}
+	a.SeriesID = int(seriesID.Int64)
+	return nil
+}
+
+func (s *pgAppointmentStore) Create(ctx context.Context, scope Scope, a *models.Appointment) error {
+	a.SeriesID = 0
+	return s.book(ctx, scope, []*models.Appointment{a}, func(tx *sql.Tx, a *models.Appointment, start time.Time) error {
+		return insertAppointment(ctx, tx, scope, a, start)
+	})
+}
+
+func (s *pgAppointmentStore) Update(ctx context.Context, scope Scope, a *models.Appointment) error {
+	// The appointment may only be moved to another pet visible in the scope (checked by lockForBooking)
+	return s.book(ctx, scope, []*models.Appointment{a}, func(tx *sql.Tx, a *models.Appointment, start time.Time) error {
+		return updateAppointment(ctx, tx, scope, a, start)
+	})
+}
+
+func (s *pgAppointmentStore) CreateSeries(ctx context.Context, scope Scope, series *models.AppointmentSeries) error {
+	batch := make([]*models.Appointment, len(series.Appointments))
+	for i := range series.Appointments {
+		batch[i] = &series.Appointments[i]
+		batch[i].ID = 0
+	}
+	seriesID := 0
+	err := s.book(ctx, scope, batch, func(tx *sql.Tx, a *models.Appointment, start time.Time) error {
+		if seriesID == 0 {
+			sqlStatement := `INSERT INTO appointment_series (clinic_id, rrule) VALUES ($1, $2) RETURNING id`
+			if err := tx.QueryRowContext(ctx, sqlStatement, scope.ClinicID, series.RRule).Scan(&seriesID); err != nil {
+				return err
+			}
+		}
+		a.SeriesID = seriesID
+		return insertAppointment(ctx, tx, scope, a, start)
+	})
+	if err != nil {
+		return err
+	}
+	series.ID = seriesID
+	return nil
+}
+
+func (s *pgAppointmentStore) Following(ctx context.Context, scope Scope, id int) ([]models.Appointment, error) {
+	a, err := s.Get(ctx, scope, id)
+	if err != nil {
+		return nil, err
+	}
+	if a.SeriesID == 0 {
+		return []models.Appointment{a}, nil
+	}
+	sqlStatement := `
+		SELECT ` + appointmentColumns + ` FROM appointments
+		WHERE clinic_id = $1 AND ($2::int IS NULL OR pet_id IN (SELECT id FROM pets WHERE owner_id = $2))
+		AND series_id = $3 AND (starts_at, id) >= ($4::timestamptz, $5)
+		ORDER BY starts_at, id`
+	rows, err := s.db.QueryContext(ctx, sqlStatement, scope.ClinicID, ownerFilter(scope), a.SeriesID, a.StartsAt, a.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	appointments := []models.Appointment{}
+	for rows.Next() {
+		var a models.Appointment
+		if err := scanAppointment(rows, &a); err != nil {
+			return nil, err
+		}
+		appointments = append(appointments, a)
+	}
+	return appointments, rows.Err()
+}
+
+func (s *pgAppointmentStore) UpdateMany(ctx context.Context, scope Scope, appointments []models.Appointment) error {
+	batch := make([]*models.Appointment, len(appointments))
+	for i := range appointments {
+		batch[i] = &appointments[i]
+	}
+	return s.book(ctx, scope, batch, func(tx *sql.Tx, a *models.Appointment, start time.Time) error {
+		return updateAppointment(ctx, tx, scope, a, start)
+	})
+}
+
+func (s *pgAppointmentStore) DeleteMany(ctx context.Context, scope Scope, ids []int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	keys := make([]int64, len(ids))
+	for i, id := range ids {
+		keys[i] = int64(id)
+	}
+	sqlStatement := `
+		DELETE FROM appointments
+		WHERE id = ANY($1) AND clinic_id = $2 AND ($3::int IS NULL OR pet_id IN (SELECT id FROM pets WHERE owner_id = $3))`
+	res, err := tx.ExecContext(ctx, sqlStatement, pq.Array(keys), scope.ClinicID, ownerFilter(scope))
+	if err != nil {
+		return err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if int(count) != len(ids) {
+		return ErrNotFound
+	}
+	return tx.Commit()
+}
+
+func (s *pgAppointmentStore) Delete(ctx context.Context, scope Scope, id int) error {
+	sqlStatement := `
+		DELETE FROM appointments
+		WHERE id = $1 AND clinic_id = $2 AND ($3::int IS NULL OR pet_id IN (SELECT id FROM pets WHERE owner_id = $3))`
+	res, err := s.db.ExecContext(ctx, sqlStatement, id, scope.ClinicID, ownerFilter(scope))
+	if err != nil {
+		return err
+	}
+	return checkAffected(res)
+}
+
+func (s *pgAppointmentStore) Transition(ctx context.Context, scope Scope, ids []int, from []string, ev models.AppointmentStatusEvent) ([]models.Appointment, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	appointments := make([]models.Appointment, 0, len(ids))
+	for _, id := range ids {
+		var a models.Appointment
+		sqlStatement := `
+			SELECT ` + appointmentColumns + ` FROM appointments
+			WHERE id = $1 AND clinic_id = $2 AND ($3::int IS NULL OR pet_id IN (SELECT id FROM pets WHERE owner_id = $3))
+			FOR UPDATE`
+		if err := scanAppointment(tx.QueryRowContext(ctx, sqlStatement, id, scope.ClinicID, ownerFilter(scope)), &a); err != nil {
+			return nil, notFound(err)
+		}
+		if !containsString(from, a.Status) {
+			return nil, &TransitionError{AppointmentID: id, From: a.Status, To: ev.ToStatus}
+		}
+		sqlStatement = `
+			INSERT INTO appointment_status_events (appointment_id, from_status, to_status, reason, user_id)
+			VALUES ($1, $2, $3, $4, $5)`
+		if _, err := tx.ExecContext(ctx, sqlStatement, id, a.Status, ev.ToStatus, ev.Reason, nullableID(ev.UserID)); err != nil {
+			return nil, err
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE appointments SET status = $1 WHERE id = $2`, ev.ToStatus, id); err != nil {
+			return nil, err
+		}
+		a.Status = ev.ToStatus
+		appointments = append(appointments, a)
+	}
+	return appointments, tx.Commit()
+}
+
+func (s *pgAppointmentStore) History(ctx context.Context, scope Scope, id int) ([]models.AppointmentStatusEvent, error) {
+	if _, err := s.Get(ctx, scope, id); err != nil {
+		return nil, err
+	}
+	sqlStatement := `
+		SELECT id, appointment_id, from_status, to_status, reason, user_id, created_at
+		FROM appointment_status_events
+		WHERE appointment_id = $1
+		ORDER BY id`
+	rows, err := s.db.QueryContext(ctx, sqlStatement, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	events := []models.AppointmentStatusEvent{}
+	for rows.Next() {
+		var ev models.AppointmentStatusEvent
+		var userID sql.NullInt64
+		var createdAt time.Time
+		if err := rows.Scan(&ev.ID, &ev.AppointmentID, &ev.FromStatus, &ev.ToStatus, &ev.Reason, &userID, &createdAt); err != nil {
+			return nil, err
+		}
+		ev.UserID = int(userID.Int64)
+		ev.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+		events = append(events, ev)
+	}
+	return events, rows.Err()
+}
+
+func (s *pgAppointmentStore) ListForVet(ctx context.Context, clinicID int, vetID int, from, to time.Time) ([]models.Appointment, error) {
+	sqlStatement := `
+		SELECT ` + appointmentColumns + ` FROM appointments
+		WHERE clinic_id = $1 AND vet_id = $2 AND ` + activeAppointment + `
+		AND starts_at < $4 AND starts_at + make_interval(mins => duration_minutes) > $3
+		ORDER BY starts_at, id`
+	rows, err := s.db.QueryContext(ctx, sqlStatement, clinicID, vetID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	appointments := []models.Appointment{}
+	for rows.Next() {
+		var a models.Appointment
+		if err := scanAppointment(rows, &a); err != nil {
+			return nil, err
+		}
+		appointments = append(appointments, a)
+	}
+	return appointments, rows.Err()
+}
+
+func (s *pgAppointmentStore) Calendar(ctx context.Context, scope Scope, filter AppointmentFilter) ([]models.CalendarEntry, error) {
+	sqlStatement := `
+		SELECT a.id, a.pet_id, a.vet_id, a.starts_at, a.duration_minutes, a.reason, a.series_id, a.status,
+		       p.name, COALESCE(o.name, ''), COALESCE(st.name, ''), a.revision, a.updated_at
+		FROM appointments a
+		JOIN pets p ON p.id = a.pet_id
+		LEFT JOIN owners o ON o.id = p.owner_id
+		LEFT JOIN staff st ON st.id = a.vet_id
+		WHERE a.clinic_id = $1 AND ($2::int IS NULL OR p.owner_id = $2)
+		AND ($3::int IS NULL OR a.pet_id = $3)
+		AND ($4::int IS NULL OR a.vet_id = $4)
+		AND ($5::timestamptz IS NULL OR a.starts_at >= $5)
+		AND ($6::timestamptz IS NULL OR a.starts_at < $6)
+		AND ($7::int IS NULL OR a.series_id = $7)
+		AND ($8::text[] IS NULL OR a.status = ANY($8))
+		AND ($9::int IS NULL OR p.owner_id = $9)
+		ORDER BY a.starts_at, a.id`
+	rows, err := s.db.QueryContext(ctx, sqlStatement, scope.ClinicID, ownerFilter(scope), nullableID(filter.PetID), nullableID(filter.VetID),
+		nullableTime(filter.From), nullableTime(filter.To), nullableID(filter.SeriesID), pq.Array(filter.Statuses), nullableID(filter.OwnerID))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	entries := []models.CalendarEntry{}
+	for rows.Next() {
+		var e models.CalendarEntry
+		var vetID, seriesID sql.NullInt64
+		var startsAt, updatedAt time.Time
+		var reason sql.NullString
+		if err := rows.Scan(&e.ID, &e.PetID, &vetID, &startsAt, &e.DurationMinutes, &reason, &seriesID, &e.Status,
+			&e.PetName, &e.OwnerName, &e.VetName, &e.Revision, &updatedAt); err != nil {
+			return nil, err
+		}
+		e.UpdatedAt = updatedAt.UTC().Format(time.RFC3339)
+		e.VetID = int(vetID.Int64)
+		e.SeriesID = int(seriesID.Int64)
+		e.StartsAt = startsAt.UTC().Format(time.RFC3339)
+		e.Reason = reason.String
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,120 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"pets_project/internal/models"
+)
+
+// === Appointment Types =============================================================
+type pgAppointmentTypeStore struct {
+	db *sql.DB
+}
+
+func (s *pgAppointmentTypeStore) List(ctx context.Context, clinicID int) ([]models.AppointmentType, error) {
+	sqlStatement := `SELECT id, name, duration_minutes FROM appointment_types WHERE clinic_id = $1 ORDER BY name, id`
+	rows, err := s.db.QueryContext(ctx, sqlStatement, clinicID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	types := []models.AppointmentType{}
+	for rows.Next() {
+		var t models.AppointmentType
+		if err := rows.Scan(&t.ID, &t.Name, &t.DurationMinutes); err != nil {
+			return nil, err
+		}
+		types = append(types, t)
+	}
+	return types, rows.Err()
+}
+
+func (s *pgAppointmentTypeStore) Get(ctx context.Context, clinicID int, id int) (models.AppointmentType, error) {
+	var t models.AppointmentType
+	sqlStatement := `SELECT id, name, duration_minutes FROM appointment_types WHERE id = $1 AND clinic_id = $2`
+	err := s.db.QueryRowContext(ctx, sqlStatement, id, clinicID).Scan(&t.ID, &t.Name, &t.DurationMinutes)
+	return t, notFound(err)
+}
+
+func (s *pgAppointmentTypeStore) Create(ctx context.Context, clinicID int, t *models.AppointmentType) error {
+	sqlStatement := `INSERT INTO appointment_types (clinic_id, name, duration_minutes) VALUES ($1, $2, $3) RETURNING id`
+	err := s.db.QueryRowContext(ctx, sqlStatement, clinicID, t.Name, t.DurationMinutes).Scan(&t.ID)
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	return err
+}
+
+func (s *pgAppointmentTypeStore) Update(ctx context.Context, clinicID int, t *models.AppointmentType) error {
+	sqlStatement := `UPDATE appointment_types SET name = $1, duration_minutes = $2 WHERE id = $3 AND clinic_id = $4`
+	res, err := s.db.ExecContext(ctx, sqlStatement, t.Name, t.DurationMinutes, t.ID, clinicID)
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	if err != nil {
+		return err
+	}
+	return checkAffected(res)
+}
+
+func (s *pgAppointmentTypeStore) Delete(ctx context.Context, clinicID int, id int) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM appointment_types WHERE id = $1 AND clinic_id = $2`, id, clinicID)
+	if err != nil {
+		return err
+	}
+	return checkAffected(res)
+}
+
+// === Blocked Periods ===============================================================
+type pgBlockedPeriodStore struct {
+	db *sql.DB
+}
+
+func (s *pgBlockedPeriodStore) List(ctx context.Context, clinicID int, from, to time.Time) ([]models.BlockedPeriod, error) {
+	sqlStatement := `
+		SELECT id, staff_id, starts_at, ends_at, reason FROM blocked_periods
+		WHERE clinic_id = $1 AND starts_at < $3 AND ends_at > $2
+		ORDER BY starts_at, id`
+	rows, err := s.db.QueryContext(ctx, sqlStatement, clinicID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	periods := []models.BlockedPeriod{}
+	for rows.Next() {
+		var b models.BlockedPeriod
+		var staffID sql.NullInt64
+		var startsAt, endsAt time.Time
+		if err := rows.Scan(&b.ID, &staffID, &startsAt, &endsAt, &b.Reason); err != nil {
+			return nil, err
+		}
+		b.StaffID = int(staffID.Int64)
+		b.StartsAt = startsAt.UTC().Format(time.RFC3339)
+		b.EndsAt = endsAt.UTC().Format(time.RFC3339)
+		periods = append(periods, b)
+	}
+	return periods, rows.Err()
+}
+
+func (s *pgBlockedPeriodStore) Create(ctx context.Context, clinicID int, b *models.BlockedPeriod) error {
+	if b.StaffID != 0 {
+		if err := checkInClinic(ctx, s.db, "staff", "staff member", b.StaffID, clinicID); err != nil {
+			return err
+		}
+	}
+	sqlStatement := `
+		INSERT INTO blocked_periods (clinic_id, staff_id, starts_at, ends_at, reason)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id`
+	return s.db.QueryRowContext(ctx, sqlStatement, clinicID, nullableID(b.StaffID), b.StartsAt, b.EndsAt, b.Reason).Scan(&b.ID)
+}
+
+func (s *pgBlockedPeriodStore) Delete(ctx context.Context, clinicID int, id int) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM blocked_periods WHERE id = $1 AND clinic_id = $2`, id, clinicID)
+	if err != nil {
+		return err
+	}
+	return checkAffected(res)
+}
@@ -0,0 +1,186 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"pets_project/internal/models"
+)
+
+type pgFileRecordStore struct {
+	db *sql.DB
+}
+
+const fileRecordColumns = `id, pet_id, file_name, file_path, document_id, version, uploaded_at, size_bytes, sha256,
+	content_type, scan_status, scan_signature, scanned_at`
+
+// scanFileRecord reads the columns listed in fileRecordColumns
+func scanFileRecord(row interface{ Scan(...interface{}) error }, fr *models.FileRecord) error {
+	var uploadedAt time.Time
+	var size sql.NullInt64
+	var sha, contentType, signature sql.NullString
+	var scannedAt sql.NullTime
+	if err := row.Scan(&fr.ID, &fr.PetID, &fr.FileName, &fr.FilePath, &fr.DocumentID, &fr.Version, &uploadedAt, &size, &sha,
+		&contentType, &fr.ScanStatus, &signature, &scannedAt); err != nil {
+		return err
+	}
+	fr.UploadedAt = uploadedAt.Format(time.RFC3339)
+	fr.Size = size.Int64
+	fr.SHA256 = sha.String
+	fr.ContentType = contentType.String
+	fr.ScanSignature = signature.String
+	if scannedAt.Valid {
+		fr.ScannedAt = scannedAt.Time.Format(time.RFC3339)
+	}
+	return nil
+}
+
+func (s *pgFileRecordStore) ListByPet(ctx context.Context, scope Scope, petID int) ([]models.FileRecord, error) {
+	sqlStatement := `
+		SELECT ` + fileRecordColumns + ` FROM file_records
+		WHERE pet_id = $1 AND clinic_id = $2 AND ($3::int IS NULL OR pet_id IN (SELECT id FROM pets WHERE owner_id = $3))
+		AND NOT EXISTS (
+			SELECT 1 FROM file_records newer
+			WHERE newer.document_id = file_records.document_id AND newer.version > file_records.version)
+		ORDER BY id`
+	rows, err := s.db.QueryContext(ctx, sqlStatement, petID, scope.ClinicID, ownerFilter(scope))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	files := []models.FileRecord{}
+	for rows.Next() {
+		var fr models.FileRecord
+		if err := scanFileRecord(rows, &fr); err != nil {
+			return nil, err
+		}
+		files = append(files, fr)
+	}
+	return files, rows.Err()
+}
+
+func (s *pgFileRecordStore) Get(ctx context.Context, scope Scope, id int) (models.FileRecord, error) {
+	var fr models.FileRecord
+	sqlStatement := `
+		SELECT ` + fileRecordColumns + ` FROM file_records
+		WHERE id = $1 AND clinic_id = $2 AND ($3::int IS NULL OR pet_id IN (SELECT id FROM pets WHERE owner_id = $3))`
+	err := scanFileRecord(s.db.QueryRowContext(ctx, sqlStatement, id, scope.ClinicID, ownerFilter(scope)), &fr)
+	return fr, notFound(err)
+}
+
+func (s *pgFileRecordStore) Create(ctx context.Context, scope Scope, f *models.FileRecord) error {
+	if err := checkPetInScope(ctx, s.db, scope, f.PetID); err != nil {
+		return err
+	}
+	// A new document takes the id of its first version, drawn before the insert
+	sqlStatement := `
+		INSERT INTO file_records (id, document_id, version, pet_id, file_name, file_path, clinic_id, size_bytes, sha256,
+			content_type, scan_status)
+		SELECT id, id, 1, $1, $2, $3, $4, $5, $6, $7, COALESCE($8, 'unscanned')
+		FROM (SELECT nextval(pg_get_serial_sequence('file_records', 'id')) AS id) AS new
+		RETURNING id, document_id, version, uploaded_at, scan_status`
+	args := []interface{}{f.PetID, f.FileName, f.FilePath, scope.ClinicID, f.Size,
+		nullableString(f.SHA256), nullableString(f.ContentType), nullableString(f.ScanStatus)}
+	if f.DocumentID != 0 {
+		// HAVING yields no row when the pet has no such document
+		sqlStatement = `
+			INSERT INTO file_records (document_id, version, pet_id, file_name, file_path, clinic_id, size_bytes, sha256,
+				content_type, scan_status)
+			SELECT $9, MAX(version) + 1, $1, $2, $3, $4, $5, $6, $7, COALESCE($8, 'unscanned')
+			FROM file_records
+			WHERE document_id = $9 AND pet_id = $1 AND clinic_id = $4
+			HAVING COUNT(*) > 0
+			RETURNING id, document_id, version, uploaded_at, scan_status`
+		args = append(args, f.DocumentID)
+	}
+	var uploadedAt time.Time
+	err := s.db.QueryRowContext(ctx, sqlStatement, args...).Scan(&f.ID, &f.DocumentID, &f.Version, &uploadedAt, &f.ScanStatus)
+	if err == sql.ErrNoRows {
+		return &ReferenceError{Entity: "document", ID: f.DocumentID}
+	}
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	if err != nil {
+		return err
+	}
+	f.UploadedAt = uploadedAt.Format(time.RFC3339)
+	return nil
+}
+
+func (s *pgFileRecordStore) Versions(ctx context.Context, scope Scope, documentID int) ([]models.FileRecord, error) {
+	sqlStatement := `
+		SELECT ` + fileRecordColumns + ` FROM file_records
+		WHERE document_id = $1 AND clinic_id = $2 AND ($3::int IS NULL OR pet_id IN (SELECT id FROM pets WHERE owner_id = $3))
+		ORDER BY version`
+	rows, err := s.db.QueryContext(ctx, sqlStatement, documentID, scope.ClinicID, ownerFilter(scope))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	files := []models.FileRecord{}
+	for rows.Next() {
+		var fr models.FileRecord
+		if err := scanFileRecord(rows, &fr); err != nil {
+			return nil, err
+		}
+		files = append(files, fr)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, ErrNotFound
+	}
+	return files, nil
+}
+
+func (s *pgFileRecordStore) Delete(ctx context.Context, scope Scope, id int) (models.FileRecord, error) {
+	var fr models.FileRecord
+	sqlStatement := `
+		DELETE FROM file_records
+		WHERE id = $1 AND clinic_id = $2 AND ($3::int IS NULL OR pet_id IN (SELECT id FROM pets WHERE owner_id = $3))
+		RETURNING ` + fileRecordColumns
+	err := scanFileRecord(s.db.QueryRowContext(ctx, sqlStatement, id, scope.ClinicID, ownerFilter(scope)), &fr)
+	return fr, notFound(err)
+}
+
+func (s *pgFileRecordStore) PendingScans(ctx context.Context, before time.Time, limit int) ([]models.FileRecord, error) {
+	// SKIP LOCKED keeps two servers from taking the same files
+	sqlStatement := `
+		UPDATE file_records SET scan_attempted_at = NOW()
+		WHERE id IN (
+			SELECT id FROM file_records
+			WHERE scan_status = 'pending' AND uploaded_at < $1
+				AND (scan_attempted_at IS NULL OR scan_attempted_at < $1)
+			ORDER BY scan_attempted_at NULLS FIRST, uploaded_at, id
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED)
+		RETURNING ` + fileRecordColumns
+	rows, err := s.db.QueryContext(ctx, sqlStatement, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	files := []models.FileRecord{}
+	for rows.Next() {
+		var fr models.FileRecord
+		if err := scanFileRecord(rows, &fr); err != nil {
+			return nil, err
+		}
+		files = append(files, fr)
+	}
+	return files, rows.Err()
+}
+
+func (s *pgFileRecordStore) SetScanResult(ctx context.Context, id int, status, signature string, scannedAt time.Time) error {
+	sqlStatement := `
+		UPDATE file_records SET scan_status = $2, scan_signature = $3, scanned_at = $4
+		WHERE id = $1 AND scan_status = 'pending'`
+	res, err := s.db.ExecContext(ctx, sqlStatement, id, status, nullableString(signature), scannedAt)
+	if err != nil {
+		return err
+	}
+	return checkAffected(res)
+}
@@ -0,0 +1,30 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type pgDownloadLinkStore struct {
+	db *sql.DB
+}
+
+func (s *pgDownloadLinkStore) Use(ctx context.Context, nonce string, expiresAt time.Time) (bool, error) {
+	sqlStatement := `INSERT INTO used_download_links (nonce, expires_at) VALUES ($1, $2) ON CONFLICT (nonce) DO NOTHING`
+	res, err := s.db.ExecContext(ctx, sqlStatement, nonce, expiresAt)
+	if err != nil {
+		return false, err
+	}
+	count, err := res.RowsAffected()
+	return count == 1, err
+}
+
+func (s *pgDownloadLinkStore) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM used_download_links WHERE expires_at < $1`, now)
+	if err != nil {
+		return 0, err
+	}
+	count, err := res.RowsAffected()
+	return int(count), err
+}
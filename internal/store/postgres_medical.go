@@ -0,0 +1,92 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"pets_project/internal/models"
+)
+
+type pgMedicalEntryStore struct {
+	db *sql.DB
+}
+
+const medicalEntryColumns = `id, pet_id, entry_type, description, to_char(occurred_on, 'YYYY-MM-DD'), author_user_id, amends_id, created_at`
+
+// scanMedicalEntry reads the columns listed in medicalEntryColumns
+func scanMedicalEntry(row interface{ Scan(...interface{}) error }, e *models.MedicalEntry) error {
+	var authorID, amendsID sql.NullInt64
+	var createdAt time.Time
+	if err := row.Scan(&e.ID, &e.PetID, &e.Type, &e.Description, &e.OccurredOn, &authorID, &amendsID, &createdAt); err != nil {
+		return err
+	}
+	e.AuthorUserID = int(authorID.Int64)
+	e.AmendsID = int(amendsID.Int64)
+	e.CreatedAt = createdAt.Format(time.RFC3339)
+	return nil
+}
+
+func (s *pgMedicalEntryStore) List(ctx context.Context, scope Scope, petID int, page Page) ([]models.MedicalEntry, string, error) {
+	k, err := resolvePage(page, medicalSortFields)
+	if err != nil {
+		return nil, "", err
+	}
+	sqlStatement := `
+		SELECT ` + medicalEntryColumns + ` FROM medical_entries
+		WHERE pet_id = $1 AND clinic_id = $2 AND ($3::int IS NULL OR pet_id IN (SELECT id FROM pets WHERE owner_id = $3))`
+	clauses, args := k.sqlClauses([]interface{}{petID, scope.ClinicID, ownerFilter(scope)})
+	rows, err := s.db.QueryContext(ctx, sqlStatement+clauses, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+	entries := []models.MedicalEntry{}
+	for rows.Next() {
+		var e models.MedicalEntry
+		if err := scanMedicalEntry(rows, &e); err != nil {
+			return nil, "", err
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+	entries, next := trimPage(k, entries, medicalSortKey(k.field))
+	return entries, next, nil
+}
+
+func (s *pgMedicalEntryStore) Get(ctx context.Context, scope Scope, petID int, id int) (models.MedicalEntry, error) {
+	var e models.MedicalEntry
+	sqlStatement := `
+		SELECT ` + medicalEntryColumns + ` FROM medical_entries
+		WHERE id = $1 AND pet_id = $2 AND clinic_id = $3 AND ($4::int IS NULL OR pet_id IN (SELECT id FROM pets WHERE owner_id = $4))`
+	err := scanMedicalEntry(s.db.QueryRowContext(ctx, sqlStatement, id, petID, scope.ClinicID, ownerFilter(scope)), &e)
+	return e, notFound(err)
+}
+
+func (s *pgMedicalEntryStore) Create(ctx context.Context, scope Scope, e *models.MedicalEntry) error {
+	if err := checkPetInScope(ctx, s.db, scope, e.PetID); err != nil {
+		return err
+	}
+	if e.AmendsID != 0 {
+		var exists bool
+		sqlStatement := `SELECT EXISTS (SELECT 1 FROM medical_entries WHERE id = $1 AND pet_id = $2)`
+		if err := s.db.QueryRowContext(ctx, sqlStatement, e.AmendsID, e.PetID).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return &ReferenceError{Entity: "medical entry", ID: e.AmendsID}
+		}
+	}
+	var occurredOn interface{}
+	if e.OccurredOn != "" {
+		occurredOn = e.OccurredOn
+	}
+	sqlStatement := `
+		INSERT INTO medical_entries (clinic_id, pet_id, entry_type, description, occurred_on, author_user_id, amends_id)
+		VALUES ($1, $2, $3, $4, COALESCE($5::date, CURRENT_DATE), $6, $7)
+		RETURNING ` + medicalEntryColumns
+	row := s.db.QueryRowContext(ctx, sqlStatement, scope.ClinicID, e.PetID, e.Type, e.Description, occurredOn, nullableID(e.AuthorUserID), nullableID(e.AmendsID))
+	return scanMedicalEntry(row, e)
+}
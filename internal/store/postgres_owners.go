@@ -0,0 +1,115 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"pets_project/internal/models"
+)
+
+type pgOwnerStore struct {
+	db *sql.DB
+}
+
+// scanOwner reads the columns selected by the owner queries below
+func scanOwner(row interface{ Scan(...interface{}) error }, o *models.Owner) error {
+	var userID sql.NullInt64
+	if err := row.Scan(&o.ID, &o.Name, &o.Contact, &o.Email, &userID); err != nil {
+		return err
+	}
+	o.UserID = int(userID.Int64)
+	return nil
+}
+
+func (s *pgOwnerStore) List(ctx context.Context, scope Scope, filter OwnerFilter, page Page) ([]models.Owner, string, error) {
+	k, err := resolvePage(page, ownerSortFields)
+	if err != nil {
+		return nil, "", err
+	}
+	sqlStatement := `
+		SELECT id, name, contact, email, user_id FROM owners
+		WHERE clinic_id = $1 AND ($2::int IS NULL OR id = $2)
+		AND ($3::text = '' OR name ILIKE '%' || $3 || '%')
+		AND ($4::text = '' OR lower(email) = lower($4))`
+	clauses, args := k.sqlClauses([]interface{}{scope.ClinicID, ownerFilter(scope), escapeLike(filter.Name), filter.Email})
+	rows, err := s.db.QueryContext(ctx, sqlStatement+clauses, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+	owners := []models.Owner{}
+	for rows.Next() {
+		var o models.Owner
+		if err := scanOwner(rows, &o); err != nil {
+			return nil, "", err
+		}
+		owners = append(owners, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+	owners, next := trimPage(k, owners, ownerSortKey(k.field))
+	return owners, next, nil
+}
+
+func (s *pgOwnerStore) Get(ctx context.Context, scope Scope, id int) (models.Owner, error) {
+	var o models.Owner
+	sqlStatement := `
+		SELECT id, name, contact, email, user_id FROM owners
+		WHERE id = $1 AND clinic_id = $2 AND ($3::int IS NULL OR id = $3)`
+	err := scanOwner(s.db.QueryRowContext(ctx, sqlStatement, id, scope.ClinicID, ownerFilter(scope)), &o)
+	return o, notFound(err)
+}
+
+func (s *pgOwnerStore) GetByUser(ctx context.Context, clinicID int, userID int) (models.Owner, error) {
+	var o models.Owner
+	sqlStatement := `SELECT id, name, contact, email, user_id FROM owners WHERE user_id = $1 AND clinic_id = $2`
+	err := scanOwner(s.db.QueryRowContext(ctx, sqlStatement, userID, clinicID), &o)
+	return o, notFound(err)
+}
+
+func (s *pgOwnerStore) Create(ctx context.Context, scope Scope, o *models.Owner) error {
+	if o.UserID != 0 {
+		if err := checkInClinic(ctx, s.db, "users", "user", o.UserID, scope.ClinicID); err != nil {
+			return err
+		}
+	}
+	sqlStatement := `
+		INSERT INTO owners (name, contact, email, user_id, clinic_id)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id`
+	err := s.db.QueryRowContext(ctx, sqlStatement, o.Name, o.Contact, o.Email, nullableID(o.UserID), scope.ClinicID).Scan(&o.ID)
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	return err
+}
+
+func (s *pgOwnerStore) Update(ctx context.Context, scope Scope, o *models.Owner) error {
+	if o.UserID != 0 {
+		if err := checkInClinic(ctx, s.db, "users", "user", o.UserID, scope.ClinicID); err != nil {
+			return err
+		}
+	}
+	sqlStatement := `
+		UPDATE owners
+		SET name = $1, contact = $2, email = $3, user_id = $4
+		WHERE id = $5 AND clinic_id = $6 AND ($7::int IS NULL OR id = $7)`
+	res, err := s.db.ExecContext(ctx, sqlStatement, o.Name, o.Contact, o.Email, nullableID(o.UserID), o.ID, scope.ClinicID, ownerFilter(scope))
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	if err != nil {
+		return err
+	}
+	return checkAffected(res)
+}
+
+func (s *pgOwnerStore) Delete(ctx context.Context, scope Scope, id int) error {
+	sqlStatement := `DELETE FROM owners WHERE id = $1 AND clinic_id = $2 AND ($3::int IS NULL OR id = $3)`
+	res, err := s.db.ExecContext(ctx, sqlStatement, id, scope.ClinicID, ownerFilter(scope))
+	if err != nil {
+		return err
+	}
+	return checkAffected(res)
+}
@@ -0,0 +1,88 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"pets_project/internal/models"
+)
+
+type pgPetStore struct {
+	db *sql.DB
+}
+
+func (s *pgPetStore) List(ctx context.Context, scope Scope, filter PetFilter, page Page) ([]models.Pet, string, error) {
+	k, err := resolvePage(page, petSortFields)
+	if err != nil {
+		return nil, "", err
+	}
+	sqlStatement := `
+		SELECT id, name, species, breed, owner_id FROM pets
+		WHERE clinic_id = $1 AND ($2::int IS NULL OR owner_id = $2)
+		AND ($3::text = '' OR lower(species) = lower($3))
+		AND ($4::text = '' OR lower(breed) = lower($4))
+		AND ($5::int IS NULL OR owner_id = $5)`
+	clauses, args := k.sqlClauses([]interface{}{scope.ClinicID, ownerFilter(scope), filter.Species, filter.Breed, nullableID(filter.OwnerID)})
+	rows, err := s.db.QueryContext(ctx, sqlStatement+clauses, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+	pets := []models.Pet{}
+	for rows.Next() {
+		var p models.Pet
+		if err := rows.Scan(&p.ID, &p.Name, &p.Species, &p.Breed, &p.OwnerID); err != nil {
+			return nil, "", err
+		}
+		pets = append(pets, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+	pets, next := trimPage(k, pets, petSortKey(k.field))
+	return pets, next, nil
+}
+
+func (s *pgPetStore) Get(ctx context.Context, scope Scope, id int) (models.Pet, error) {
+	var p models.Pet
+	sqlStatement := `
+		SELECT id, name, species, breed, owner_id FROM pets
+		WHERE id = $1 AND clinic_id = $2 AND ($3::int IS NULL OR owner_id = $3)`
+	err := s.db.QueryRowContext(ctx, sqlStatement, id, scope.ClinicID, ownerFilter(scope)).Scan(&p.ID, &p.Name, &p.Species, &p.Breed, &p.OwnerID)
+	return p, notFound(err)
+}
+
+func (s *pgPetStore) Create(ctx context.Context, scope Scope, p *models.Pet) error {
+	if err := checkInClinic(ctx, s.db, "owners", "owner", p.OwnerID, scope.ClinicID); err != nil {
+		return err
+	}
+	sqlStatement := `
+		INSERT INTO pets (name, species, breed, owner_id, clinic_id)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id`
+	return s.db.QueryRowContext(ctx, sqlStatement, p.Name, p.Species, p.Breed, p.OwnerID, scope.ClinicID).Scan(&p.ID)
+}
+
+func (s *pgPetStore) Update(ctx context.Context, scope Scope, p *models.Pet) error {
+	if err := checkInClinic(ctx, s.db, "owners", "owner", p.OwnerID, scope.ClinicID); err != nil {
+		return err
+	}
+	sqlStatement := `
+		UPDATE pets
+		SET name = $1, species = $2, breed = $3, owner_id = $4
+		WHERE id = $5 AND clinic_id = $6 AND ($7::int IS NULL OR owner_id = $7)`
+	res, err := s.db.ExecContext(ctx, sqlStatement, p.Name, p.Species, p.Breed, p.OwnerID, p.ID, scope.ClinicID, ownerFilter(scope))
+	if err != nil {
+		return err
+	}
+	return checkAffected(res)
+}
+
+func (s *pgPetStore) Delete(ctx context.Context, scope Scope, id int) error {
+	sqlStatement := `DELETE FROM pets WHERE id = $1 AND clinic_id = $2 AND ($3::int IS NULL OR owner_id = $3)`
+	res, err := s.db.ExecContext(ctx, sqlStatement, id, scope.ClinicID, ownerFilter(scope))
+	if err != nil {
+		return err
+	}
+	return checkAffected(res)
+}
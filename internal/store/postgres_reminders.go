@@ -0,0 +1,83 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"pets_project/internal/models"
+)
+
+type pgReminderStore struct {
+	db *sql.DB
+}
+
+func (s *pgReminderStore) Due(ctx context.Context, now, until time.Time, limit int) ([]models.Reminder, error) {
+	sqlStatement := `
+		SELECT a.id, a.starts_at, a.duration_minutes, COALESCE(a.reason, ''), p.name, o.name, o.email,
+		       COALESCE(st.name, ''), c.name, c.timezone
+		FROM appointments a
+		JOIN pets p ON p.id = a.pet_id
+		JOIN owners o ON o.id = p.owner_id
+		JOIN clinics c ON c.id = a.clinic_id
+		LEFT JOIN staff st ON st.id = a.vet_id
+		WHERE a.status = 'scheduled' AND a.starts_at > $1 AND a.starts_at <= $2 AND o.email <> ''
+		AND NOT EXISTS (
+			SELECT 1 FROM appointment_reminders r
+			WHERE r.appointment_id = a.id AND r.starts_at = a.starts_at
+			AND (r.sent_at IS NOT NULL OR r.claimed_at > $3)
+		)
+		ORDER BY a.starts_at, a.id
+		LIMIT $4`
+	rows, err := s.db.QueryContext(ctx, sqlStatement, now, until, now.Add(-ReminderClaimTTL), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	reminders := []models.Reminder{}
+	for rows.Next() {
+		var r models.Reminder
+		var startsAt time.Time
+		if err := rows.Scan(&r.AppointmentID, &startsAt, &r.DurationMinutes, &r.Reason, &r.PetName, &r.OwnerName, &r.OwnerEmail,
+			&r.VetName, &r.ClinicName, &r.ClinicTimezone); err != nil {
+			return nil, err
+		}
+		r.StartsAt = startsAt.UTC().Format(time.RFC3339)
+		reminders = append(reminders, r)
+	}
+	return reminders, rows.Err()
+}
+
+func (s *pgReminderStore) Claim(ctx context.Context, r models.Reminder, now time.Time) (bool, error) {
+	// Take over a claim only if it expired without the reminder being sent
+	sqlStatement := `
+		INSERT INTO appointment_reminders (appointment_id, starts_at, claimed_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (appointment_id, starts_at) DO UPDATE SET claimed_at = EXCLUDED.claimed_at
+		WHERE appointment_reminders.sent_at IS NULL AND appointment_reminders.claimed_at <= $4
+		RETURNING appointment_id`
+	var id int
+	err := s.db.QueryRowContext(ctx, sqlStatement, r.AppointmentID, r.StartsAt, now, now.Add(-ReminderClaimTTL)).Scan(&id)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *pgReminderStore) MarkSent(ctx context.Context, r models.Reminder, now time.Time) error {
+	sqlStatement := `UPDATE appointment_reminders SET sent_at = $1 WHERE appointment_id = $2 AND starts_at = $3`
+	res, err := s.db.ExecContext(ctx, sqlStatement, now, r.AppointmentID, r.StartsAt)
+	if err != nil {
+		return err
+	}
+	return checkAffected(res)
+}
+
+func (s *pgReminderStore) Release(ctx context.Context, r models.Reminder) error {
+	sqlStatement := `DELETE FROM appointment_reminders WHERE appointment_id = $1 AND starts_at = $2 AND sent_at IS NULL`
+	_, err := s.db.ExecContext(ctx, sqlStatement, r.AppointmentID, r.StartsAt)
+	return err
+}
@@ -0,0 +1,199 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+
+	"pets_project/internal/models"
+)
+
+type pgStaffStore struct {
+	db *sql.DB
+}
+
+const staffColumns = `id, user_id, name, role, specialties`
+
+// scanStaff reads the columns listed in staffColumns
+func scanStaff(row interface{ Scan(...interface{}) error }, st *models.Staff) error {
+	var userID sql.NullInt64
+	var specialties []string
+	if err := row.Scan(&st.ID, &userID, &st.Name, &st.Role, pq.Array(&specialties)); err != nil {
+		return err
+	}
+	st.UserID = int(userID.Int64)
+	st.Specialties = specialties
+	if st.Specialties == nil {
+		st.Specialties = []string{}
+	}
+	st.WorkingHours = []models.WorkingHours{}
+	return nil
+}
+
+// loadHours fills in the working hours of the given staff members
+func (s *pgStaffStore) loadHours(ctx context.Context, staff []models.Staff) error {
+	if len(staff) == 0 {
+		return nil
+	}
+	index := map[int]int{}
+	ids := make([]int64, len(staff))
+	for i, st := range staff {
+		index[st.ID] = i
+		ids[i] = int64(st.ID)
+	}
+	sqlStatement := `
+		SELECT staff_id, weekday, to_char(start_time, 'HH24:MI'), to_char(end_time, 'HH24:MI')
+		FROM staff_working_hours
+		WHERE staff_id = ANY($1)
+		ORDER BY staff_id, weekday, start_time`
+	rows, err := s.db.QueryContext(ctx, sqlStatement, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var staffID int
+		var h models.WorkingHours
+		if err := rows.Scan(&staffID, &h.Weekday, &h.Start, &h.End); err != nil {
+			return err
+		}
+		i := index[staffID]
+		staff[i].WorkingHours = append(staff[i].WorkingHours, h)
+	}
+	return rows.Err()
+}
+
+func (s *pgStaffStore) List(ctx context.Context, clinicID int, filter StaffFilter, page Page) ([]models.Staff, string, error) {
+	k, err := resolvePage(page, staffSortFields)
+	if err != nil {
+		return nil, "", err
+	}
+	sqlStatement := `
+		SELECT ` + staffColumns + ` FROM staff
+		WHERE clinic_id = $1
+		AND ($2::text = '' OR role = $2)
+		AND ($3::text = '' OR lower($3) = ANY (SELECT lower(unnest(specialties))))`
+	clauses, args := k.sqlClauses([]interface{}{clinicID, filter.Role, filter.Specialty})
+	rows, err := s.db.QueryContext(ctx, sqlStatement+clauses, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+	staff := []models.Staff{}
+	for rows.Next() {
+		var st models.Staff
+		if err := scanStaff(rows, &st); err != nil {
+			return nil, "", err
+		}
+		staff = append(staff, st)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+	staff, next := trimPage(k, staff, staffSortKey(k.field))
+	if err := s.loadHours(ctx, staff); err != nil {
+		return nil, "", err
+	}
+	return staff, next, nil
+}
+
+func (s *pgStaffStore) Get(ctx context.Context, clinicID int, id int) (models.Staff, error) {
+	var st models.Staff
+	sqlStatement := `SELECT ` + staffColumns + ` FROM staff WHERE id = $1 AND clinic_id = $2`
+	if err := scanStaff(s.db.QueryRowContext(ctx, sqlStatement, id, clinicID), &st); err != nil {
+		return st, notFound(err)
+	}
+	staff := []models.Staff{st}
+	if err := s.loadHours(ctx, staff); err != nil {
+		return st, err
+	}
+	return staff[0], nil
+}
+
+// replaceHours swaps the staff member's working hours for st.WorkingHours
+func replaceHours(ctx context.Context, tx *sql.Tx, st *models.Staff) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM staff_working_hours WHERE staff_id = $1`, st.ID); err != nil {
+		return err
+	}
+	for _, h := range st.WorkingHours {
+		sqlStatement := `INSERT INTO staff_working_hours (staff_id, weekday, start_time, end_time) VALUES ($1, $2, $3, $4)`
+		_, err := tx.ExecContext(ctx, sqlStatement, st.ID, h.Weekday, h.Start, h.End)
+		if isUniqueViolation(err) {
+			return ErrConflict
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *pgStaffStore) Create(ctx context.Context, clinicID int, st *models.Staff) error {
+	if st.UserID != 0 {
+		if err := checkInClinic(ctx, s.db, "users", "user", st.UserID, clinicID); err != nil {
+			return err
+		}
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	sqlStatement := `
+		INSERT INTO staff (clinic_id, user_id, name, role, specialties)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id`
+	err = tx.QueryRowContext(ctx, sqlStatement, clinicID, nullableID(st.UserID), st.Name, st.Role, pq.Array(st.Specialties)).Scan(&st.ID)
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	if err != nil {
+		return err
+	}
+	if err := replaceHours(ctx, tx, st); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *pgStaffStore) Update(ctx context.Context, clinicID int, st *models.Staff) error {
+	if st.UserID != 0 {
+		if err := checkInClinic(ctx, s.db, "users", "user", st.UserID, clinicID); err != nil {
+			return err
+		}
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	sqlStatement := `
+		UPDATE staff
+		SET user_id = $1, name = $2, role = $3, specialties = $4
+		WHERE id = $5 AND clinic_id = $6`
+	res, err := tx.ExecContext(ctx, sqlStatement, nullableID(st.UserID), st.Name, st.Role, pq.Array(st.Specialties), st.ID, clinicID)
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	if err != nil {
+		return err
+	}
+	if err := checkAffected(res); err != nil {
+		return err
+	}
+	if err := replaceHours(ctx, tx, st); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *pgStaffStore) Delete(ctx context.Context, clinicID int, id int) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM staff WHERE id = $1 AND clinic_id = $2`, id, clinicID)
+	if err != nil {
+		return err
+	}
+	return checkAffected(res)
+}
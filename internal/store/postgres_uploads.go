@@ -0,0 +1,140 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+
+	"pets_project/internal/models"
+)
+
+type pgUploadStore struct {
+	db *sql.DB
+}
+
+const uploadColumns = `id, pet_id, file_name, content_type, document_id, upload_length, upload_offset, chunk_keys, file_id,
+	expires_at`
+
+// uploadOwnerFilter is the owner scoping of upload queries, with the owner ID as $2
+const uploadOwnerFilter = `($2::int IS NULL OR pet_id IN (SELECT id FROM pets WHERE owner_id = $2))`
+
+// scanUpload reads the columns listed in uploadColumns
+func scanUpload(row interface{ Scan(...interface{}) error }, u *models.Upload) error {
+	var documentID, fileID sql.NullInt64
+	var expiresAt time.Time
+	var keys pq.StringArray
+	if err := row.Scan(&u.ID, &u.PetID, &u.FileName, &u.ContentType, &documentID, &u.Length, &u.Offset, &keys, &fileID,
+		&expiresAt); err != nil {
+		return err
+	}
+	u.ChunkKeys = []string(keys)
+	u.DocumentID = int(documentID.Int64)
+	u.FileID = int(fileID.Int64)
+	u.ExpiresAt = expiresAt.UTC().Format(time.RFC3339)
+	return nil
+}
+
+func (s *pgUploadStore) Create(ctx context.Context, scope Scope, u *models.Upload) error {
+	if err := checkPetInScope(ctx, s.db, scope, u.PetID); err != nil {
+		return err
+	}
+	sqlStatement := `
+		INSERT INTO uploads (id, clinic_id, pet_id, file_name, content_type, document_id, upload_length, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING ` + uploadColumns
+	return scanUpload(s.db.QueryRowContext(ctx, sqlStatement, u.ID, scope.ClinicID, u.PetID, u.FileName, u.ContentType,
+		nullableID(u.DocumentID), u.Length, u.ExpiresAt), u)
+}
+
+func (s *pgUploadStore) Get(ctx context.Context, scope Scope, id string) (models.Upload, error) {
+	var u models.Upload
+	sqlStatement := `SELECT ` + uploadColumns + ` FROM uploads WHERE clinic_id = $1 AND ` + uploadOwnerFilter + ` AND id = $3`
+	err := scanUpload(s.db.QueryRowContext(ctx, sqlStatement, scope.ClinicID, ownerFilter(scope), id), &u)
+	return u, notFound(err)
+}
+
+func (s *pgUploadStore) AppendChunk(ctx context.Context, scope Scope, id string, offset, size int64, key string, expiresAt time.Time) (models.Upload, error) {
+	var u models.Upload
+	// The offset condition makes the first of two concurrent chunks win
+	sqlStatement := `
+		UPDATE uploads
+		SET upload_offset = upload_offset + $5, chunk_keys = array_append(chunk_keys, $6), expires_at = $7
+		WHERE clinic_id = $1 AND ` + uploadOwnerFilter + ` AND id = $3 AND upload_offset = $4
+		RETURNING ` + uploadColumns
+	err := scanUpload(s.db.QueryRowContext(ctx, sqlStatement, scope.ClinicID, ownerFilter(scope), id, offset, size, key, expiresAt), &u)
+	if err != sql.ErrNoRows {
+		return u, err
+	}
+	current, err := s.Get(ctx, scope, id)
+	if err != nil {
+		return u, err
+	}
+	return current, &UploadOffsetError{UploadID: id, Offset: current.Offset}
+}
+
+func (s *pgUploadStore) ClaimFinish(ctx context.Context, scope Scope, id string, staleBefore time.Time) error {
+	sqlStatement := `
+		UPDATE uploads SET finishing_at = NOW()
+		WHERE clinic_id = $1 AND ` + uploadOwnerFilter + ` AND id = $3
+			AND upload_offset = upload_length AND file_id IS NULL
+			AND (finishing_at IS NULL OR finishing_at < $4)`
+	res, err := s.db.ExecContext(ctx, sqlStatement, scope.ClinicID, ownerFilter(scope), id, staleBefore)
+	if err != nil {
+		return err
+	}
+	if err := checkAffected(res); err != ErrNotFound {
+		return err
+	}
+	if _, err := s.Get(ctx, scope, id); err != nil {
+		return err
+	}
+	return ErrConflict
+}
+
+func (s *pgUploadStore) ReleaseFinish(ctx context.Context, scope Scope, id string) error {
+	sqlStatement := `
+		UPDATE uploads SET finishing_at = NULL
+		WHERE clinic_id = $1 AND ` + uploadOwnerFilter + ` AND id = $3 AND file_id IS NULL`
+	res, err := s.db.ExecContext(ctx, sqlStatement, scope.ClinicID, ownerFilter(scope), id)
+	if err != nil {
+		return err
+	}
+	return checkAffected(res)
+}
+
+func (s *pgUploadStore) Complete(ctx context.Context, scope Scope, id string, fileID int) error {
+	sqlStatement := `
+		UPDATE uploads SET file_id = $4, chunk_keys = '{}', finishing_at = NULL
+		WHERE clinic_id = $1 AND ` + uploadOwnerFilter + ` AND id = $3 AND file_id IS NULL`
+	res, err := s.db.ExecContext(ctx, sqlStatement, scope.ClinicID, ownerFilter(scope), id, fileID)
+	if err != nil {
+		return err
+	}
+	return checkAffected(res)
+}
+
+func (s *pgUploadStore) Delete(ctx context.Context, scope Scope, id string) (models.Upload, error) {
+	var u models.Upload
+	sqlStatement := `DELETE FROM uploads WHERE clinic_id = $1 AND ` + uploadOwnerFilter + ` AND id = $3 RETURNING ` + uploadColumns
+	err := scanUpload(s.db.QueryRowContext(ctx, sqlStatement, scope.ClinicID, ownerFilter(scope), id), &u)
+	return u, notFound(err)
+}
+
+func (s *pgUploadStore) DeleteExpired(ctx context.Context, now time.Time) ([]models.Upload, error) {
+	rows, err := s.db.QueryContext(ctx, `DELETE FROM uploads WHERE expires_at < $1 RETURNING `+uploadColumns, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	uploads := []models.Upload{}
+	for rows.Next() {
+		var u models.Upload
+		if err := scanUpload(rows, &u); err != nil {
+			return nil, err
+		}
+		uploads = append(uploads, u)
+	}
+	return uploads, rows.Err()
+}
@@ -0,0 +1,275 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"pets_project/internal/models"
+)
+
+// === Users =========================================================================
+type pgUserStore struct {
+	db *sql.DB
+}
+
+func (s *pgUserStore) Create(ctx context.Context, u *models.User) error {
+	sqlStatement := `INSERT INTO users (email, password_hash, clinic_id) VALUES ($1, $2, $3) RETURNING id, role`
+	err := s.db.QueryRowContext(ctx, sqlStatement, u.Email, u.PasswordHash, u.ClinicID).Scan(&u.ID, &u.Role)
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	return err
+}
+
+func (s *pgUserStore) GetByEmail(ctx context.Context, email string) (models.User, error) {
+	var u models.User
+	sqlStatement := `SELECT id, email, role, clinic_id, password_hash FROM users WHERE email = $1`
+	err := s.db.QueryRowContext(ctx, sqlStatement, email).Scan(&u.ID, &u.Email, &u.Role, &u.ClinicID, &u.PasswordHash)
+	return u, notFound(err)
+}
+
+func (s *pgUserStore) ListByClinic(ctx context.Context, clinicID int) ([]models.User, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT id, email, role, clinic_id FROM users WHERE clinic_id = $1 ORDER BY id", clinicID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	users := []models.User{}
+	for rows.Next() {
+		var u models.User
+		if err := rows.Scan(&u.ID, &u.Email, &u.Role, &u.ClinicID); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+func (s *pgUserStore) UpdateRole(ctx context.Context, clinicID int, id int, role string) (models.User, error) {
+	var u models.User
+	sqlStatement := `UPDATE users SET role = $1 WHERE id = $2 AND clinic_id = $3 RETURNING id, email, role, clinic_id`
+	err := s.db.QueryRowContext(ctx, sqlStatement, role, id, clinicID).Scan(&u.ID, &u.Email, &u.Role, &u.ClinicID)
+	return u, notFound(err)
+}
+
+func (s *pgUserStore) SetFeedToken(ctx context.Context, userID int, tokenHash string) error {
+	sqlStatement := `
+		INSERT INTO calendar_feed_tokens (user_id, token_hash) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET token_hash = EXCLUDED.token_hash, created_at = NOW()`
+	_, err := s.db.ExecContext(ctx, sqlStatement, userID, tokenHash)
+	return err
+}
+
+func (s *pgUserStore) DeleteFeedToken(ctx context.Context, userID int) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM calendar_feed_tokens WHERE user_id = $1`, userID)
+	if err != nil {
+		return err
+	}
+	return checkAffected(res)
+}
+
+func (s *pgUserStore) GetByFeedToken(ctx context.Context, tokenHash string) (models.User, error) {
+	var u models.User
+	sqlStatement := `
+		SELECT u.id, u.email, u.role, u.clinic_id FROM calendar_feed_tokens f
+		JOIN users u ON u.id = f.user_id
+		WHERE f.token_hash = $1`
+	err := s.db.QueryRowContext(ctx, sqlStatement, tokenHash).Scan(&u.ID, &u.Email, &u.Role, &u.ClinicID)
+	return u, notFound(err)
+}
+
+// === Sessions ======================================================================
+type pgSessionStore struct {
+	db *sql.DB
+}
+
+func (s *pgSessionStore) Create(ctx context.Context, sessionID string, userID int, tokenHash string, ttl time.Duration) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `INSERT INTO user_sessions (id, user_id) VALUES ($1, $2)`, sessionID, userID); err != nil {
+		return err
+	}
+	if err := insertRefreshToken(ctx, tx, sessionID, tokenHash, ttl); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *pgSessionStore) Rotate(ctx context.Context, tokenHash string, newTokenHash string, ttl time.Duration) (models.User, string, error) {
+	var (
+		user      models.User
+		tokenID   int
+		sessionID string
+		expired   bool
+		usedAt    sql.NullTime
+		revokedAt sql.NullTime
+	)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return user, "", err
+	}
+	defer tx.Rollback()
+
+	sqlStatement := `
+		SELECT rt.id, rt.session_id, u.id, u.role, u.clinic_id, rt.expires_at <= NOW(), rt.used_at, s.revoked_at
+		FROM refresh_tokens rt
+		JOIN user_sessions s ON s.id = rt.session_id
+		JOIN users u ON u.id = s.user_id
+		WHERE rt.token_hash = $1
+		FOR UPDATE OF rt, s`
+	err = tx.QueryRowContext(ctx, sqlStatement, tokenHash).Scan(&tokenID, &sessionID, &user.ID, &user.Role, &user.ClinicID, &expired, &usedAt, &revokedAt)
+	if err == sql.ErrNoRows {
+		return user, "", ErrTokenInvalid
+	}
+	if err != nil {
+		return user, "", err
+	}
+
+	if revokedAt.Valid {
+		return user, sessionID, ErrSessionRevoked
+	}
+	if usedAt.Valid {
+		// Reuse of a rotated token means it leaked; kill the session for everyone holding it
+		if _, err := tx.ExecContext(ctx, `UPDATE user_sessions SET revoked_at = NOW() WHERE id = $1`, sessionID); err != nil {
+			return user, sessionID, err
+		}
+		if err := tx.Commit(); err != nil {
+			return user, sessionID, err
+		}
+		return user, sessionID, ErrTokenReused
+	}
+	if expired {
+		return user, sessionID, ErrTokenExpired
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE refresh_tokens SET used_at = NOW() WHERE id = $1`, tokenID); err != nil {
+		return user, sessionID, err
+	}
+	if err := insertRefreshToken(ctx, tx, sessionID, newTokenHash, ttl); err != nil {
+		return user, sessionID, err
+	}
+	return user, sessionID, tx.Commit()
+}
+
+// insertRefreshToken stores the hash of a new refresh token for the session
+func insertRefreshToken(ctx context.Context, tx *sql.Tx, sessionID string, tokenHash string, ttl time.Duration) error {
+	sqlStatement := `
+		INSERT INTO refresh_tokens (session_id, token_hash, expires_at)
+		VALUES ($1, $2, NOW() + make_interval(secs => $3))`
+	_, err := tx.ExecContext(ctx, sqlStatement, sessionID, tokenHash, int(ttl.Seconds()))
+	return err
+}
+
+func (s *pgSessionStore) IsActive(ctx context.Context, sessionID string, userID int) (bool, error) {
+	var revokedAt sql.NullTime
+	err := s.db.QueryRowContext(ctx, `SELECT revoked_at FROM user_sessions WHERE id = $1 AND user_id = $2`, sessionID, userID).Scan(&revokedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return !revokedAt.Valid, nil
+}
+
+func (s *pgSessionStore) Revoke(ctx context.Context, sessionID string) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE user_sessions SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL`, sessionID)
+	return err
+}
+
+func (s *pgSessionStore) RevokeAllForUser(ctx context.Context, userID int) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE user_sessions SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL`, userID)
+	return err
+}
+
+// === Clinics =======================================================================
+type pgClinicStore struct {
+	db *sql.DB
+}
+
+func (s *pgClinicStore) List(ctx context.Context) ([]models.Clinic, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT id, name, COALESCE(address, ''), timezone FROM clinics ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	clinics := []models.Clinic{}
+	for rows.Next() {
+		var c models.Clinic
+		if err := rows.Scan(&c.ID, &c.Name, &c.Address, &c.Timezone); err != nil {
+			return nil, err
+		}
+		clinics = append(clinics, c)
+	}
+	return clinics, rows.Err()
+}
+
+func (s *pgClinicStore) Get(ctx context.Context, id int) (models.Clinic, error) {
+	var c models.Clinic
+	err := s.db.QueryRowContext(ctx, "SELECT id, name, COALESCE(address, ''), timezone FROM clinics WHERE id = $1", id).Scan(&c.ID, &c.Name, &c.Address, &c.Timezone)
+	return c, notFound(err)
+}
+
+func (s *pgClinicStore) Hours(ctx context.Context, clinicID int) ([]models.WorkingHours, error) {
+	sqlStatement := `
+		SELECT weekday, to_char(start_time, 'HH24:MI'), to_char(end_time, 'HH24:MI')
+		FROM clinic_hours
+		WHERE clinic_id = $1
+		ORDER BY weekday, start_time`
+	rows, err := s.db.QueryContext(ctx, sqlStatement, clinicID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	hours := []models.WorkingHours{}
+	for rows.Next() {
+		var h models.WorkingHours
+		if err := rows.Scan(&h.Weekday, &h.Start, &h.End); err != nil {
+			return nil, err
+		}
+		hours = append(hours, h)
+	}
+	return hours, rows.Err()
+}
+
+func (s *pgClinicStore) SetHours(ctx context.Context, clinicID int, hours []models.WorkingHours) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM clinic_hours WHERE clinic_id = $1`, clinicID); err != nil {
+		return err
+	}
+	for _, h := range hours {
+		sqlStatement := `INSERT INTO clinic_hours (clinic_id, weekday, start_time, end_time) VALUES ($1, $2, $3, $4)`
+		_, err := tx.ExecContext(ctx, sqlStatement, clinicID, h.Weekday, h.Start, h.End)
+		if isUniqueViolation(err) {
+			return ErrConflict
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *pgClinicStore) Exists(ctx context.Context, id int) (bool, error) {
+	var exists bool
+	err := s.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM clinics WHERE id = $1)`, id).Scan(&exists)
+	return exists, err
+}
+
+func (s *pgClinicStore) Create(ctx context.Context, c *models.Clinic) error {
+	if c.Timezone == "" {
+		c.Timezone = "UTC"
+	}
+	return s.db.QueryRowContext(ctx, `INSERT INTO clinics (name, address, timezone) VALUES ($1, $2, $3) RETURNING id`, c.Name, c.Address, c.Timezone).Scan(&c.ID)
+}
@@ -0,0 +1,160 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"pets_project/internal/models"
+)
+
+type pgVaccinationStore struct {
+	db *sql.DB
+}
+
+const vaccinationColumns = `id, pet_id, vaccine, lot_number, to_char(administered_on, 'YYYY-MM-DD'), COALESCE(to_char(next_due_on, 'YYYY-MM-DD'), ''), administered_by`
+
+// scanVaccination reads the columns listed in vaccinationColumns
+func scanVaccination(row interface{ Scan(...interface{}) error }, v *models.Vaccination) error {
+	var administeredBy sql.NullInt64
+	if err := row.Scan(&v.ID, &v.PetID, &v.Vaccine, &v.LotNumber, &v.AdministeredOn, &v.NextDueOn, &administeredBy); err != nil {
+		return err
+	}
+	v.AdministeredBy = int(administeredBy.Int64)
+	return nil
+}
+
+// nullableDate maps an unset date to SQL NULL
+func nullableDate(date string) interface{} {
+	if date == "" {
+		return nil
+	}
+	return date
+}
+
+func (s *pgVaccinationStore) List(ctx context.Context, scope Scope, petID int, page Page) ([]models.Vaccination, string, error) {
+	k, err := resolvePage(page, vaccinationSortFields)
+	if err != nil {
+		return nil, "", err
+	}
+	sqlStatement := `
+		SELECT ` + vaccinationColumns + ` FROM vaccinations
+		WHERE pet_id = $1 AND clinic_id = $2 AND ($3::int IS NULL OR pet_id IN (SELECT id FROM pets WHERE owner_id = $3))`
+	clauses, args := k.sqlClauses([]interface{}{petID, scope.ClinicID, ownerFilter(scope)})
+	rows, err := s.db.QueryContext(ctx, sqlStatement+clauses, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+	vaccinations := []models.Vaccination{}
+	for rows.Next() {
+		var v models.Vaccination
+		if err := scanVaccination(rows, &v); err != nil {
+			return nil, "", err
+		}
+		vaccinations = append(vaccinations, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+	vaccinations, next := trimPage(k, vaccinations, vaccinationSortKey(k.field))
+	return vaccinations, next, nil
+}
+
+func (s *pgVaccinationStore) Get(ctx context.Context, scope Scope, petID int, id int) (models.Vaccination, error) {
+	var v models.Vaccination
+	sqlStatement := `
+		SELECT ` + vaccinationColumns + ` FROM vaccinations
+		WHERE id = $1 AND pet_id = $2 AND clinic_id = $3 AND ($4::int IS NULL OR pet_id IN (SELECT id FROM pets WHERE owner_id = $4))`
+	err := scanVaccination(s.db.QueryRowContext(ctx, sqlStatement, id, petID, scope.ClinicID, ownerFilter(scope)), &v)
+	return v, notFound(err)
+}
+
+// checkVaccinationRefs validates the pet and administering user of a vaccination
+func (s *pgVaccinationStore) checkVaccinationRefs(ctx context.Context, scope Scope, v *models.Vaccination) error {
+	if err := checkPetInScope(ctx, s.db, scope, v.PetID); err != nil {
+		return err
+	}
+	if v.AdministeredBy != 0 {
+		return checkInClinic(ctx, s.db, "users", "user", v.AdministeredBy, scope.ClinicID)
+	}
+	return nil
+}
+
+func (s *pgVaccinationStore) Create(ctx context.Context, scope Scope, v *models.Vaccination) error {
+	if err := s.checkVaccinationRefs(ctx, scope, v); err != nil {
+		return err
+	}
+	sqlStatement := `
+		INSERT INTO vaccinations (clinic_id, pet_id, vaccine, lot_number, administered_on, next_due_on, administered_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id`
+	return s.db.QueryRowContext(ctx, sqlStatement, scope.ClinicID, v.PetID, v.Vaccine, v.LotNumber, v.AdministeredOn, nullableDate(v.NextDueOn), nullableID(v.AdministeredBy)).Scan(&v.ID)
+}
+
+func (s *pgVaccinationStore) Update(ctx context.Context, scope Scope, v *models.Vaccination) error {
+	if err := s.checkVaccinationRefs(ctx, scope, v); err != nil {
+		return err
+	}
+	sqlStatement := `
+		UPDATE vaccinations
+		SET vaccine = $1, lot_number = $2, administered_on = $3, next_due_on = $4, administered_by = $5
+		WHERE id = $6 AND pet_id = $7 AND clinic_id = $8`
+	res, err := s.db.ExecContext(ctx, sqlStatement, v.Vaccine, v.LotNumber, v.AdministeredOn, nullableDate(v.NextDueOn), nullableID(v.AdministeredBy), v.ID, v.PetID, scope.ClinicID)
+	if err != nil {
+		return err
+	}
+	return checkAffected(res)
+}
+
+func (s *pgVaccinationStore) Delete(ctx context.Context, scope Scope, petID int, id int) error {
+	sqlStatement := `
+		DELETE FROM vaccinations
+		WHERE id = $1 AND pet_id = $2 AND clinic_id = $3 AND ($4::int IS NULL OR pet_id IN (SELECT id FROM pets WHERE owner_id = $4))`
+	res, err := s.db.ExecContext(ctx, sqlStatement, id, petID, scope.ClinicID, ownerFilter(scope))
+	if err != nil {
+		return err
+	}
+	return checkAffected(res)
+}
+
+func (s *pgVaccinationStore) Due(ctx context.Context, scope Scope, dueBy string, page Page) ([]models.VaccinationDue, string, error) {
+	k, err := resolvePage(page, vaccinationDueSortFields)
+	if err != nil {
+		return nil, "", err
+	}
+	// DISTINCT ON keeps the latest dose per pet and vaccine, so a booster clears the earlier due date
+	sqlStatement := `
+		SELECT id, pet_id, pet_name, owner_id, owner_name, owner_contact, owner_email, vaccine,
+			to_char(administered_on, 'YYYY-MM-DD'), to_char(next_due_on, 'YYYY-MM-DD')
+		FROM (
+			SELECT DISTINCT ON (v.pet_id, lower(v.vaccine))
+				v.id, v.pet_id, p.name AS pet_name, o.id AS owner_id, o.name AS owner_name,
+				COALESCE(o.contact, '') AS owner_contact, COALESCE(o.email, '') AS owner_email,
+				v.vaccine, v.administered_on, v.next_due_on
+			FROM vaccinations v
+			JOIN pets p ON p.id = v.pet_id
+			JOIN owners o ON o.id = p.owner_id
+			WHERE v.clinic_id = $1 AND ($2::int IS NULL OR p.owner_id = $2)
+			ORDER BY v.pet_id, lower(v.vaccine), v.administered_on DESC, v.id DESC
+		) latest
+		WHERE next_due_on IS NOT NULL AND next_due_on <= $3`
+	clauses, args := k.sqlClauses([]interface{}{scope.ClinicID, ownerFilter(scope), dueBy})
+	rows, err := s.db.QueryContext(ctx, sqlStatement+clauses, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+	due := []models.VaccinationDue{}
+	for rows.Next() {
+		var d models.VaccinationDue
+		if err := rows.Scan(&d.VaccinationID, &d.PetID, &d.PetName, &d.OwnerID, &d.OwnerName, &d.OwnerContact, &d.OwnerEmail, &d.Vaccine, &d.AdministeredOn, &d.NextDueOn); err != nil {
+			return nil, "", err
+		}
+		due = append(due, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+	due, next := trimPage(k, due, vaccinationDueSortKey(k.field))
+	return due, next, nil
+}
@@ -0,0 +1,292 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+
+	"pets_project/internal/models"
+)
+
+type pgWaitlistStore struct {
+	db *sql.DB
+}
+
+const waitlistColumns = `id, pet_id, vet_id, to_char(earliest_date, 'YYYY-MM-DD'), to_char(latest_date, 'YYYY-MM-DD'),
+	duration_minutes, priority, notes, status, offered_starts_at, offered_duration_minutes, offered_vet_id, offered_at, appointment_id, created_at`
+
+// waitlistOwnerFilter is the owner scoping of waitlist queries, with the owner ID as $2
+const waitlistOwnerFilter = `($2::int IS NULL OR pet_id IN (SELECT id FROM pets WHERE owner_id = $2))`
+
+// scanWaitlistEntry reads the columns listed in waitlistColumns
+func scanWaitlistEntry(row interface{ Scan(...interface{}) error }, e *models.WaitlistEntry) error {
+	var vetID, offeredDuration, offeredVetID, appointmentID sql.NullInt64
+	var offeredStartsAt, offeredAt sql.NullTime
+	var createdAt time.Time
+	if err := row.Scan(&e.ID, &e.PetID, &vetID, &e.EarliestDate, &e.LatestDate, &e.DurationMinutes, &e.Priority, &e.Notes,
+		&e.Status, &offeredStartsAt, &offeredDuration, &offeredVetID, &offeredAt, &appointmentID, &createdAt); err != nil {
+		return err
+	}
+	e.VetID = int(vetID.Int64)
+	e.AppointmentID = int(appointmentID.Int64)
+	e.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+	e.Offer = nil
+	if offeredStartsAt.Valid {
+		e.Offer = &models.WaitlistOffer{
+			StartsAt:        offeredStartsAt.Time.UTC().Format(time.RFC3339),
+			DurationMinutes: int(offeredDuration.Int64),
+			VetID:           int(offeredVetID.Int64),
+			OfferedAt:       offeredAt.Time.UTC().Format(time.RFC3339),
+		}
+	}
+	return nil
+}
+
+func (s *pgWaitlistStore) List(ctx context.Context, scope Scope, filter WaitlistFilter) ([]models.WaitlistEntry, error) {
+	sqlStatement := `
+		SELECT ` + waitlistColumns + ` FROM waitlist_entries
+		WHERE clinic_id = $1 AND ` + waitlistOwnerFilter + `
+		AND ($3::int IS NULL OR pet_id = $3)
+		AND ($4::int IS NULL OR vet_id = $4)
+		AND ($5::text IS NULL OR status = $5)
+		ORDER BY priority DESC, created_at, id`
+	var status interface{}
+	if filter.Status != "" {
+		status = filter.Status
+	}
+	rows, err := s.db.QueryContext(ctx, sqlStatement, scope.ClinicID, ownerFilter(scope), nullableID(filter.PetID), nullableID(filter.VetID), status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	entries := []models.WaitlistEntry{}
+	for rows.Next() {
+		var e models.WaitlistEntry
+		if err := scanWaitlistEntry(rows, &e); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func (s *pgWaitlistStore) Get(ctx context.Context, scope Scope, id int) (models.WaitlistEntry, error) {
+	var e models.WaitlistEntry
+	sqlStatement := `SELECT ` + waitlistColumns + ` FROM waitlist_entries WHERE clinic_id = $1 AND ` + waitlistOwnerFilter + ` AND id = $3`
+	err := scanWaitlistEntry(s.db.QueryRowContext(ctx, sqlStatement, scope.ClinicID, ownerFilter(scope), id), &e)
+	return e, notFound(err)
+}
+
+// checkWaitlistRefs validates the pet and preferred vet of an entry
+func checkWaitlistRefs(ctx context.Context, q queryer, scope Scope, e *models.WaitlistEntry) error {
+	if err := checkPetInScope(ctx, q, scope, e.PetID); err != nil {
+		return err
+	}
+	if e.VetID == 0 {
+		return nil
+	}
+	var exists bool
+	sqlStatement := `SELECT EXISTS (SELECT 1 FROM staff WHERE id = $1 AND clinic_id = $2 AND role = 'vet')`
+	if err := q.QueryRowContext(ctx, sqlStatement, e.VetID, scope.ClinicID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return &ReferenceError{Entity: "vet", ID: e.VetID}
+	}
+	return nil
+}
+
+func (s *pgWaitlistStore) Create(ctx context.Context, scope Scope, e *models.WaitlistEntry) error {
+	if err := checkWaitlistRefs(ctx, s.db, scope, e); err != nil {
+		return err
+	}
+	sqlStatement := `
+		INSERT INTO waitlist_entries (clinic_id, pet_id, vet_id, earliest_date, latest_date, duration_minutes, priority, notes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING ` + waitlistColumns
+	return scanWaitlistEntry(s.db.QueryRowContext(ctx, sqlStatement, scope.ClinicID, e.PetID, nullableID(e.VetID),
+		e.EarliestDate, e.LatestDate, e.DurationMinutes, e.Priority, e.Notes), e)
+}
+
+// lockWaitlistEntry locks the entry for the rest of the transaction and returns its status
+func lockWaitlistEntry(ctx context.Context, tx *sql.Tx, scope Scope, id int) (string, error) {
+	var status string
+	sqlStatement := `SELECT status FROM waitlist_entries WHERE clinic_id = $1 AND ` + waitlistOwnerFilter + ` AND id = $3 FOR UPDATE`
+	err := tx.QueryRowContext(ctx, sqlStatement, scope.ClinicID, ownerFilter(scope), id).Scan(&status)
+	return status, notFound(err)
+}
+
+func (s *pgWaitlistStore) Update(ctx context.Context, scope Scope, e *models.WaitlistEntry) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	status, err := lockWaitlistEntry(ctx, tx, scope, e.ID)
+	if err != nil {
+		return err
+	}
+	if status != models.WaitlistWaiting {
+		return &WaitlistStateError{EntryID: e.ID, Status: status, Want: models.WaitlistWaiting}
+	}
+	if err := checkWaitlistRefs(ctx, tx, scope, e); err != nil {
+		return err
+	}
+	sqlStatement := `
+		UPDATE waitlist_entries
+		SET pet_id = $1, vet_id = $2, earliest_date = $3, latest_date = $4, duration_minutes = $5, priority = $6, notes = $7
+		WHERE id = $8
+		RETURNING ` + waitlistColumns
+	err = scanWaitlistEntry(tx.QueryRowContext(ctx, sqlStatement, e.PetID, nullableID(e.VetID), e.EarliestDate, e.LatestDate,
+		e.DurationMinutes, e.Priority, e.Notes, e.ID), e)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *pgWaitlistStore) Delete(ctx context.Context, scope Scope, id int) error {
+	sqlStatement := `DELETE FROM waitlist_entries WHERE clinic_id = $1 AND ` + waitlistOwnerFilter + ` AND id = $3`
+	res, err := s.db.ExecContext(ctx, sqlStatement, scope.ClinicID, ownerFilter(scope), id)
+	if err != nil {
+		return err
+	}
+	return checkAffected(res)
+}
+
+func (s *pgWaitlistStore) Offer(ctx context.Context, clinicID int, slot FreedSlot, now time.Time, exclude []int) (models.WaitlistEntry, error) {
+	// SKIP LOCKED lets concurrent offers pass over an entry another transaction is taking
+	sqlStatement := `
+		UPDATE waitlist_entries
+		SET status = 'offered', offered_starts_at = $2, offered_duration_minutes = $6, offered_vet_id = $3, offered_at = $4
+		WHERE status = 'waiting' AND id = (
+			SELECT id FROM waitlist_entries
+			WHERE clinic_id = $1 AND status = 'waiting'
+			AND $5::date BETWEEN earliest_date AND latest_date
+			AND duration_minutes <= $6
+			AND (vet_id IS NULL OR vet_id = $3)
+			AND id <> ALL($7)
+			ORDER BY priority DESC, created_at, id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + waitlistColumns
+	excluded := make([]int64, len(exclude))
+	for i, id := range exclude {
+		excluded[i] = int64(id)
+	}
+	var e models.WaitlistEntry
+	err := scanWaitlistEntry(s.db.QueryRowContext(ctx, sqlStatement, clinicID, slot.StartsAt, nullableID(slot.VetID), now,
+		slot.Date, slot.DurationMinutes, pq.Array(excluded)), &e)
+	return e, notFound(err)
+}
+
+func (s *pgWaitlistStore) Decline(ctx context.Context, scope Scope, id int) (models.WaitlistEntry, error) {
+	var e models.WaitlistEntry
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return e, err
+	}
+	defer tx.Rollback()
+
+	sqlStatement := `SELECT ` + waitlistColumns + ` FROM waitlist_entries WHERE clinic_id = $1 AND ` + waitlistOwnerFilter + ` AND id = $3 FOR UPDATE`
+	if err := scanWaitlistEntry(tx.QueryRowContext(ctx, sqlStatement, scope.ClinicID, ownerFilter(scope), id), &e); err != nil {
+		return e, notFound(err)
+	}
+	if e.Status != models.WaitlistOffered {
+		return e, &WaitlistStateError{EntryID: id, Status: e.Status, Want: models.WaitlistOffered}
+	}
+	sqlStatement = `
+		UPDATE waitlist_entries
+		SET status = 'waiting', offered_starts_at = NULL, offered_duration_minutes = NULL, offered_vet_id = NULL, offered_at = NULL
+		WHERE id = $1`
+	if _, err := tx.ExecContext(ctx, sqlStatement, id); err != nil {
+		return e, err
+	}
+	return e, tx.Commit()
+}
+
+func (s *pgWaitlistStore) MarkBooked(ctx context.Context, scope Scope, id int, appointmentID int) (models.WaitlistEntry, error) {
+	var e models.WaitlistEntry
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return e, err
+	}
+	defer tx.Rollback()
+
+	status, err := lockWaitlistEntry(ctx, tx, scope, id)
+	if err != nil {
+		return e, err
+	}
+	if status != models.WaitlistOffered {
+		return e, &WaitlistStateError{EntryID: id, Status: status, Want: models.WaitlistOffered}
+	}
+	sqlStatement := `
+		UPDATE waitlist_entries
+		SET status = 'booked', appointment_id = $1, offered_starts_at = NULL, offered_duration_minutes = NULL, offered_vet_id = NULL, offered_at = NULL
+		WHERE id = $2
+		RETURNING ` + waitlistColumns
+	if err := scanWaitlistEntry(tx.QueryRowContext(ctx, sqlStatement, appointmentID, id), &e); err != nil {
+		return e, err
+	}
+	return e, tx.Commit()
+}
+
+func (s *pgWaitlistStore) ExpireOffers(ctx context.Context, offeredBefore, now time.Time) ([]ExpiredOffer, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// SKIP LOCKED leaves entries being accepted or declined right now to that request
+	sqlStatement := `
+		SELECT clinic_id, ` + waitlistColumns + ` FROM waitlist_entries
+		WHERE status = 'offered' AND (offered_at < $1 OR offered_starts_at < $2)
+		ORDER BY id
+		FOR UPDATE SKIP LOCKED`
+	rows, err := tx.QueryContext(ctx, sqlStatement, offeredBefore, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	expired := []ExpiredOffer{}
+	ids := []int64{}
+	for rows.Next() {
+		var x ExpiredOffer
+		var clinicID int
+		if err := scanWaitlistEntry(prefixedRow{rows, &clinicID}, &x.Entry); err != nil {
+			return nil, err
+		}
+		x.ClinicID = clinicID
+		expired = append(expired, x)
+		ids = append(ids, int64(x.Entry.ID))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return expired, nil
+	}
+	sqlStatement = `
+		UPDATE waitlist_entries
+		SET status = 'waiting', offered_starts_at = NULL, offered_duration_minutes = NULL, offered_vet_id = NULL, offered_at = NULL
+		WHERE id = ANY($1)`
+	if _, err := tx.ExecContext(ctx, sqlStatement, pq.Array(ids)); err != nil {
+		return nil, err
+	}
+	return expired, tx.Commit()
+}
+
+// prefixedRow scans one leading column into dest before the columns of row
+type prefixedRow struct {
+	row  interface{ Scan(...interface{}) error }
+	dest interface{}
+}
+
+func (p prefixedRow) Scan(dest ...interface{}) error {
+	return p.row.Scan(append([]interface{}{p.dest}, dest...)...)
+}
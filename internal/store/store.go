@@ -0,0 +1,399 @@
+// Package store defines the persistence interfaces used by the HTTP handlers,
+// with a Postgres implementation for production and an in-memory one for tests.
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"pets_project/internal/models"
+)
+
+var (
+	// ErrNotFound is returned when a record does not exist or is outside the caller's scope
+	ErrNotFound = errors.New("record not found")
+	// ErrConflict is returned when a unique value (such as an email) is already taken
+	ErrConflict = errors.New("record already exists")
+
+	// Refresh token failures reported by SessionStore.Rotate
+	ErrTokenInvalid   = errors.New("invalid refresh token")
+	ErrTokenExpired   = errors.New("refresh token expired")
+	ErrTokenReused    = errors.New("refresh token reuse detected")
+	ErrSessionRevoked = errors.New("session has been revoked")
+)
+
+// ConflictError reports a booking that overlaps an existing appointment of the same pet or vet
+type ConflictError struct {
+	With        string // "pet" or "vet"
+	Appointment models.Appointment
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s already has appointment %d at %s (%d minutes)", e.With, e.Appointment.ID, e.Appointment.StartsAt, e.Appointment.DurationMinutes)
+}
+
+// Unwrap lets errors.Is(err, ErrConflict) match booking conflicts too
+func (e *ConflictError) Unwrap() error { return ErrConflict }
+
+// TransitionError reports a status change the appointment's current status does not allow
+type TransitionError struct {
+	AppointmentID int
+	From, To      string
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("appointment %d is %s and cannot become %s", e.AppointmentID, e.From, e.To)
+}
+
+// Unwrap lets errors.Is(err, ErrConflict) match invalid transitions too
+func (e *TransitionError) Unwrap() error { return ErrConflict }
+
+// WaitlistStateError reports a waitlist change the entry's current status does not allow
+type WaitlistStateError struct {
+	EntryID int
+	Status  string // current status
+	Want    string // status the change requires
+}
+
+func (e *WaitlistStateError) Error() string {
+	return fmt.Sprintf("waitlist entry %d is %s, not %s", e.EntryID, e.Status, e.Want)
+}
+
+// Unwrap lets errors.Is(err, ErrConflict) match waitlist state errors too
+func (e *WaitlistStateError) Unwrap() error { return ErrConflict }
+
+// UploadOffsetError reports a chunk that does not start where the upload currently ends
+type UploadOffsetError struct {
+	UploadID string
+	Offset   int64 // current offset of the upload
+}
+
+func (e *UploadOffsetError) Error() string {
+	return fmt.Sprintf("upload %s is at offset %d", e.UploadID, e.Offset)
+}
+
+// Unwrap lets errors.Is(err, ErrConflict) match offset mismatches too
+func (e *UploadOffsetError) Unwrap() error { return ErrConflict }
+
+// ReferenceError reports a foreign key that does not point at a record in the caller's scope
+type ReferenceError struct {
+	Entity string
+	ID     int
+}
+
+func (e *ReferenceError) Error() string {
+	return fmt.Sprintf("%s %d not found in this clinic", e.Entity, e.ID)
+}
+
+// Scope restricts queries to one clinic (tenant) and, for pet-owner accounts,
+// to the records belonging to a single owner.
+type Scope struct {
+	ClinicID  int
+	OwnerOnly bool
+	OwnerID   int // owner the records must belong to when OwnerOnly is set; 0 matches nothing
+}
+
+// PetFilter narrows a pet listing; zero fields match everything
+type PetFilter struct {
+	Species string
+	Breed   string
+	OwnerID int
+}
+
+// OwnerFilter narrows an owner listing; Name matches case-insensitively as a substring
+type OwnerFilter struct {
+	Name  string
+	Email string
+}
+
+// AppointmentFilter narrows an appointment listing to appointments starting in [From, To).
+// Zero times leave that end of the range open.
+type AppointmentFilter struct {
+	PetID    int
+	VetID    int
+	OwnerID  int
+	SeriesID int
+	Statuses []string // any of these; nil for all
+	From     time.Time
+	To       time.Time
+}
+
+// PetStore persists pets
+type PetStore interface {
+	// List returns one page of pets and the cursor of the next page ("" on the last page)
+	List(ctx context.Context, scope Scope, filter PetFilter, page Page) ([]models.Pet, string, error)
+	Get(ctx context.Context, scope Scope, id int) (models.Pet, error)
+	Create(ctx context.Context, scope Scope, p *models.Pet) error
+	Update(ctx context.Context, scope Scope, p *models.Pet) error
+	Delete(ctx context.Context, scope Scope, id int) error
+}
+
+// OwnerStore persists pet owners
+type OwnerStore interface {
+	List(ctx context.Context, scope Scope, filter OwnerFilter, page Page) ([]models.Owner, string, error)
+	Get(ctx context.Context, scope Scope, id int) (models.Owner, error)
+	GetByUser(ctx context.Context, clinicID int, userID int) (models.Owner, error)
+	Create(ctx context.Context, scope Scope, o *models.Owner) error
+	Update(ctx context.Context, scope Scope, o *models.Owner) error
+	Delete(ctx context.Context, scope Scope, id int) error
+}
+
+// AppointmentStore persists appointments
+type AppointmentStore interface {
+	List(ctx context.Context, scope Scope, filter AppointmentFilter, page Page) ([]models.Appointment, string, error)
+	Get(ctx context.Context, scope Scope, id int) (models.Appointment, error)
+	// Create and Update return a *ConflictError when the pet or the vet is already booked
+	// for part of the time. StartsAt is normalised to UTC. New appointments are scheduled;
+	// Update keeps the status and series.
+	Create(ctx context.Context, scope Scope, a *models.Appointment) error
+	Update(ctx context.Context, scope Scope, a *models.Appointment) error
+	Delete(ctx context.Context, scope Scope, id int) error
+	// CreateSeries books every appointment of the series, or none if one of them clashes
+	CreateSeries(ctx context.Context, scope Scope, series *models.AppointmentSeries) error
+	// Following returns the appointment and the later occurrences of its series, in order
+	Following(ctx context.Context, scope Scope, id int) ([]models.Appointment, error)
+	// UpdateMany and DeleteMany change several appointments in one transaction.
+	// Appointments being updated are not checked for conflicts with each other's old times.
+	UpdateMany(ctx context.Context, scope Scope, appointments []models.Appointment) error
+	DeleteMany(ctx context.Context, scope Scope, ids []int) error
+	// Transition moves the appointments to ev.ToStatus and records one event each, all or none.
+	// It returns a *TransitionError if an appointment's status is not in from.
+	Transition(ctx context.Context, scope Scope, ids []int, from []string, ev models.AppointmentStatusEvent) ([]models.Appointment, error)
+	History(ctx context.Context, scope Scope, id int) ([]models.AppointmentStatusEvent, error)
+	// ListForVet returns every appointment of the vet overlapping [from, to) that still occupies
+	// its slot (not cancelled or no-show), ignoring owner scoping.
+	// It backs schedule and free-slot views, which must see all bookings.
+	ListForVet(ctx context.Context, clinicID int, vetID int, from, to time.Time) ([]models.Appointment, error)
+	// Calendar returns every matching appointment in any status, with pet, owner and vet
+	// names, ordered by start time. It backs calendar feeds, which are not paged.
+	Calendar(ctx context.Context, scope Scope, filter AppointmentFilter) ([]models.CalendarEntry, error)
+}
+
+// FileRecordStore persists metadata of uploaded files. Each record is a version of a
+// document; the version with the highest number is the document's current one.
+type FileRecordStore interface {
+	// ListByPet returns the current version of each of the pet's documents
+	ListByPet(ctx context.Context, scope Scope, petID int) ([]models.FileRecord, error)
+	Get(ctx context.Context, scope Scope, id int) (models.FileRecord, error)
+	// Create adds f as the first version of a new document, or as the next version of
+	// f.DocumentID when it is set, which must be a document of the same pet. Another
+	// version created at the same time gives ErrConflict.
+	Create(ctx context.Context, scope Scope, f *models.FileRecord) error
+	// Versions returns every version of a document, oldest first
+	Versions(ctx context.Context, scope Scope, documentID int) ([]models.FileRecord, error)
+	// Delete removes the record and returns it so the caller can clean up the stored file
+	Delete(ctx context.Context, scope Scope, id int) (models.FileRecord, error)
+	// PendingScans returns files of every clinic that still await a scan, uploaded and last
+	// attempted before the given time, and records the attempt. The files attempted least
+	// recently come first, so ones that keep failing cannot hold up the rest.
+	PendingScans(ctx context.Context, before time.Time, limit int) ([]models.FileRecord, error)
+	// SetScanResult records the verdict on a pending file: FileClean, FileInfected with the
+	// signature found, or FileScanFailed with the reason the scanner gave
+	SetScanResult(ctx context.Context, id int, status, signature string, scannedAt time.Time) error
+}
+
+// UploadStore tracks resumable uploads. Only the chunks' blob keys are kept here; a
+// chunk is recorded once it is stored, so concurrent requests cannot both extend an upload.
+type UploadStore interface {
+	Create(ctx context.Context, scope Scope, u *models.Upload) error
+	// Get returns the upload even after it expired, until DeleteExpired removes it
+	Get(ctx context.Context, scope Scope, id string) (models.Upload, error)
+	// AppendChunk records a stored chunk of size bytes at offset and moves the expiry to expiresAt.
+	// It returns an *UploadOffsetError if the upload no longer ends at offset.
+	AppendChunk(ctx context.Context, scope Scope, id string, offset, size int64, key string, expiresAt time.Time) (models.Upload, error)
+	// ClaimFinish lets one request join the chunks of a complete upload. It returns ErrConflict
+	// if the upload already has a file record, or another request claimed it after staleBefore.
+	ClaimFinish(ctx context.Context, scope Scope, id string, staleBefore time.Time) error
+	// ReleaseFinish gives up the claim after joining failed, so a retry can claim it again
+	ReleaseFinish(ctx context.Context, scope Scope, id string) error
+	// Complete links a finished upload to its file record and forgets its chunks. An upload
+	// that already has a file record gives ErrNotFound.
+	Complete(ctx context.Context, scope Scope, id string, fileID int) error
+	// Delete removes the upload and returns it so the caller can remove its chunks
+	Delete(ctx context.Context, scope Scope, id string) (models.Upload, error)
+	// DeleteExpired removes uploads of every clinic that expired before now and returns them
+	DeleteExpired(ctx context.Context, now time.Time) ([]models.Upload, error)
+}
+
+// DownloadLinkStore remembers which single-use download links were used. The links
+// themselves are signed rather than stored.
+type DownloadLinkStore interface {
+	// Use marks the link with this nonce as used and reports false if it already was
+	Use(ctx context.Context, nonce string, expiresAt time.Time) (bool, error)
+	// DeleteExpired forgets links that expired before now, which are refused anyway
+	DeleteExpired(ctx context.Context, now time.Time) (int, error)
+}
+
+// MedicalEntryStore persists the append-only medical history of pets.
+// There is deliberately no Update or Delete.
+type MedicalEntryStore interface {
+	// List returns one page of a pet's entries; sortable by occurred_on
+	List(ctx context.Context, scope Scope, petID int, page Page) ([]models.MedicalEntry, string, error)
+	Get(ctx context.Context, scope Scope, petID int, id int) (models.MedicalEntry, error)
+	// Create appends an entry; AmendsID must name an earlier entry of the same pet
+	Create(ctx context.Context, scope Scope, e *models.MedicalEntry) error
+}
+
+// VaccinationStore persists vaccinations given to pets
+type VaccinationStore interface {
+	List(ctx context.Context, scope Scope, petID int, page Page) ([]models.Vaccination, string, error)
+	Get(ctx context.Context, scope Scope, petID int, id int) (models.Vaccination, error)
+	Create(ctx context.Context, scope Scope, v *models.Vaccination) error
+	Update(ctx context.Context, scope Scope, v *models.Vaccination) error
+	Delete(ctx context.Context, scope Scope, petID int, id int) error
+	// Due lists, for every pet and vaccine, the most recent dose whose next due date
+	// is on or before dueBy (YYYY-MM-DD). Earlier doses are superseded by later ones.
+	Due(ctx context.Context, scope Scope, dueBy string, page Page) ([]models.VaccinationDue, string, error)
+}
+
+// UserStore persists login accounts
+type UserStore interface {
+	Create(ctx context.Context, u *models.User) error
+	GetByEmail(ctx context.Context, email string) (models.User, error)
+	ListByClinic(ctx context.Context, clinicID int) ([]models.User, error)
+	UpdateRole(ctx context.Context, clinicID int, id int, role string) (models.User, error)
+	// SetFeedToken replaces the user's calendar feed token; DeleteFeedToken revokes it
+	SetFeedToken(ctx context.Context, userID int, tokenHash string) error
+	DeleteFeedToken(ctx context.Context, userID int) error
+	GetByFeedToken(ctx context.Context, tokenHash string) (models.User, error)
+}
+
+// SessionStore persists login sessions and their hashed refresh tokens
+type SessionStore interface {
+	// Create opens a session together with its first refresh token
+	Create(ctx context.Context, sessionID string, userID int, tokenHash string, ttl time.Duration) error
+	// Rotate consumes the refresh token and stores its replacement in the same session.
+	// Presenting an already consumed token revokes the session and returns ErrTokenReused.
+	Rotate(ctx context.Context, tokenHash string, newTokenHash string, ttl time.Duration) (models.User, string, error)
+	IsActive(ctx context.Context, sessionID string, userID int) (bool, error)
+	Revoke(ctx context.Context, sessionID string) error
+	RevokeAllForUser(ctx context.Context, userID int) error
+}
+
+// StaffFilter narrows a staff listing; zero fields match everything
+type StaffFilter struct {
+	Role      string
+	Specialty string
+}
+
+// StaffStore persists clinic staff together with their weekly working hours
+type StaffStore interface {
+	List(ctx context.Context, clinicID int, filter StaffFilter, page Page) ([]models.Staff, string, error)
+	Get(ctx context.Context, clinicID int, id int) (models.Staff, error)
+	Create(ctx context.Context, clinicID int, st *models.Staff) error
+	// Update replaces the staff member's details and working hours
+	Update(ctx context.Context, clinicID int, st *models.Staff) error
+	Delete(ctx context.Context, clinicID int, id int) error
+}
+
+// AppointmentTypeStore persists the kinds of appointment a clinic offers
+type AppointmentTypeStore interface {
+	List(ctx context.Context, clinicID int) ([]models.AppointmentType, error)
+	Get(ctx context.Context, clinicID int, id int) (models.AppointmentType, error)
+	Create(ctx context.Context, clinicID int, t *models.AppointmentType) error
+	Update(ctx context.Context, clinicID int, t *models.AppointmentType) error
+	Delete(ctx context.Context, clinicID int, id int) error
+}
+
+// BlockedPeriodStore persists periods in which the clinic or a staff member cannot be booked
+type BlockedPeriodStore interface {
+	// List returns the periods overlapping [from, to), clinic-wide and per staff member
+	List(ctx context.Context, clinicID int, from, to time.Time) ([]models.BlockedPeriod, error)
+	Create(ctx context.Context, clinicID int, b *models.BlockedPeriod) error
+	Delete(ctx context.Context, clinicID int, id int) error
+}
+
+// WaitlistFilter narrows a waitlist listing; zero fields match everything
+type WaitlistFilter struct {
+	PetID  int
+	VetID  int
+	Status string
+}
+
+// FreedSlot is the time given up by a cancelled or deleted appointment
+type FreedSlot struct {
+	StartsAt        time.Time
+	Date            string // clinic local date of StartsAt, YYYY-MM-DD
+	DurationMinutes int
+	VetID           int
+}
+
+// ExpiredOffer is a waitlist offer withdrawn by ExpireOffers, with the entry as it was
+type ExpiredOffer struct {
+	ClinicID int
+	Entry    models.WaitlistEntry
+}
+
+// WaitlistStore persists waitlist entries and the slots offered to them
+type WaitlistStore interface {
+	// List returns entries in the order slots are offered: highest priority, then oldest
+	List(ctx context.Context, scope Scope, filter WaitlistFilter) ([]models.WaitlistEntry, error)
+	Get(ctx context.Context, scope Scope, id int) (models.WaitlistEntry, error)
+	Create(ctx context.Context, scope Scope, e *models.WaitlistEntry) error
+	// Update changes the preferences of a waiting entry; other entries give a *WaitlistStateError
+	Update(ctx context.Context, scope Scope, e *models.WaitlistEntry) error
+	Delete(ctx context.Context, scope Scope, id int) error
+	// Offer marks the first waiting entry that fits the slot as offered and returns it: the slot's
+	// date is in its range, it needs no longer than the slot and it prefers the slot's vet or none.
+	// Entries in exclude are skipped. It returns ErrNotFound when no entry fits.
+	Offer(ctx context.Context, clinicID int, slot FreedSlot, now time.Time, exclude []int) (models.WaitlistEntry, error)
+	// Decline returns an offered entry to waiting and returns it as it was, with the declined offer
+	Decline(ctx context.Context, scope Scope, id int) (models.WaitlistEntry, error)
+	// MarkBooked records the appointment booked from an offered entry
+	MarkBooked(ctx context.Context, scope Scope, id int, appointmentID int) (models.WaitlistEntry, error)
+	// ExpireOffers returns entries of every clinic that were offered a slot before offeredBefore,
+	// or whose offered slot starts before now, to waiting and returns the withdrawn offers
+	ExpireOffers(ctx context.Context, offeredBefore, now time.Time) ([]ExpiredOffer, error)
+}
+
+// ReminderClaimTTL is how long a claimed reminder that was never marked sent blocks
+// another attempt, e.g. after a crash while sending
+const ReminderClaimTTL = 15 * time.Minute
+
+// ReminderStore finds appointments due a reminder and records the reminders sent.
+// A reminder is claimed before it is sent so that concurrent workers or a restart do not
+// send it twice. Reminders are keyed by appointment and start time, so a rescheduled
+// appointment gets a new one. This store works across clinics.
+type ReminderStore interface {
+	// Due returns scheduled appointments starting in (now, until] whose owner has an email
+	// address and whose reminder is neither sent nor claimed within ReminderClaimTTL
+	Due(ctx context.Context, now, until time.Time, limit int) ([]models.Reminder, error)
+	// Claim reserves the reminder and reports false if it is already sent or claimed
+	Claim(ctx context.Context, r models.Reminder, now time.Time) (bool, error)
+	MarkSent(ctx context.Context, r models.Reminder, now time.Time) error
+	// Release drops an unsent claim so the reminder is retried on the next run
+	Release(ctx context.Context, r models.Reminder) error
+}
+
+// ClinicStore persists clinic locations (tenants)
+type ClinicStore interface {
+	List(ctx context.Context) ([]models.Clinic, error)
+	Get(ctx context.Context, id int) (models.Clinic, error)
+	// Hours returns the weekly opening hours; none means the clinic does not restrict bookings
+	Hours(ctx context.Context, clinicID int) ([]models.WorkingHours, error)
+	SetHours(ctx context.Context, clinicID int, hours []models.WorkingHours) error
+	Exists(ctx context.Context, id int) (bool, error)
+	Create(ctx context.Context, c *models.Clinic) error
+}
+
+// Stores bundles every store the handlers depend on
+type Stores struct {
+	Pets         PetStore
+	Owners       OwnerStore
+	Appointments AppointmentStore
+	Files        FileRecordStore
+	Uploads      UploadStore
+	Links        DownloadLinkStore
+	Medical      MedicalEntryStore
+	Vaccinations VaccinationStore
+	Staff        StaffStore
+	Types        AppointmentTypeStore
+	Blocked      BlockedPeriodStore
+	Reminders    ReminderStore
+	Waitlist     WaitlistStore
+	Users        UserStore
+	Sessions     SessionStore
+	Clinics      ClinicStore
+}
@@ -0,0 +1,117 @@
+package thumbnail
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestFitSize(t *testing.T) {
+	tests := []struct {
+		w, h, size   int
+		wantW, wantH int
+	}{
+		{4000, 3000, 256, 256, 192},
+		{3000, 4000, 256, 192, 256},
+		{4000, 3000, 128, 128, 96},
+		{1000, 1000, 512, 512, 512},
+		{5000, 2, 128, 128, 1},  // never scaled to nothing
+		{100, 50, 256, 100, 50}, // small images keep their size
+		{256, 256, 256, 256, 256},
+	}
+	for _, tt := range tests {
+		got := Fit(image.NewGray(image.Rect(0, 0, tt.w, tt.h)), tt.size).Bounds()
+		if got.Dx() != tt.wantW || got.Dy() != tt.wantH {
+			t.Errorf("Fit(%dx%d, %d) = %dx%d, want %dx%d", tt.w, tt.h, tt.size, got.Dx(), got.Dy(), tt.wantW, tt.wantH)
+		}
+	}
+}
+
+func TestFitAveragesCoveredPixels(t *testing.T) {
+	// A 4x2 image, red on the left half and blue on the right, with one white pixel
+	src := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 4; x++ {
+			c := color.RGBA{R: 255, A: 255}
+			if x >= 2 {
+				c = color.RGBA{B: 255, A: 255}
+			}
+			src.SetRGBA(x, y, c)
+		}
+	}
+	src.SetRGBA(3, 1, color.RGBA{R: 255, G: 255, B: 255, A: 255})
+
+	dst := Fit(src, 2)
+	if b := dst.Bounds(); b.Dx() != 2 || b.Dy() != 1 {
+		t.Fatalf("Fit = %dx%d, want 2x1", b.Dx(), b.Dy())
+	}
+	if got, want := dst.RGBAAt(0, 0), (color.RGBA{R: 255, A: 255}); got != want {
+		t.Errorf("left pixel = %v, want %v", got, want)
+	}
+	// Three blue pixels and a white one: blue stays full, red and green a quarter
+	if got, want := dst.RGBAAt(1, 0), (color.RGBA{R: 63, G: 63, B: 255, A: 255}); got != want {
+		t.Errorf("right pixel = %v, want %v", got, want)
+	}
+}
+
+func TestFitHonoursBounds(t *testing.T) {
+	// A sub-image does not start at 0,0; only its own pixels count
+	src := image.NewGray(image.Rect(0, 0, 8, 8))
+	for i := range src.Pix {
+		src.Pix[i] = 255
+	}
+	for y := 4; y < 8; y++ {
+		for x := 4; x < 8; x++ {
+			src.SetGray(x, y, color.Gray{Y: 0})
+		}
+	}
+	dst := Fit(src.SubImage(image.Rect(4, 4, 8, 8)), 1)
+	if got := dst.RGBAAt(0, 0); got != (color.RGBA{A: 255}) {
+		t.Errorf("pixel = %v, want black", got)
+	}
+}
+
+func TestDecodeRejectsHugeImages(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatal(err)
+	}
+	// Declare 60000x60000 pixels in the IHDR chunk, which follows the 8-byte signature,
+	// and fix up the chunk's CRC so the header still reads as valid
+	data := buf.Bytes()
+	binary.BigEndian.PutUint32(data[16:], 60000)
+	binary.BigEndian.PutUint32(data[20:], 60000)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+	if _, err := Decode(bytes.NewReader(data)); err == nil || !strings.Contains(err.Error(), "too large") {
+		t.Errorf("Decode = %v, want a too large error", err)
+	}
+}
+
+func TestDecodeEncodeRoundTrip(t *testing.T) {
+	var src bytes.Buffer
+	if err := png.Encode(&src, image.NewRGBA(image.Rect(0, 0, 300, 200))); err != nil {
+		t.Fatal(err)
+	}
+	img, err := Decode(&src)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	for _, contentType := range []string{"image/jpeg", "image/png"} {
+		var out bytes.Buffer
+		if err := Encode(&out, Fit(img, 128), contentType); err != nil {
+			t.Fatalf("Encode %s: %v", contentType, err)
+		}
+		cfg, format, err := image.DecodeConfig(&out)
+		if err != nil || "image/"+format != contentType || cfg.Width != 128 || cfg.Height != 85 {
+			t.Errorf("%s thumbnail is %s %dx%d (%v)", contentType, format, cfg.Width, cfg.Height, err)
+		}
+	}
+	if err := Encode(&bytes.Buffer{}, img, "image/gif"); err == nil {
+		t.Error("Encode as GIF succeeded, want an error")
+	}
+}
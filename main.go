@@ -1,13 +1,26 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // clinic time zones must resolve even where the host has no zoneinfo
 
+	"pets_project/internal/blob"
 	"pets_project/internal/db"
+	"pets_project/internal/db/migrations"
 	"pets_project/internal/handlers"
+	"pets_project/internal/notify"
+	"pets_project/internal/reminders"
+	"pets_project/internal/scan"
+	"pets_project/internal/store"
 
 	"github.com/joho/godotenv"
 )
@@ -35,13 +48,60 @@ func main() {
 		dbUser, dbPassword, dbHost, dbPort, dbName,
 	)
 
-	// Initialize DB connection
-	dbConn := db.InitDB(connStr)
+	// `server migrate ...` manages the schema and exits without starting the API
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		dbConn := db.InitDB(connStr, false)
+		defer dbConn.Close()
+		if err := runMigrate(dbConn, os.Args[2:]); err != nil {
+			log.Fatalf("ERROR: %v", err)
+		}
+		return
+	}
+
+	// Initialize DB connection, applying pending migrations unless DB_AUTO_MIGRATE=false
+	autoMigrate := os.Getenv("DB_AUTO_MIGRATE") != "false"
+	dbConn := db.InitDB(connStr, autoMigrate)
 	defer dbConn.Close()
 	handlers.Info("Database connection established successfully")
 
-	// Shared environment instance
-	env := &handlers.Env{DB: dbConn}
+	// Shared environment instance backed by the Postgres stores; reminders and
+	// waitlist offers are emailed through the same notifier
+	notifier := newNotifier()
+	env := &handlers.Env{Stores: store.NewPostgres(dbConn), Notifier: notifier, Blobs: newBlobStore()}
+
+	// MAX_UPLOAD_MB limits the size of file uploads (default 512)
+	maxUploadMB, err := envInt("MAX_UPLOAD_MB", 0)
+	if err != nil {
+		log.Fatalf("ERROR: %v", err)
+	}
+	env.MaxUploadBytes = int64(maxUploadMB) << 20
+	// UPLOAD_ALLOWED_TYPES replaces the default list of accepted file types
+	env.AllowedUploadTypes = envList("UPLOAD_ALLOWED_TYPES")
+	// DOWNLOAD_LINK_SECRET signs download links; rotating it invalidates every link
+	env.LinkSecret = []byte(os.Getenv("DOWNLOAD_LINK_SECRET"))
+	// WAITLIST_OFFER_HOURS is how long owners have to answer a waitlist offer (default 24)
+	offerHours, err := envInt("WAITLIST_OFFER_HOURS", 0)
+	if err != nil {
+		log.Fatalf("ERROR: %v", err)
+	}
+	env.WaitlistOfferTTL = time.Duration(offerHours) * time.Hour
+	// PUBLIC_BASE_URL is where clients reach the server; secret feed and download URLs start with it
+	env.PublicBaseURL, err = publicBaseURL()
+	if err != nil {
+		log.Fatalf("ERROR: %v", err)
+	}
+
+	// Appointment reminders run in the background alongside the API
+	startReminders(env.Reminders, notifier)
+
+	// Abandoned resumable uploads and unanswered waitlist offers expire in the background
+	go expireRecords(env)
+
+	// Uploaded files are scanned for malware when CLAMD_ADDR is set
+	env.Scanner = newScanner()
+	if env.Scanner != nil {
+		go scanPendingFiles(env)
+	}
 
 	// ============================================================
 	// PROTECTED ROUTER (JWT REQUIRED)
@@ -49,6 +109,12 @@ func main() {
 
 	apiRouter := http.NewServeMux()
 
+	// Session routes
+	apiRouter.HandleFunc("/logout", env.LogoutHandler)
+
+	// Calendar feed token
+	apiRouter.HandleFunc("/calendar-feed", env.CalendarFeedTokenHandler)
+
 	// Pets routes
 	apiRouter.HandleFunc("/pets", env.PetsHandler)
 	apiRouter.HandleFunc("/pets/", env.PetsHandler)
@@ -61,6 +127,24 @@ func main() {
 	apiRouter.HandleFunc("/appointments", env.AppointmentsHandler)
 	apiRouter.HandleFunc("/appointments/", env.AppointmentsHandler)
 
+	// Staff and their schedules
+	apiRouter.HandleFunc("/staff", env.StaffHandler)
+	apiRouter.HandleFunc("/staff/", env.StaffHandler)
+
+	// Vaccination reports
+	apiRouter.HandleFunc("/vaccinations/", env.VaccinationsHandler)
+
+	// Availability inputs
+	apiRouter.HandleFunc("/appointment-types", env.AppointmentTypesHandler)
+	apiRouter.HandleFunc("/appointment-types/", env.AppointmentTypesHandler)
+	apiRouter.HandleFunc("/clinic-hours", env.ClinicHoursHandler)
+	apiRouter.HandleFunc("/blocked-periods", env.BlockedPeriodsHandler)
+	apiRouter.HandleFunc("/blocked-periods/", env.BlockedPeriodsHandler)
+
+	// Waitlist
+	apiRouter.HandleFunc("/waitlist", env.WaitlistHandler)
+	apiRouter.HandleFunc("/waitlist/", env.WaitlistHandler)
+
 	// File upload & download
 	apiRouter.HandleFunc("/upload", env.UploadFileHandler)
 	apiRouter.HandleFunc("/download", env.DownloadFileHandler)
@@ -69,10 +153,22 @@ func main() {
 	apiRouter.HandleFunc("/files", env.ListFilesHandler)
 	apiRouter.HandleFunc("/files/delete", env.DeleteFileHandler)
 
+	// File thumbnails: /files/{id}/thumbnail
+	apiRouter.HandleFunc("/files/", env.FilesHandler)
+
+	// Resumable (tus) uploads
+	apiRouter.HandleFunc("/files/uploads", env.TusUploadsHandler)
+	apiRouter.HandleFunc("/files/uploads/", env.TusUploadsHandler)
+
+	// Admin routes
+	apiRouter.HandleFunc("/admin/users", env.AdminUsersHandler)
+	apiRouter.HandleFunc("/admin/users/", env.AdminUsersHandler)
+	apiRouter.HandleFunc("/admin/clinics", env.AdminClinicsHandler)
+
 	handlers.Info("All protected routes registered successfully")
 
-	// Wrap with JWT middleware
-	protectedAPI := env.JwtAuthMiddleware(apiRouter)
+	// Wrap with role checks, then JWT middleware (JWT runs first and provides the role)
+	protectedAPI := env.JwtAuthMiddleware(env.AuthorizeMiddleware(apiRouter))
 
 	// ============================================================
 	// PUBLIC ROUTER (NO AUTH REQUIRED)
@@ -83,6 +179,18 @@ func main() {
 	// Public endpoints
 	masterRouter.HandleFunc("/signup", env.SignupHandler)
 	masterRouter.HandleFunc("/login", env.LoginHandler)
+	masterRouter.HandleFunc("/token/refresh", env.RefreshTokenHandler)
+	masterRouter.HandleFunc("/clinics", env.ClinicsHandler)
+
+	// Calendar feeds authenticate with the secret token in the URL instead of a JWT
+	masterRouter.HandleFunc("/calendar/", env.CalendarFeedHandler)
+
+	// Signed download links authenticate with their signature instead of a JWT
+	masterRouter.HandleFunc("/shared/files/", env.SharedDownloadHandler)
+
+	// tus clients discover the server's capabilities with OPTIONS before authenticating
+	masterRouter.HandleFunc("OPTIONS /files/uploads", env.TusUploadsHandler)
+	masterRouter.HandleFunc("OPTIONS /files/uploads/", env.TusUploadsHandler)
 
 	// All other endpoints require JWT
 	masterRouter.Handle("/", protectedAPI)
@@ -100,3 +208,224 @@ func main() {
 	handlers.Info("Server running on port :%s", port)
 	log.Fatal(http.ListenAndServe(":"+port, masterRouter))
 }
+
+// newNotifier sends email through the server in SMTP_ADDR, or only logs messages without it.
+//
+//	SMTP_ADDR                host:port of the mail server
+//	SMTP_FROM, SMTP_USERNAME, SMTP_PASSWORD
+func newNotifier() notify.Notifier {
+	addr := os.Getenv("SMTP_ADDR")
+	if addr == "" {
+		handlers.Warn("SMTP_ADDR not set — reminders and waitlist offers will only be logged")
+		return notify.LogNotifier{Logf: handlers.Info}
+	}
+	return notify.SMTPNotifier{
+		Addr:     addr,
+		From:     os.Getenv("SMTP_FROM"),
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+	}
+}
+
+// newBlobStore keeps uploaded files on local disk, or in an S3-compatible bucket shared by
+// every replica when FILE_STORAGE=s3.
+//
+//	UPLOAD_DIR               directory of local storage (default ./uploads)
+//	S3_BUCKET                bucket name (required for s3)
+//	S3_ENDPOINT              e.g. http://localhost:9000 for MinIO; AWS S3 when unset
+//	S3_REGION                default us-east-1
+//	S3_ACCESS_KEY_ID, S3_SECRET_ACCESS_KEY
+func newBlobStore() blob.Store {
+	switch backend := os.Getenv("FILE_STORAGE"); backend {
+	case "", "local":
+		dir := os.Getenv("UPLOAD_DIR")
+		if dir == "" {
+			dir = "./uploads"
+		}
+		handlers.Info("Storing uploaded files in %s", dir)
+		return blob.Local{Dir: dir}
+	case "s3":
+		region := os.Getenv("S3_REGION")
+		if region == "" {
+			region = "us-east-1"
+		}
+		s3 := &blob.S3{
+			Endpoint:        os.Getenv("S3_ENDPOINT"),
+			Region:          region,
+			Bucket:          os.Getenv("S3_BUCKET"),
+			AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+			// Custom endpoints such as MinIO address buckets by path
+			PathStyle: os.Getenv("S3_ENDPOINT") != "",
+		}
+		if s3.Endpoint == "" {
+			s3.Endpoint = "https://s3." + region + ".amazonaws.com"
+		}
+		if s3.Bucket == "" || s3.AccessKeyID == "" || s3.SecretAccessKey == "" {
+			log.Fatalf("ERROR: FILE_STORAGE=s3 requires S3_BUCKET, S3_ACCESS_KEY_ID and S3_SECRET_ACCESS_KEY")
+		}
+		handlers.Info("Storing uploaded files in S3 bucket %s at %s", s3.Bucket, s3.Endpoint)
+		return s3
+	default:
+		log.Fatalf("ERROR: unknown FILE_STORAGE %q (use local or s3)", backend)
+		return nil
+	}
+}
+
+// newScanner scans uploads with the ClamAV daemon at CLAMD_ADDR, e.g. tcp://localhost:3310
+// or unix:///var/run/clamav/clamd.ctl. Without it files are not scanned.
+func newScanner() scan.Scanner {
+	addr := os.Getenv("CLAMD_ADDR")
+	if addr == "" {
+		handlers.Warn("CLAMD_ADDR not set — uploaded files will not be scanned for malware")
+		return nil
+	}
+	network, address, err := scan.ParseClamdAddr(addr)
+	if err != nil {
+		log.Fatalf("ERROR: %v", err)
+	}
+	handlers.Info("Scanning uploaded files with clamd at %s", addr)
+	return scan.Clamd{Network: network, Address: address}
+}
+
+// startReminders launches the reminder worker unless REMINDER_LEAD_HOURS=0.
+//
+//	REMINDER_LEAD_HOURS      hours before an appointment to remind the owner (default 24)
+//	REMINDER_CHECK_MINUTES   minutes between checks (default 5)
+func startReminders(reminderStore store.ReminderStore, notifier notify.Notifier) {
+	leadHours, err := envInt("REMINDER_LEAD_HOURS", 24)
+	if err != nil {
+		log.Fatalf("ERROR: %v", err)
+	}
+	checkMinutes, err := envInt("REMINDER_CHECK_MINUTES", 5)
+	if err != nil || checkMinutes == 0 {
+		log.Fatalf("ERROR: REMINDER_CHECK_MINUTES must be a positive integer")
+	}
+	if leadHours == 0 {
+		handlers.Info("Appointment reminders disabled (REMINDER_LEAD_HOURS=0)")
+		return
+	}
+
+	worker := &reminders.Worker{
+		Store:    reminderStore,
+		Notifier: notifier,
+		Lead:     time.Duration(leadHours) * time.Hour,
+		Interval: time.Duration(checkMinutes) * time.Minute,
+		Infof:    handlers.Info,
+		Errorf:   handlers.Error,
+	}
+	go worker.Run(context.Background())
+}
+
+// expireRecords removes expired resumable uploads, forgets used download links that expired
+// and passes unanswered waitlist offers on, every five minutes
+func expireRecords(env *handlers.Env) {
+	ticker := time.NewTicker(5 * time.Minute)
+	defer ticker.Stop()
+	for {
+		env.ExpireUploads(context.Background())
+		env.ExpireDownloadLinks(context.Background())
+		env.ExpireWaitlistOffers(context.Background())
+		<-ticker.C
+	}
+}
+
+// scanPendingFiles retries failed scans every minute, e.g. after clamd was unavailable
+func scanPendingFiles(env *handlers.Env) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		<-ticker.C
+		env.ScanPendingFiles(context.Background())
+	}
+}
+
+// envInt reads a non-negative integer environment variable, or def when it is unset
+func envInt(name string, def int) (int, error) {
+	s := os.Getenv(name)
+	if s == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative integer, got %q", name, s)
+	}
+	return n, nil
+}
+
+// envList reads a comma-separated, case-insensitive list, or nil when the variable is unset
+func envList(name string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(name), ",") {
+		if item = strings.ToLower(strings.TrimSpace(item)); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// publicBaseURL reads PUBLIC_BASE_URL, an http(s) URL without a path. Without it URLs follow
+// the Host header of each request, which clients can forge.
+func publicBaseURL() (string, error) {
+	raw := strings.TrimSuffix(strings.TrimSpace(os.Getenv("PUBLIC_BASE_URL")), "/")
+	if raw == "" {
+		handlers.Warn("PUBLIC_BASE_URL not set — feed and download URLs will use the request's Host header")
+		return "", nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.Path != "" || u.RawQuery != "" {
+		return "", fmt.Errorf("invalid PUBLIC_BASE_URL %q: want e.g. https://vet.example.com", raw)
+	}
+	return raw, nil
+}
+
+// runMigrate implements the `migrate` subcommand:
+//
+//	server migrate up          apply all pending migrations (default)
+//	server migrate down [n]    revert the last n migrations (default 1)
+//	server migrate status      list migrations and whether they are applied
+func runMigrate(dbConn *sql.DB, args []string) error {
+	ctx := context.Background()
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	switch command {
+	case "up":
+		applied, err := migrations.Up(ctx, dbConn)
+		if err != nil {
+			return err
+		}
+		handlers.Info("Applied %d migration(s)", applied)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n <= 0 {
+				return fmt.Errorf("invalid number of steps: %s", args[1])
+			}
+			steps = n
+		}
+		reverted, err := migrations.Down(ctx, dbConn, steps)
+		if err != nil {
+			return err
+		}
+		handlers.Info("Reverted %d migration(s)", reverted)
+	case "status":
+		statuses, err := migrations.Statuses(ctx, dbConn)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.Applied {
+				state = "applied " + s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d_%s\t%s\n", s.Version, s.Name, state)
+		}
+	default:
+		return fmt.Errorf("unknown migrate command %q (use up, down [n] or status)", command)
+	}
+	return nil
+}